@@ -0,0 +1,154 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"vip-switch-go/internal/config"
+)
+
+// newConfigCmd creates the config command group
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect and check the configuration",
+	}
+	cmd.AddCommand(newConfigValidateCmd())
+	cmd.AddCommand(newConfigShowCmd())
+	return cmd
+}
+
+// effectiveHeader explains the sources config show --effective adds
+const effectiveHeader = `# Environment and flags override the files. Later sources win:
+#   1. the files above
+#   2. VIPSWITCH_* environment variables, e.g. VIPSWITCH_HOOKS_TIMEOUT=30s
+#   3. flags: --set key.path=value, then --data-dir
+`
+
+// printIgnoredEnv prints the VIPSWITCH_* variables that name no setting
+func printIgnoredEnv(out io.Writer, names []string) {
+	for _, name := range names {
+		fmt.Fprintf(out, "warning: %s names no setting, ignored\n", name)
+	}
+}
+
+// printFiles prints the files a configuration was merged from
+func printFiles(out io.Writer, cfg *config.Config) {
+	fmt.Fprintln(out, "# Merged from, later files override earlier ones:")
+	for _, file := range cfg.Files() {
+		fmt.Fprintf(out, "#   %s\n", file)
+	}
+	fmt.Fprintln(out, "# Each value is marked with its source; unmarked values are defaults.")
+}
+
+// newConfigShowCmd creates the config show command
+func newConfigShowCmd() *cobra.Command {
+	var effective bool
+
+	cmd := &cobra.Command{
+		Use:   "show [file]",
+		Short: "Print the configuration with defaults filled in",
+		Long: `Print the configuration as the daemon reads it, with defaults filled in and
+every value marked with the file and line it came from. The file defaults to
+--config; it overrides the files it includes, and the conf.d drop-ins next to
+it are merged over it.
+
+With --effective the VIPSWITCH_* environment variables and the --set flags are
+applied as well, and every value they set is marked with its source. The file
+is overridden by the environment, which is overridden by flags.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := configFile
+			if len(args) == 1 {
+				path = args[0]
+			}
+			if path == "" {
+				return errors.New("a file or --config is required")
+			}
+
+			var layers config.Overlay
+			if effective {
+				layers = overlay()
+			}
+			cfg, err := config.LoadWithOverlay(path, layers)
+			if err != nil {
+				return err
+			}
+			data, err := cfg.Marshal()
+			if err != nil {
+				return err
+			}
+
+			printIgnoredEnv(cmd.ErrOrStderr(), cfg.IgnoredEnv())
+			printFiles(cmd.OutOrStdout(), cfg)
+			if effective {
+				fmt.Fprint(cmd.OutOrStdout(), effectiveHeader)
+			}
+			_, err = cmd.OutOrStdout().Write(data)
+			return err
+		},
+	}
+
+	cmd.Flags().BoolVar(&effective, "effective", false, "Apply the environment and flags and mark the values they set")
+	return cmd
+}
+
+// newConfigValidateCmd creates the config validate command
+func newConfigValidateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate [file]",
+		Short: "Check a configuration file without starting the daemon",
+		Long: `Load a configuration file and report every problem found, each with its line
+number, after the VIPSWITCH_* environment variables and --set flags are
+applied: missing or malformed settings, a node.id missing from cluster.nodes or
+a raft_addr that does not match its entry, duplicate cluster members, unknown
+on_failure strategies, hook templates that do not parse and, with hooks
+enabled, hook commands that are not executable. The file defaults to --config.
+The command exits non-zero when a problem is found.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := configFile
+			if len(args) == 1 {
+				path = args[0]
+			}
+			if path == "" {
+				return errors.New("a file or --config is required")
+			}
+
+			cfg, err := config.LoadWithOverlay(path, overlay())
+			if cfg != nil {
+				printIgnoredEnv(cmd.ErrOrStderr(), cfg.IgnoredEnv())
+			}
+			var verr *config.ValidationError
+			if errors.As(err, &verr) {
+				printIgnoredEnv(cmd.ErrOrStderr(), verr.IgnoredEnv)
+				for _, line := range verr.Lines() {
+					fmt.Fprintln(cmd.ErrOrStderr(), line)
+				}
+				return fmt.Errorf("%s: %d problem(s) found", path, len(verr.Problems))
+			}
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "%s: configuration is valid\n", path)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,396 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"vip-switch-go/internal/admin"
+	"vip-switch-go/internal/config"
+	"vip-switch-go/internal/hook"
+	"vip-switch-go/internal/redact"
+	"vip-switch-go/internal/state"
+)
+
+// newHooksCmd creates the hooks command group
+func newHooksCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hooks",
+		Short: "Inspect and test hooks",
+	}
+	cmd.AddCommand(newHooksHistoryCmd())
+	cmd.AddCommand(newHooksTestCmd())
+	return cmd
+}
+
+// newHooksTestCmd creates the hooks test command
+func newHooksTestCmd() *cobra.Command {
+	var (
+		event      string
+		from       string
+		to         string
+		renderOnly bool
+		verbose    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "test",
+		Short: "Render and optionally run a hook without a failover",
+		Long: `Resolve a hook as the daemon would, with templates expanded and security checks
+applied, and print its command, arguments, environment and stdin payload. Unless
+--render-only is given the hook is then run with its configured timeout and failure
+strategy. The exit status is non-zero when the hook fails.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !slices.Contains(config.EventTypes, event) {
+				return fmt.Errorf("invalid --event %q: must be one of %s", event, strings.Join(config.EventTypes, ", "))
+			}
+
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			if !cfg.Hooks.Enabled {
+				fmt.Fprintln(cmd.ErrOrStderr(), "Note: hooks are disabled in this configuration, testing anyway")
+				cfg.Hooks.Enabled = true
+			}
+			// Show all of the output rather than the tail kept in the history
+			cfg.Hooks.History.OutputTail = testOutputLimit
+
+			level := slog.LevelWarn
+			if verbose {
+				level = slog.LevelInfo
+			}
+			secrets := redact.NewSecrets()
+			logger := slog.New(redact.NewHandler(slog.NewTextHandler(cmd.ErrOrStderr(), &slog.HandlerOptions{Level: level}), secrets))
+			system := hook.NewSystem(cfg, logger)
+			system.SetSecrets(secrets)
+			if err := system.LoadSecrets(cfg); err != nil {
+				logger.Warn("Failed to read hook secrets", "error", err)
+			}
+
+			tr := defaultTransition(event)
+			if cmd.Flags().Changed("from") {
+				tr.PreviousState = from
+			}
+			if cmd.Flags().Changed("to") {
+				tr.NewState = to
+			}
+
+			hookDef, err := cfg.GetHookByEventType(event)
+			if err != nil {
+				return err
+			}
+			inv, err := system.Render(event, tr)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			runners := hookDef.RunnerNames()
+			if len(runners) == 0 {
+				fmt.Fprintf(out, "No command is configured for %s\n", event)
+				return nil
+			}
+			if inv != nil {
+				printInvocation(out, inv, hookDef, tr)
+			} else {
+				fmt.Fprintf(out, "Event: %s (%s -> %s)\nRunners: %s\n", event, orNone(tr.PreviousState), tr.NewState, strings.Join(runners, ", "))
+			}
+
+			if renderOnly {
+				return nil
+			}
+
+			fmt.Fprintf(out, "\nRunning %s...\n", event)
+			rec, err := system.Run(cmd.Context(), event, tr)
+			if rec != nil {
+				printTestResult(out, rec)
+			}
+			if err != nil {
+				return err
+			}
+			if rec != nil && !rec.Success {
+				return fmt.Errorf("hook failed, the %s strategy would let the transition proceed", hookDef.OnFailure)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&event, "event", "", "Hook event to test: "+strings.Join(config.EventTypes, ", "))
+	cmd.Flags().StringVar(&from, "from", "", "Previous state passed to the hook (default depends on the event)")
+	cmd.Flags().StringVar(&to, "to", "", "New state passed to the hook (default depends on the event)")
+	cmd.Flags().BoolVar(&renderOnly, "render-only", false, "Print the resolved hook without running it")
+	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Log hook execution details to stderr")
+	cmd.MarkFlagRequired("event")
+	return cmd
+}
+
+// testOutputLimit caps the output hooks test captures from a hook
+const testOutputLimit = 1 << 20
+
+// defaultTransition returns a typical transition for a hook event
+func defaultTransition(event string) hook.Transition {
+	switch event {
+	case "ToReady":
+		return hook.Transition{NewState: state.StateReady.String()}
+	case "ToMaster":
+		return hook.Transition{PreviousState: state.StateSlave.String(), NewState: state.StateMaster.String()}
+	case "ToSlave":
+		return hook.Transition{PreviousState: state.StateReady.String(), NewState: state.StateSlave.String()}
+	case "EnsureMaster":
+		return hook.Transition{PreviousState: state.StateMaster.String(), NewState: state.StateMaster.String()}
+	case "EnsureSlave":
+		return hook.Transition{PreviousState: state.StateSlave.String(), NewState: state.StateSlave.String()}
+	default:
+		return hook.Transition{PreviousState: state.StateSlave.String(), NewState: state.StateDestroy.String()}
+	}
+}
+
+// printInvocation prints a resolved hook
+func printInvocation(out io.Writer, inv *hook.Invocation, hookDef *config.HookDefinition, tr hook.Transition) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Event:\t%s (%s -> %s)\n", inv.EventType, orNone(tr.PreviousState), tr.NewState)
+	if len(hookDef.Runners) > 0 {
+		fmt.Fprintf(w, "Runners:\t%s\n", strings.Join(hookDef.Runners, ", "))
+	}
+	fmt.Fprintf(w, "Command:\t%s\n", inv.Command)
+	fmt.Fprintf(w, "Args:\t%s\n", formatArgs(inv.Args))
+	if inv.WorkingDir != "" {
+		fmt.Fprintf(w, "Working dir:\t%s\n", inv.WorkingDir)
+	}
+	if hookDef.User != "" {
+		fmt.Fprintf(w, "User:\t%s\n", hookDef.User)
+	}
+	fmt.Fprintf(w, "Timeout:\t%s\n", hookDef.Timeout)
+	fmt.Fprintf(w, "On failure:\t%s\n", hookDef.OnFailure)
+	if hookDef.OnFailure == "retry" {
+		fmt.Fprintf(w, "Retry:\t%d attempts, %s attempt timeout\n", hookDef.Retry.MaxAttempts, hookDef.Retry.AttemptTimeout)
+	}
+	w.Flush()
+
+	env := slices.Clone(inv.Env)
+	slices.Sort(env)
+	fmt.Fprintln(out, "Environment:")
+	for _, kv := range env {
+		if name, _, _ := strings.Cut(kv, "="); slices.Contains(inv.Secrets, name) {
+			kv = name + "=" + redact.Placeholder
+		}
+		fmt.Fprintf(out, "  %s\n", kv)
+	}
+
+	fmt.Fprintln(out, "Payload (stdin):")
+	var payload bytes.Buffer
+	if err := json.Indent(&payload, inv.Stdin, "  ", "  "); err != nil {
+		payload.Write(inv.Stdin)
+	}
+	fmt.Fprintf(out, "  %s\n", strings.TrimSpace(payload.String()))
+}
+
+// printTestResult prints the outcome of a test run
+func printTestResult(out io.Writer, rec *hook.HistoryRecord) {
+	for _, attempt := range rec.Attempts {
+		fmt.Fprintf(out, "Attempt %d", attempt.Attempt)
+		if attempt.Runner != config.RunnerExec {
+			fmt.Fprintf(out, " (%s)", attempt.Runner)
+		}
+		fmt.Fprintf(out, ": exit %d", attempt.ExitCode)
+		if attempt.Signal != "" {
+			fmt.Fprintf(out, ", signal %s", attempt.Signal)
+		}
+		if attempt.Reason != "" {
+			fmt.Fprintf(out, ", %s", attempt.Reason)
+		}
+		fmt.Fprintf(out, ", %dms\n", attempt.DurationMS)
+		printOutput(out, "stdout", attempt.Stdout)
+		printOutput(out, "stderr", attempt.Stderr)
+	}
+
+	if rec.Success {
+		fmt.Fprintf(out, "Result: success in %s\n", rec.Duration().Round(time.Millisecond))
+	} else {
+		fmt.Fprintf(out, "Result: failed in %s: %s\n", rec.Duration().Round(time.Millisecond), rec.Error)
+	}
+}
+
+// formatArgs quotes arguments containing spaces for display
+func formatArgs(args []string) string {
+	if len(args) == 0 {
+		return "(none)"
+	}
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"") {
+			arg = strconv.Quote(arg)
+		}
+		quoted[i] = arg
+	}
+	return strings.Join(quoted, " ")
+}
+
+// orNone returns s, or "(none)" when it is empty
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+// newHooksHistoryCmd creates the hooks history command
+func newHooksHistoryCmd() *cobra.Command {
+	var (
+		event   string
+		since   string
+		limit   int
+		asJSON  bool
+		verbose bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show recorded hook runs",
+		Long: `Show recorded hook runs, oldest first. The running daemon is queried over
+its admin socket; when it is not running the journal in data_dir is read directly.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			filter := hook.HistoryFilter{Event: event, Limit: limit}
+			if since != "" {
+				if filter.Since, err = parseSince(since, time.Now()); err != nil {
+					return err
+				}
+			}
+
+			records, err := admin.NewClient(cfg.AdminSocketPath()).HookHistory(cmd.Context(), filter)
+			if err != nil {
+				records, err = hook.ReadHistory(cfg.HookHistoryPath(), filter)
+				if err != nil {
+					return err
+				}
+			}
+
+			if asJSON {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				for i := range records {
+					if err := enc.Encode(&records[i]); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+			printHistory(cmd.OutOrStdout(), records, verbose)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&event, "event", "", "Only show runs of this event, e.g. ToMaster")
+	cmd.Flags().StringVar(&since, "since", "", "Only show runs started after a duration ago (e.g. 24h) or an RFC 3339 time")
+	cmd.Flags().IntVar(&limit, "limit", 50, "Show at most this many of the most recent runs, 0 for all")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print records as JSON lines")
+	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show every attempt with its output")
+	return cmd
+}
+
+// parseSince accepts a duration before now or an RFC 3339 time
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --since %q: want a duration such as 24h or an RFC 3339 time", value)
+	}
+	return t, nil
+}
+
+// printHistory prints records as a table, with attempt details when verbose
+func printHistory(out io.Writer, records []hook.HistoryRecord, verbose bool) {
+	if len(records) == 0 {
+		fmt.Fprintln(out, "No hook runs recorded")
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "STARTED\tEVENT\tTRANSITION\tRESULT\tATTEMPTS\tDURATION\tCOMMAND")
+	for _, rec := range records {
+		result := "ok"
+		if !rec.Success {
+			result = "failed"
+		}
+		transition := "-"
+		if rec.PreviousState != "" || rec.NewState != "" {
+			transition = rec.PreviousState + "->" + rec.NewState
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			rec.StartedAt.Local().Format(time.DateTime),
+			rec.Event,
+			transition,
+			result,
+			len(rec.Attempts),
+			rec.Duration().Round(time.Millisecond),
+			strings.Join(append([]string{rec.Command}, rec.Args...), " "),
+		)
+	}
+	w.Flush()
+
+	if !verbose {
+		return
+	}
+	for _, rec := range records {
+		fmt.Fprintf(out, "\n%s %s\n", rec.StartedAt.Local().Format(time.DateTime), rec.Event)
+		if rec.Error != "" {
+			fmt.Fprintf(out, "  error: %s\n", rec.Error)
+		}
+		if len(rec.EnvKeys) > 0 {
+			fmt.Fprintf(out, "  env: %s\n", strings.Join(rec.EnvKeys, " "))
+		}
+		for _, attempt := range rec.Attempts {
+			fmt.Fprintf(out, "  attempt %d: exit %d", attempt.Attempt, attempt.ExitCode)
+			if attempt.Signal != "" {
+				fmt.Fprintf(out, ", signal %s", attempt.Signal)
+			}
+			if attempt.Reason != "" {
+				fmt.Fprintf(out, ", %s", attempt.Reason)
+			}
+			fmt.Fprintf(out, ", %dms\n", attempt.DurationMS)
+			printOutput(out, "stdout", attempt.Stdout)
+			printOutput(out, "stderr", attempt.Stderr)
+		}
+	}
+}
+
+// printOutput prints a captured output tail indented under its stream name
+func printOutput(out io.Writer, stream, text string) {
+	if text == "" {
+		return
+	}
+	fmt.Fprintf(out, "    %s:\n", stream)
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		fmt.Fprintf(out, "      %s\n", line)
+	}
+}
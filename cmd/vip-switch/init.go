@@ -0,0 +1,241 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"vip-switch-go/internal/config"
+)
+
+// Files written by init next to the per-node directories
+const (
+	initHookScript = "vip-switch-hook.sh"
+	initUnitFile   = "vip-switch.service"
+)
+
+// initHookPath is where the configurations expect the hook script by default
+const initHookPath = "/usr/local/bin/" + initHookScript
+
+// hookScript is the hook skeleton init writes, run for every event
+const hookScript = `#!/bin/sh
+# vip-switch hook, generated by vip-switch init. It is run for every event
+# with EVENT_TYPE, NODE_ID, VIP_ADDRESS and VIP_INTERFACE set and the event
+# payload as JSON on stdin. A non-zero exit fails the hook.
+set -eu
+
+case "$EVENT_TYPE" in
+ToReady)
+    ;;
+ToMaster)
+    ip addr replace "$VIP_ADDRESS" dev "$VIP_INTERFACE"
+    arping -U -c 3 -I "$VIP_INTERFACE" "${VIP_ADDRESS%/*}" || true
+    ;;
+ToSlave|ToDestroy)
+    ip addr del "$VIP_ADDRESS" dev "$VIP_INTERFACE" 2>/dev/null || true
+    ;;
+*)
+    echo "unexpected event $EVENT_TYPE" >&2
+    exit 1
+    ;;
+esac
+`
+
+// systemdUnit is the service unit init writes
+const systemdUnit = `[Unit]
+Description=VIP-Switch virtual IP failover
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=notify
+NotifyAccess=main
+WatchdogSec=30s
+Delegate=yes
+ExecStart=/usr/local/bin/vip-switch --config /etc/vip-switch/config.yaml
+ExecReload=/bin/kill -HUP $MAINPID
+Restart=on-failure
+RestartSec=5s
+
+[Install]
+WantedBy=multi-user.target
+`
+
+// newInitCmd creates the init command
+func newInitCmd() *cobra.Command {
+	var (
+		nodes    string
+		vip      string
+		iface    string
+		outDir   string
+		hookPath string
+		force    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Generate configuration files for a new cluster",
+		Long: `Generate a config.yaml for every node of a cluster, in a directory named after
+the node, together with a hook script shared by all nodes and a systemd unit.
+Missing --nodes, --vip and --iface values are asked for on stdin.
+
+The hooks run --hook-path, /usr/local/bin/vip-switch-hook.sh by default, where
+the generated script is to be installed; config validate passes once it is.
+Pass the generated script's own path to try the configurations in place, in
+which case they are validated right away. Existing files are kept unless
+--force is given.`,
+		Example: `  vip-switch init --nodes node1=10.0.0.1:7946,node2=10.0.0.2:7946,node3=10.0.0.3:7946 \
+    --vip 10.0.0.100/24 --iface eth0 --out-dir ./cluster`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			in := bufio.NewReader(cmd.InOrStdin())
+			out := cmd.OutOrStdout()
+			for _, value := range []struct {
+				flag   *string
+				prompt string
+			}{
+				{&nodes, "Cluster nodes (id=host:port, comma separated)"},
+				{&vip, "Virtual IP (CIDR, e.g. 10.0.0.100/24)"},
+				{&iface, "Interface for the virtual IP"},
+			} {
+				if *value.flag != "" {
+					continue
+				}
+				answer, err := prompt(in, out, value.prompt)
+				if err != nil {
+					return err
+				}
+				*value.flag = answer
+			}
+
+			spec := config.ClusterSpec{
+				VIP:     config.VIPConfig{Address: vip, Interface: iface},
+				DataDir: dataDir,
+			}
+			if spec.DataDir == "" {
+				spec.DataDir = "/var/lib/vip-switch"
+			}
+			var err error
+			if spec.Nodes, err = config.ParseClusterNodes(nodes); err != nil {
+				return err
+			}
+			if len(spec.Nodes) == 0 {
+				return errors.New("at least one node is required")
+			}
+
+			if outDir, err = filepath.Abs(outDir); err != nil {
+				return err
+			}
+			script := filepath.Join(outDir, initHookScript)
+			spec.HookCommand = hookPath
+
+			files := map[string][]byte{
+				script:                              []byte(hookScript),
+				filepath.Join(outDir, initUnitFile): []byte(systemdUnit),
+			}
+			var configs []string
+			for _, node := range spec.Nodes {
+				content, err := spec.Generate(node.ID)
+				if err != nil {
+					return err
+				}
+				path := filepath.Join(outDir, node.ID, "config.yaml")
+				files[path] = content
+				configs = append(configs, path)
+			}
+			if !force {
+				for path := range files {
+					if _, err := os.Stat(path); err == nil {
+						return fmt.Errorf("%s already exists, use --force to overwrite it", path)
+					}
+				}
+			}
+			for path, content := range files {
+				mode := os.FileMode(0644)
+				if path == script {
+					mode = 0755
+				}
+				if err := writeFile(path, content, mode); err != nil {
+					return err
+				}
+			}
+
+			for _, path := range configs {
+				fmt.Fprintf(out, "Wrote %s\n", path)
+			}
+			fmt.Fprintf(out, "Wrote %s\n", script)
+			fmt.Fprintf(out, "Wrote %s\n", filepath.Join(outDir, initUnitFile))
+			if spec.HookCommand == script {
+				// The configurations refer to files that now exist
+				for _, path := range configs {
+					if _, err := config.Load(path); err != nil {
+						return err
+					}
+				}
+			}
+
+			fmt.Fprintf(out, "\nOn each node:\n")
+			fmt.Fprintf(out, "  install -D -m 0644 %s /etc/vip-switch/config.yaml\n", filepath.Join(outDir, "<node>", "config.yaml"))
+			if spec.HookCommand != script {
+				fmt.Fprintf(out, "  install -D -m 0755 %s %s\n", script, spec.HookCommand)
+			}
+			fmt.Fprintf(out, "  install -m 0644 %s /etc/systemd/system/\n", filepath.Join(outDir, initUnitFile))
+			fmt.Fprintf(out, "  vip-switch config validate /etc/vip-switch/config.yaml\n")
+			fmt.Fprintf(out, "  systemctl daemon-reload && systemctl enable --now vip-switch\n")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&nodes, "nodes", "", "Cluster members as id=host:port, comma separated")
+	cmd.Flags().StringVar(&vip, "vip", "", "Virtual IP in CIDR notation")
+	cmd.Flags().StringVar(&iface, "iface", "", "Interface the virtual IP is bound to")
+	cmd.Flags().StringVar(&outDir, "out-dir", ".", "Directory to write the files to")
+	cmd.Flags().StringVar(&hookPath, "hook-path", initHookPath, "Hook command in the configurations")
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite existing files")
+	return cmd
+}
+
+// prompt asks for a value on in, failing when none is given
+func prompt(in *bufio.Reader, out io.Writer, question string) (string, error) {
+	fmt.Fprintf(out, "%s: ", question)
+	answer, err := in.ReadString('\n')
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", err
+		}
+		return "", fmt.Errorf("no answer given for %q", question)
+	}
+	return answer, nil
+}
+
+// writeFile writes content to path, creating its directory
+func writeFile(path string, content []byte, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, content, mode); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of an existing file
+	return os.Chmod(path, mode)
+}
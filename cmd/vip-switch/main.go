@@ -16,6 +16,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -24,12 +25,18 @@ import (
 	"path/filepath"
 	"strings"
 	"syscall"
+	"time"
 
+	metrics "github.com/hashicorp/go-metrics/compat"
 	"github.com/spf13/cobra"
+	"vip-switch-go/internal/admin"
 	"vip-switch-go/internal/config"
 	"vip-switch-go/internal/hook"
 	"vip-switch-go/internal/raft"
+	"vip-switch-go/internal/redact"
 	"vip-switch-go/internal/state"
+	"vip-switch-go/internal/systemd"
+	"vip-switch-go/internal/track"
 )
 
 var (
@@ -39,6 +46,7 @@ var (
 	dataDir    string
 	logLevel   string
 	logFormat  string
+	settings   []string
 )
 
 func main() {
@@ -56,54 +64,81 @@ Key features:
 - Support for cluster membership changes
 - Configurable failure strategies and timeouts`,
 		Run: run,
+		// main reports errors itself
+		SilenceErrors: true,
+		SilenceUsage:  true,
 	}
 
-	rootCmd.Flags().StringVarP(&configFile, "config", "c", "", "Path to configuration file (required)")
+	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "Path to configuration file (required)")
+	rootCmd.PersistentFlags().StringVar(&dataDir, "data-dir", "", "Raft data directory (overrides config file)")
+	rootCmd.PersistentFlags().StringArrayVar(&settings, "set", nil, "Override a setting, e.g. --set hooks.timeout=30s (repeatable)")
 	rootCmd.Flags().StringVar(&nodeID, "node-id", "", "Node ID (overrides config file)")
 	rootCmd.Flags().StringVar(&raftAddr, "raft-addr", "", "Raft RPC address (overrides config file)")
-	rootCmd.Flags().StringVar(&dataDir, "data-dir", "", "Raft data directory (overrides config file)")
 	rootCmd.Flags().StringVar(&logLevel, "log-level", "", "Log level: debug, info, warn, error (overrides config file)")
 	rootCmd.Flags().StringVar(&logFormat, "log-format", "", "Log format: json, text (overrides config file)")
 
+	rootCmd.AddCommand(newHooksCmd())
+	rootCmd.AddCommand(newStatusCmd())
+	rootCmd.AddCommand(newReloadCmd())
+	rootCmd.AddCommand(newConfigCmd())
+	rootCmd.AddCommand(newInitCmd())
+
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func run(cmd *cobra.Command, args []string) {
-	// Validate required flags
+// loadConfig loads the configuration named by --config with the
+// environment and command line overrides applied
+func loadConfig() (*config.Config, error) {
 	if configFile == "" {
-		fmt.Fprintln(os.Stderr, "Error: --config is required")
-		os.Exit(1)
+		return nil, errors.New("--config is required")
 	}
 
-	// Load configuration
-	cfg, err := config.Load(configFile)
+	cfg, err := config.LoadWithOverlay(configFile, overlay())
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
 	}
+	return cfg, nil
+}
 
-	// Override config with CLI flags
-	if nodeID != "" {
-		cfg.Node.ID = nodeID
-	}
-	if raftAddr != "" {
-		cfg.Node.RaftAddr = raftAddr
+// warnIgnoredEnv logs the VIPSWITCH_* variables that name no setting
+func warnIgnoredEnv(logger *slog.Logger, cfg *config.Config) {
+	for _, name := range cfg.IgnoredEnv() {
+		logger.Warn("Ignoring environment variable that names no setting", "name", name)
 	}
-	if dataDir != "" {
-		cfg.Node.DataDir = dataDir
-	}
-	if logLevel != "" {
-		cfg.Logging.Level = logLevel
+}
+
+// overlay returns the VIPSWITCH_* environment variables and the flags that
+// override the file. The dedicated flags take precedence over --set.
+func overlay() config.Overlay {
+	set := append([]string(nil), settings...)
+	for _, flag := range []struct{ path, value string }{
+		{"node.id", nodeID},
+		{"node.raft_addr", raftAddr},
+		{"node.data_dir", dataDir},
+		{"logging.level", logLevel},
+		{"logging.format", logFormat},
+	} {
+		if flag.value != "" {
+			set = append(set, flag.path+"="+flag.value)
+		}
 	}
-	if logFormat != "" {
-		cfg.Logging.Format = logFormat
+	return config.Overlay{Env: os.Environ(), Set: set}
+}
+
+func run(cmd *cobra.Command, args []string) {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
 
 	// Initialize logger
-	logger := initLogger(cfg.Logging)
+	level := new(slog.LevelVar)
+	secrets := redact.NewSecrets()
+	logger := initLogger(cfg.Logging, level, secrets)
 
 	logger.Info("Starting VIP-Switch",
 		"node_id", cfg.Node.ID,
@@ -111,6 +146,9 @@ func run(cmd *cobra.Command, args []string) {
 		"data_dir", cfg.Node.DataDir,
 		"config_file", configFile,
 	)
+	warnIgnoredEnv(logger, cfg)
+
+	initMetrics(logger)
 
 	// Create context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
@@ -118,8 +156,54 @@ func run(cmd *cobra.Command, args []string) {
 
 	// Initialize hook system
 	hookSystem := hook.NewSystem(cfg, logger)
+	hookSystem.SetSecrets(secrets)
+	if err := hookSystem.LoadSecrets(cfg); err != nil {
+		logger.Warn("Failed to read hook secrets", "error", err)
+	}
+
+	history, err := hook.OpenHistory(cfg.HookHistoryPath(), cfg.Hooks.History.MaxRecords, cfg.Hooks.History.MaxAge)
+	if err != nil {
+		logger.Warn("Hook history unavailable", "error", err)
+	} else {
+		hookSystem.SetHistory(history)
+	}
+
+	adminServer := admin.NewServer(cfg.AdminSocketPath(), logger)
+	if history != nil {
+		adminServer.SetHistory(history)
+	}
+	// Under socket activation systemd owns the admin socket
+	listeners, err := systemd.Listeners()
+	if err != nil {
+		logger.Error("Failed to use activated sockets", "error", err)
+		os.Exit(1)
+	}
+	if len(listeners) > 0 {
+		adminServer.SetListener(listeners[0])
+	}
 
-	stateMachine := state.NewMachine(hookSystem, cfg.Node.ID, logger)
+	// All hooks run one at a time, in order, on the dispatcher
+	dispatcher := hook.NewDispatcher(hookSystem.ExecuteHook, logger)
+	dispatcher.Start()
+
+	stateMachine := state.NewMachine(dispatcher, cfg.Node.ID, logger)
+
+	eligibility := track.NewEligibility(cfg.Score.Priority, logger)
+	if len(cfg.Track.Interfaces) > 0 {
+		watcher := track.NewLinkWatcher(cfg.Track.Interfaces, cfg.Track.UpDelay, eligibility, logger)
+		if err := watcher.Start(ctx); err != nil {
+			logger.Error("Failed to start link tracking", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("Tracking links", "count", len(cfg.Track.Interfaces), "up_delay", cfg.Track.UpDelay)
+	}
+	var probes *track.ProbeWatcher
+	if len(cfg.Track.Targets) > 0 || len(cfg.Track.Checks) > 0 {
+		probes = track.NewProbeWatcher(cfg.Track.Targets, cfg.Track.Checks, eligibility, logger)
+		probes.Start(ctx)
+		logger.Info("Tracking targets and checks", "targets", len(cfg.Track.Targets), "checks", len(cfg.Track.Checks))
+	}
+	stateMachine.SetEligibility(eligibility)
 
 	fsm := raft.NewFSM(logger)
 	raftNode, err := raft.NewNode(cfg, fsm, logger)
@@ -128,61 +212,145 @@ func run(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
-	stateMachine.SetRaftNode(raftNode)
+	stateMachine.SetConsensus(raftNode)
+	hookSystem.SetClusterInfo(raftNode)
+	raftNode.HandleRPC(track.ScoreRPC, track.ScoreHandler(cfg.Node.ID, eligibility, logger))
+
+	balancer := state.NewBalancer(cfg.Node.ID, cfg.Score, eligibility, raftNode, logger)
+	adminServer.SetStatus(func() admin.Status {
+		return admin.Status{
+			NodeID: cfg.Node.ID,
+			State:  stateMachine.GetCurrentState().String(),
+			Leader: raftNode.LeaderID(),
+			Term:   raftNode.Term(),
+			Scores: balancer.Scores(),
+		}
+	})
+
+	reloader := &reloader{
+		ctx:         ctx,
+		config:      cfg,
+		logLevel:    level,
+		hooks:       hookSystem,
+		eligibility: eligibility,
+		probes:      probes,
+		balancer:    balancer,
+		logger:      logger,
+	}
+	adminServer.SetReload(reloader.reload)
+
+	// Served only once the handlers are set, as an activated socket may
+	// already have a request waiting
+	if err := adminServer.Start(); err != nil {
+		logger.Error("Failed to start admin API", "error", err)
+		os.Exit(1)
+	}
+	defer adminServer.Shutdown(context.Background())
 
 	if err := raftNode.Start(); err != nil {
 		logger.Error("Failed to start Raft node", "error", err)
 		os.Exit(1)
 	}
-	defer raftNode.Shutdown()
 
 	if err := stateMachine.Start(ctx); err != nil {
 		logger.Error("Failed to start state machine", "error", err)
 		os.Exit(1)
 	}
+	balancer.Start(ctx)
 
 	logger.Info("Executing ToReady hook")
-	if err := hookSystem.ExecuteHook(ctx, "ToReady"); err != nil {
+	if err := dispatcher.Dispatch(ctx, "ToReady", hook.Transition{
+		NewState: state.StateReady.String(),
+	}); err != nil {
 		logger.Error("ToReady hook failed", "error", err)
 	} else {
 		logger.Info("ToReady hook completed successfully")
 	}
 
+	// systemd considers the service started once Raft runs and ToReady is done
+	notifier := systemd.NewNotifier(logger)
+	statusLine := func() string {
+		leader := raftNode.LeaderID()
+		if leader == "" {
+			leader = "none"
+		}
+		return fmt.Sprintf("%s, leader %s, term %d", stateMachine.GetCurrentState(), leader, raftNode.Term())
+	}
+	if err := notifier.Notify(systemd.Ready); err != nil {
+		logger.Warn("Failed to notify systemd", "error", err)
+	}
+	go notifier.RunStatus(ctx, time.Second, statusLine)
+	if interval := systemd.WatchdogInterval(); interval > 0 {
+		go notifier.RunWatchdog(ctx, interval, func() bool {
+			return time.Since(stateMachine.Heartbeat()) < interval
+		})
+		logger.Info("systemd watchdog enabled", "interval", interval)
+	}
+
+	if cfg.Hooks.Ensure.Interval > 0 {
+		reconciler := state.NewReconciler(stateMachine, cfg.Hooks.Ensure.Interval, logger)
+		if cfg.Hooks.Ensure.NativeCheck {
+			reconciler.AddCheck("native", state.VIPCheck(cfg.VIP.Interface, cfg.VIP.Address))
+		}
+		reconciler.AddCheck("hook", state.HookCheck(hookSystem))
+		reconciler.Start(ctx)
+		logger.Info("VIP reconciliation enabled", "interval", cfg.Hooks.Ensure.Interval)
+	}
+
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
 	go func() {
-		sig := <-sigChan
-		logger.Info("Received signal, shutting down", "signal", sig.String())
-		cancel()
+		for sig := range sigChan {
+			switch {
+			case sig == syscall.SIGHUP:
+				logger.Info("Received SIGHUP, reloading configuration")
+				reloader.reload()
+			case ctx.Err() != nil:
+				logger.Warn("Received second signal, exiting without finishing shutdown", "signal", sig.String())
+				hookSystem.KillRunning()
+				os.Exit(1)
+			default:
+				logger.Info("Received signal, shutting down", "signal", sig.String())
+				cancel()
+			}
+		}
 	}()
 
 	<-ctx.Done()
 
-	logger.Info("Executing ToDestroy hook")
-	if err := hookSystem.ExecuteHook(ctx, "ToDestroy"); err != nil {
-		logger.Error("ToDestroy hook failed", "error", err)
-	} else {
-		logger.Info("ToDestroy hook completed successfully")
+	// ctx is already canceled, so the shutdown gets a context of its own.
+	// A leader hands over first, a Master releases the VIP with ToSlave,
+	// then ToDestroy runs and Raft stops.
+	shutdown := reloader.current().Shutdown
+	if err := notifier.Notify(systemd.Stopping, systemd.Status("Stopping")); err != nil {
+		logger.Warn("Failed to notify systemd", "error", err)
 	}
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdown.Timeout)
+	defer cancelShutdown()
+	stateMachine.Shutdown(shutdownCtx, shutdown.HandoffTimeout)
+	raftNode.Shutdown()
 
 	logger.Info("VIP-Switch shutdown complete")
 }
 
-func initLogger(cfg config.LoggingConfig) *slog.Logger {
-	var logLevel slog.Level
-	switch strings.ToLower(cfg.Level) {
-	case "debug":
-		logLevel = slog.LevelDebug
-	case "info":
-		logLevel = slog.LevelInfo
-	case "warn":
-		logLevel = slog.LevelWarn
-	case "error":
-		logLevel = slog.LevelError
-	default:
-		logLevel = slog.LevelInfo
+// initMetrics routes metrics, including those emitted by Raft, into an
+// in-memory sink. Sending SIGUSR1 dumps the current values to stderr.
+func initMetrics(logger *slog.Logger) {
+	sink := metrics.NewInmemSink(10*time.Second, time.Minute)
+	metrics.DefaultInmemSignal(sink)
+
+	metricsCfg := metrics.DefaultConfig("vip-switch")
+	metricsCfg.EnableHostname = false
+	if _, err := metrics.NewGlobal(metricsCfg, sink); err != nil {
+		logger.Warn("Failed to initialize metrics", "error", err)
 	}
+}
+
+// initLogger creates the daemon's logger. Its level is read from level, so
+// that a reload can change it, and values in secrets are redacted from it.
+func initLogger(cfg config.LoggingConfig, level *slog.LevelVar, secrets *redact.Secrets) *slog.Logger {
+	level.Set(parseLevel(cfg.Level))
 
 	var output io.Writer = os.Stdout
 	if cfg.Output != "" {
@@ -202,7 +370,7 @@ func initLogger(cfg config.LoggingConfig) *slog.Logger {
 
 	var handler slog.Handler
 	opts := &slog.HandlerOptions{
-		Level: logLevel,
+		Level: level,
 	}
 
 	if strings.ToLower(cfg.Format) == "json" {
@@ -211,5 +379,19 @@ func initLogger(cfg config.LoggingConfig) *slog.Logger {
 		handler = slog.NewTextHandler(output, opts)
 	}
 
-	return slog.New(handler)
+	return slog.New(redact.NewHandler(handler, secrets))
+}
+
+// parseLevel returns the slog level for a logging.level value
+func parseLevel(name string) slog.Level {
+	switch strings.ToLower(name) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
 }
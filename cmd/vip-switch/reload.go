@@ -0,0 +1,166 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"vip-switch-go/internal/admin"
+	"vip-switch-go/internal/config"
+	"vip-switch-go/internal/hook"
+	"vip-switch-go/internal/state"
+	"vip-switch-go/internal/track"
+)
+
+// newReloadCmd creates the reload command
+func newReloadCmd() *cobra.Command {
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "reload",
+		Short: "Reload the running daemon's configuration",
+		Long: `Ask the running daemon to re-read its configuration file, the same as sending it
+SIGHUP. Hooks, the log level, targets and health checks, and score settings
+take effect immediately; other changes are reported and wait for a restart.
+A configuration that is invalid or changes the node's identity is rejected
+and the running configuration kept.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			result, err := admin.NewClient(cfg.AdminSocketPath()).Reload(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			if asJSON {
+				return json.NewEncoder(cmd.OutOrStdout()).Encode(result)
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Applied:          %s\n", orNone(strings.Join(result.Applied, ", ")))
+			fmt.Fprintf(out, "Restart required: %s\n", orNone(strings.Join(result.RestartRequired, ", ")))
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print the result as JSON")
+	return cmd
+}
+
+// reloader applies configuration changes to the running daemon
+type reloader struct {
+	ctx         context.Context
+	logLevel    *slog.LevelVar
+	hooks       *hook.System
+	eligibility *track.Eligibility
+	balancer    *state.Balancer
+	logger      *slog.Logger
+
+	mu     sync.Mutex
+	config *config.Config
+	probes *track.ProbeWatcher // nil while nothing is probed
+}
+
+// reload re-reads the configuration file and applies what changed. On any
+// error the running configuration stays in place.
+func (r *reloader) reload() (*config.ReloadResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := loadConfig()
+	if err != nil {
+		r.logger.Error("Reload rejected", "error", err)
+		return nil, err
+	}
+	warnIgnoredEnv(r.logger, next)
+	merged, result, err := r.config.Reload(next)
+	if err != nil {
+		r.logger.Error("Reload rejected", "error", err)
+		return nil, err
+	}
+
+	// Hooks read hooks and security from the configuration on every run
+	r.hooks.SetConfig(merged)
+	if err := r.hooks.LoadSecrets(merged); err != nil {
+		r.logger.Warn("Failed to read hook secrets", "error", err)
+	}
+
+	probesChanged := false
+	for _, name := range result.Applied {
+		switch name {
+		case "logging.level":
+			r.logLevel.Set(parseLevel(merged.Logging.Level))
+		case "track.targets", "track.checks":
+			probesChanged = true
+		case "score.priority":
+			r.eligibility.SetPriority(merged.Score.Priority)
+		case "score.balance", "score.margin":
+			r.balancer.SetConfig(merged.Score)
+		}
+	}
+	if probesChanged {
+		r.restartProbes(merged.Track)
+	}
+	r.config = merged
+
+	r.logger.Info("Configuration reloaded", "applied", result.Applied, "restart_required", result.RestartRequired)
+	if len(result.RestartRequired) > 0 {
+		r.logger.Warn("Some changes take effect only after a restart", "settings", result.RestartRequired)
+	}
+	return result, nil
+}
+
+// current returns the configuration the daemon runs with
+func (r *reloader) current() *config.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.config
+}
+
+// restartProbes replaces the probe watcher with one for the new targets and
+// checks, dropping the state of items no longer tracked
+func (r *reloader) restartProbes(cfg config.TrackConfig) {
+	var old []string
+	if r.probes != nil {
+		r.probes.Stop()
+		old = r.probes.Sources()
+		r.probes = nil
+	}
+
+	var current []string
+	if len(cfg.Targets) > 0 || len(cfg.Checks) > 0 {
+		r.probes = track.NewProbeWatcher(cfg.Targets, cfg.Checks, r.eligibility, r.logger)
+		current = r.probes.Sources()
+	}
+	for _, source := range old {
+		if !slices.Contains(current, source) {
+			r.eligibility.Forget(source)
+		}
+	}
+	if r.probes != nil {
+		r.probes.Start(r.ctx)
+	}
+	r.logger.Info("Tracking targets and checks", "targets", len(cfg.Targets), "checks", len(cfg.Checks))
+}
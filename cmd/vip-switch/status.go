@@ -0,0 +1,74 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"vip-switch-go/internal/admin"
+)
+
+// newStatusCmd creates the status command
+func newStatusCmd() *cobra.Command {
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the node's state and the cluster's scores",
+		Long: `Show the running daemon's state, the Raft leader and the Master scores of this
+node and the other voters, queried over the admin socket.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+
+			status, err := admin.NewClient(cfg.AdminSocketPath()).Status(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			if asJSON {
+				return json.NewEncoder(cmd.OutOrStdout()).Encode(status)
+			}
+			printStatus(cmd.OutOrStdout(), status)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print the status as JSON")
+	return cmd
+}
+
+// printStatus prints a status with a table of scores
+func printStatus(out io.Writer, status *admin.Status) {
+	fmt.Fprintf(out, "Node:    %s\n", status.NodeID)
+	fmt.Fprintf(out, "State:   %s\n", status.State)
+	fmt.Fprintf(out, "Leader:  %s\n", orNone(status.Leader))
+	fmt.Fprintf(out, "Term:    %d\n\n", status.Term)
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tSCORE\tELIGIBLE\tREASONS")
+	for _, score := range status.Scores {
+		fmt.Fprintf(w, "%s\t%d\t%t\t%s\n", score.NodeID, score.Score, score.Eligible, orNone(strings.Join(score.Reasons, "; ")))
+	}
+	w.Flush()
+}
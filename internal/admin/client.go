@@ -0,0 +1,126 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"vip-switch-go/internal/config"
+	"vip-switch-go/internal/hook"
+)
+
+// Client talks to a running daemon's admin API
+type Client struct {
+	http *http.Client
+}
+
+// NewClient creates a client for the admin socket at socketPath
+func NewClient(socketPath string) *Client {
+	return &Client{
+		http: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socketPath)
+				},
+			},
+		},
+	}
+}
+
+// Status returns the daemon's status
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	var status Status
+	if err := c.get(ctx, "/v1/status", &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Reload asks the daemon to reload its configuration file
+func (c *Client) Reload(ctx context.Context) (*config.ReloadResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://vip-switch/v1/reload", nil)
+	if err != nil {
+		return nil, err
+	}
+	var result config.ReloadResult
+	if err := c.do(req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// HookHistory queries the daemon's hook history
+func (c *Client) HookHistory(ctx context.Context, filter hook.HistoryFilter) ([]hook.HistoryRecord, error) {
+	query := url.Values{}
+	if filter.Event != "" {
+		query.Set("event", filter.Event)
+	}
+	if !filter.Since.IsZero() {
+		query.Set("since", filter.Since.Format(time.RFC3339Nano))
+	}
+	if filter.Limit > 0 {
+		query.Set("limit", strconv.Itoa(filter.Limit))
+	}
+
+	var records []hook.HistoryRecord
+	if err := c.get(ctx, "/v1/hooks/history?"+query.Encode(), &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// get performs a GET request and decodes the JSON response into v
+func (c *Client) get(ctx context.Context, path string, v any) error {
+	// The host is ignored, requests always go to the socket
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://vip-switch"+path, nil)
+	if err != nil {
+		return err
+	}
+	return c.do(req, v)
+}
+
+// do sends req and decodes a JSON response, turning error bodies into errors
+func (c *Client) do(req *http.Request, v any) error {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("admin API unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		if body.Error == "" {
+			body.Error = resp.Status
+		}
+		return fmt.Errorf("admin API: %s", body.Error)
+	}
+
+	if v == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,216 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"vip-switch-go/internal/config"
+	"vip-switch-go/internal/hook"
+	"vip-switch-go/internal/track"
+)
+
+// Server is the local admin API. It listens on a unix socket only, so access
+// is controlled by file permissions.
+type Server struct {
+	socketPath string
+	logger     *slog.Logger
+	mux        *http.ServeMux
+	server     *http.Server
+	listener   net.Listener // passed in by SetListener, nil to create the socket
+	history    *hook.History
+	status     func() Status
+	reload     func() (*config.ReloadResult, error)
+}
+
+// Status is the daemon's view of itself and the cluster
+type Status struct {
+	NodeID string        `json:"node_id"`
+	State  string        `json:"state"`
+	Leader string        `json:"leader"` // node ID of the Raft leader, empty if unknown
+	Term   uint64        `json:"term"`
+	Scores []track.Score `json:"scores"` // this node first, then the other voters
+}
+
+// NewServer creates an admin API server for socketPath
+func NewServer(socketPath string, logger *slog.Logger) *Server {
+	s := &Server{
+		socketPath: socketPath,
+		logger:     logger,
+		mux:        http.NewServeMux(),
+	}
+	s.mux.HandleFunc("GET /v1/status", s.handleStatus)
+	s.mux.HandleFunc("GET /v1/hooks/history", s.handleHookHistory)
+	s.mux.HandleFunc("POST /v1/reload", s.handleReload)
+	s.server = &http.Server{
+		Handler:           s.mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return s
+}
+
+// SetHistory sets the hook journal served by the history endpoint
+func (s *Server) SetHistory(history *hook.History) {
+	s.history = history
+}
+
+// SetStatus sets the function reporting the daemon's status
+func (s *Server) SetStatus(status func() Status) {
+	s.status = status
+}
+
+// SetReload sets the function reloading the daemon's configuration
+func (s *Server) SetReload(reload func() (*config.ReloadResult, error)) {
+	s.reload = reload
+}
+
+// SetListener makes Start serve on listener, e.g. a socket passed by
+// systemd socket activation, instead of creating the socket itself
+func (s *Server) SetListener(listener net.Listener) {
+	s.listener = listener
+}
+
+// Start listens on the socket and serves requests in the background
+func (s *Server) Start() error {
+	if s.listener != nil {
+		s.serve(s.listener)
+		s.logger.Info("Admin API listening on an inherited socket", "addr", s.listener.Addr().String())
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.socketPath), 0755); err != nil {
+		return fmt.Errorf("failed to create admin socket directory: %w", err)
+	}
+	// A socket left behind by an unclean exit would make Listen fail
+	if err := os.Remove(s.socketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale admin socket: %w", err)
+	}
+
+	listener, err := net.Listen("unix", s.socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on admin socket: %w", err)
+	}
+	if err := os.Chmod(s.socketPath, 0600); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to restrict admin socket: %w", err)
+	}
+
+	s.serve(listener)
+	s.logger.Info("Admin API listening", "socket", s.socketPath)
+	return nil
+}
+
+// serve serves requests on listener in the background
+func (s *Server) serve(listener net.Listener) {
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("Admin API stopped", "error", err)
+		}
+	}()
+}
+
+// Shutdown stops the server and removes the socket it created. An
+// inherited socket belongs to systemd and is left in place.
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.server.Shutdown(ctx)
+	if s.listener == nil {
+		os.Remove(s.socketPath)
+	}
+	return err
+}
+
+// handleStatus serves the daemon's status
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if s.status == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("status is not available"))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.status())
+}
+
+// handleHookHistory serves hook runs filtered by the event, since and
+// limit query parameters
+func (s *Server) handleHookHistory(w http.ResponseWriter, r *http.Request) {
+	if s.history == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("hook history is not available"))
+		return
+	}
+
+	query := r.URL.Query()
+	filter := hook.HistoryFilter{Event: query.Get("event")}
+	if since := query.Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid since: %w", err))
+			return
+		}
+		filter.Since = t
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit: %s", limit))
+			return
+		}
+		filter.Limit = n
+	}
+
+	records, err := s.history.Query(filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if records == nil {
+		records = []hook.HistoryRecord{}
+	}
+	writeJSON(w, http.StatusOK, records)
+}
+
+// handleReload reloads the configuration and reports what changed. A
+// configuration that cannot be applied leaves the running one in place.
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	if s.reload == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("reload is not available"))
+		return
+	}
+	result, err := s.reload()
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes err as a JSON error response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,175 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"vip-switch-go/internal/config"
+	"vip-switch-go/internal/hook"
+	"vip-switch-go/internal/track"
+)
+
+// startTestServer starts a server on a socket in a temp directory
+func startTestServer(t *testing.T) (*Server, *Client) {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "admin.sock")
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	server := NewServer(socket, logger)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start() unexpected error: %v", err)
+	}
+	t.Cleanup(func() { server.Shutdown(context.Background()) })
+
+	return server, NewClient(socket)
+}
+
+func TestServer_SocketPermissions(t *testing.T) {
+	server, _ := startTestServer(t)
+
+	info, err := os.Stat(server.socketPath)
+	if err != nil {
+		t.Fatalf("failed to stat socket: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("socket mode = %o, want 600", perm)
+	}
+}
+
+func TestServer_SetListener(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "inherited.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("Listen() unexpected error: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	server := NewServer(filepath.Join(t.TempDir(), "admin.sock"), logger)
+	server.SetListener(listener)
+	server.SetStatus(func() Status { return Status{NodeID: "node1", State: "Slave"} })
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start() unexpected error: %v", err)
+	}
+
+	status, err := NewClient(socket).Status(context.Background())
+	if err != nil || status.State != "Slave" {
+		t.Errorf("Status() over the inherited socket = %+v, %v, want Slave", status, err)
+	}
+	if _, err := os.Stat(server.socketPath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Start() with a listener created %s", server.socketPath)
+	}
+	server.Shutdown(context.Background())
+}
+
+func TestServer_HookHistory(t *testing.T) {
+	server, client := startTestServer(t)
+
+	history, err := hook.OpenHistory(filepath.Join(t.TempDir(), "history.jsonl"), 100, 0)
+	if err != nil {
+		t.Fatalf("OpenHistory() unexpected error: %v", err)
+	}
+	server.SetHistory(history)
+
+	now := time.Now().UTC()
+	for _, rec := range []hook.HistoryRecord{
+		{Event: "ToMaster", StartedAt: now.Add(-2 * time.Hour), Success: true},
+		{Event: "ToSlave", StartedAt: now.Add(-time.Hour), Success: true},
+		{Event: "ToMaster", StartedAt: now, Error: "boom"},
+	} {
+		if err := history.Append(&rec); err != nil {
+			t.Fatalf("Append() unexpected error: %v", err)
+		}
+	}
+
+	records, err := client.HookHistory(context.Background(), hook.HistoryFilter{Event: "ToMaster"})
+	if err != nil {
+		t.Fatalf("HookHistory() unexpected error: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("HookHistory() returned %d records, want 2", len(records))
+	}
+
+	records, err = client.HookHistory(context.Background(), hook.HistoryFilter{Since: now.Add(-90 * time.Minute)})
+	if err != nil {
+		t.Fatalf("HookHistory() unexpected error: %v", err)
+	}
+	if len(records) != 2 || records[1].Error != "boom" {
+		t.Errorf("HookHistory() with since = %+v, want the last 2 records", records)
+	}
+}
+
+func TestServer_HookHistoryUnavailable(t *testing.T) {
+	_, client := startTestServer(t)
+
+	_, err := client.HookHistory(context.Background(), hook.HistoryFilter{})
+	if err == nil || !strings.Contains(err.Error(), "not available") {
+		t.Errorf("HookHistory() error = %v, want 'not available'", err)
+	}
+}
+
+func TestServer_Status(t *testing.T) {
+	server, client := startTestServer(t)
+	server.SetStatus(func() Status {
+		return Status{
+			NodeID: "node1",
+			State:  "Master",
+			Leader: "node1",
+			Term:   3,
+			Scores: []track.Score{{NodeID: "node1", Score: 100, Eligible: true}},
+		}
+	})
+
+	status, err := client.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status() unexpected error: %v", err)
+	}
+	if status.State != "Master" || status.Term != 3 || len(status.Scores) != 1 || status.Scores[0].Score != 100 {
+		t.Errorf("Status() = %+v, want the reported status", status)
+	}
+}
+
+func TestServer_Reload(t *testing.T) {
+	server, client := startTestServer(t)
+
+	var reloadErr error
+	server.SetReload(func() (*config.ReloadResult, error) {
+		if reloadErr != nil {
+			return nil, reloadErr
+		}
+		return &config.ReloadResult{Applied: []string{"hooks"}, RestartRequired: []string{}}, nil
+	})
+
+	result, err := client.Reload(context.Background())
+	if err != nil {
+		t.Fatalf("Reload() unexpected error: %v", err)
+	}
+	if len(result.Applied) != 1 || result.Applied[0] != "hooks" {
+		t.Errorf("Reload() = %+v, want hooks applied", result)
+	}
+
+	reloadErr = errors.New("node.id cannot change while running")
+	if _, err := client.Reload(context.Background()); err == nil || !strings.Contains(err.Error(), "node.id cannot change") {
+		t.Errorf("Reload() error = %v, want the rejection", err)
+	}
+}
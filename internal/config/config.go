@@ -15,9 +15,13 @@
 package config
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"net"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -25,11 +29,22 @@ import (
 
 // Config represents the complete configuration
 type Config struct {
-	Node     NodeConfig    `yaml:"node"`
-	Cluster  ClusterConfig `yaml:"cluster"`
-	Hooks    HooksConfig   `yaml:"hooks"`
-	Logging  LoggingConfig `yaml:"logging"`
+	Node     NodeConfig     `yaml:"node"`
+	Cluster  ClusterConfig  `yaml:"cluster"`
+	Storage  StorageConfig  `yaml:"storage"`
+	VIP      VIPConfig      `yaml:"vip"`
+	Hooks    HooksConfig    `yaml:"hooks"`
+	Track    TrackConfig    `yaml:"track"`
+	Score    ScoreConfig    `yaml:"score"`
+	Security SecurityConfig `yaml:"security"`
+	Admin    AdminConfig    `yaml:"admin"`
+	Shutdown ShutdownConfig `yaml:"shutdown"`
+	Logging  LoggingConfig  `yaml:"logging"`
 	filePath string
+	files    []string          // files read, in merge order
+	sources  map[string]source // where each setting came from, by path
+
+	ignoredEnv []string // VIPSWITCH_* variables that name no setting
 }
 
 // NodeConfig represents node-specific configuration
@@ -50,17 +65,53 @@ type ClusterNode struct {
 	Addr string `yaml:"addr"`
 }
 
+// VIPConfig describes the virtual IP managed by the cluster. The values are
+// handed to hooks; vip-switch binds the address itself only for hooks that
+// list the netlink-vip runner.
+type VIPConfig struct {
+	Address   string `yaml:"address"`   // CIDR, e.g. 192.168.1.100/32
+	Interface string `yaml:"interface"` // e.g. eth0
+}
+
 // HooksConfig represents hooks configuration
 type HooksConfig struct {
-	Enabled   bool           `yaml:"enabled"`
-	Timeout   time.Duration  `yaml:"timeout"`
-	OnFailure string         `yaml:"on_failure"` // abort | continue | retry
-	ToMaster  HookDefinition `yaml:"ToMaster"`
-	ToSlave   HookDefinition `yaml:"ToSlave"`
-	ToReady   HookDefinition `yaml:"ToReady"`
-	ToDestroy HookDefinition `yaml:"ToDestroy"`
+	Enabled         bool           `yaml:"enabled"`
+	Timeout         time.Duration  `yaml:"timeout"`
+	OnFailure       string         `yaml:"on_failure"`        // abort | continue | retry
+	KillSignal      string         `yaml:"kill_signal"`       // sent to the hook's process group on timeout
+	KillGracePeriod time.Duration  `yaml:"kill_grace_period"` // wait before escalating to SIGKILL
+	CgroupParent    string         `yaml:"cgroup_parent"`     // cgroup v2 directory for hook groups, empty to use a delegated one
+	History         HistoryConfig  `yaml:"history"`
+	Ensure          EnsureConfig   `yaml:"ensure"`
+	ToMaster        HookDefinition `yaml:"ToMaster"`
+	ToSlave         HookDefinition `yaml:"ToSlave"`
+	ToReady         HookDefinition `yaml:"ToReady"`
+	ToDestroy       HookDefinition `yaml:"ToDestroy"`
+	EnsureMaster    HookDefinition `yaml:"EnsureMaster"` // checks the VIP while Master, non-zero exit means drift
+	EnsureSlave     HookDefinition `yaml:"EnsureSlave"`  // checks the VIP while Slave, non-zero exit means drift
+}
+
+// EnsureConfig controls the periodic reconciliation of the VIP with the
+// node's role
+type EnsureConfig struct {
+	Interval    time.Duration `yaml:"interval"`     // 0 disables reconciliation
+	NativeCheck bool          `yaml:"native_check"` // check vip.address on vip.interface directly
+}
+
+// HistoryConfig controls the journal of hook runs kept in data_dir
+type HistoryConfig struct {
+	MaxRecords int           `yaml:"max_records"` // oldest runs are dropped beyond this
+	MaxAge     time.Duration `yaml:"max_age"`     // runs older than this are dropped
+	OutputTail int           `yaml:"output_tail"` // bytes of stdout and stderr kept per attempt
 }
 
+// Hook history defaults
+const (
+	DefaultHistoryMaxRecords = 1000
+	DefaultHistoryMaxAge     = 30 * 24 * time.Hour
+	DefaultHistoryOutputTail = 4096
+)
+
 // HookDefinition defines a single hook
 type HookDefinition struct {
 	Command     string            `yaml:"command"`
@@ -68,8 +119,374 @@ type HookDefinition struct {
 	Timeout     time.Duration     `yaml:"timeout"`
 	OnFailure   string            `yaml:"on_failure"` // abort | continue | retry
 	Environment map[string]string `yaml:"environment"`
+	Retry       RetryPolicy       `yaml:"retry"`
+
+	// EnvironmentFromFile maps variable names to files holding their
+	// values, e.g. API tokens. The files are read on every run and their
+	// contents are redacted from logs and the hook history.
+	EnvironmentFromFile map[string]string `yaml:"environment_from_file"`
+
+	KillSignal      string        `yaml:"kill_signal"`
+	KillGracePeriod time.Duration `yaml:"kill_grace_period"`
+
+	// Process identity. Without User the hook runs as the daemon.
+	User         string   `yaml:"user"`
+	Group        string   `yaml:"group"`
+	WorkingDir   string   `yaml:"working_dir"`
+	Umask        string   `yaml:"umask"`        // octal, e.g. "0027"
+	InheritEnv   []string `yaml:"inherit_env"`  // daemon variables passed through, e.g. PATH
+	Capabilities []string `yaml:"capabilities"` // ambient capabilities, e.g. CAP_NET_ADMIN
+
+	Limits ResourceLimits `yaml:"limits"`
+
+	// Runners lists what runs for the event, in order, see RunnerNames.
+	// Without it the hook runs Command, if set.
+	Runners []string `yaml:"runners"`
+	HTTP    HTTPHook `yaml:"http"` // used by the http runner
+
+	templates *hookTemplates // parsed args and environment, set by Load
 }
 
+// HTTPHook configures the http runner, which posts the event payload
+type HTTPHook struct {
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	// HeadersFromFile reads header values, such as a bearer token, from
+	// files by absolute path
+	HeadersFromFile map[string]string `yaml:"headers_from_file"`
+}
+
+// ResourceLimits bounds the resources a hook's process tree may use. They are
+// enforced through a transient cgroup v2 group, or rlimits where unavailable.
+type ResourceLimits struct {
+	MemoryMax string `yaml:"memory_max"` // bytes with optional K, M or G suffix
+	CPUWeight int    `yaml:"cpu_weight"` // 1 to 10000, 100 is the kernel default
+	CPUQuota  string `yaml:"cpu_quota"`  // share of one CPU, e.g. "50%" or "200%"
+	PidsMax   int    `yaml:"pids_max"`
+	NoFile    uint64 `yaml:"nofile"` // open file limit, always applied as an rlimit
+}
+
+// IsZero reports whether no limit is set
+func (r *ResourceLimits) IsZero() bool {
+	return r.MemoryMax == "" && r.CPUWeight == 0 && r.CPUQuota == "" && r.PidsMax == 0 && r.NoFile == 0
+}
+
+// validate checks that all limits parse
+func (r *ResourceLimits) validate() error {
+	if _, err := ParseByteSize(r.MemoryMax); err != nil {
+		return fmt.Errorf("limits.memory_max: %w", err)
+	}
+	if _, err := ParseCPUQuota(r.CPUQuota); err != nil {
+		return fmt.Errorf("limits.cpu_quota: %w", err)
+	}
+	if r.CPUWeight != 0 && (r.CPUWeight < 1 || r.CPUWeight > 10000) {
+		return fmt.Errorf("limits.cpu_weight must be between 1 and 10000")
+	}
+	if r.PidsMax < 0 {
+		return fmt.Errorf("limits.pids_max must not be negative")
+	}
+	return nil
+}
+
+// SecurityConfig controls the checks applied before a hook is run
+type SecurityConfig struct {
+	// SanitizeEnvironment drops hook environment variables whose names do
+	// not start with one of AllowedEnvPrefixes
+	SanitizeEnvironment bool     `yaml:"sanitize_environment"`
+	AllowedEnvPrefixes  []string `yaml:"allowed_env_prefixes"`
+	// ValidateCommandPath rejects hook commands outside SafeDirs
+	ValidateCommandPath bool     `yaml:"validate_command_path"`
+	SafeDirs            []string `yaml:"safe_dirs"`
+}
+
+// RetryPolicy controls how a hook with the retry failure strategy is re-run
+type RetryPolicy struct {
+	MaxAttempts        int           `yaml:"max_attempts"` // total attempts, including the first
+	InitialBackoff     time.Duration `yaml:"initial_backoff"`
+	MaxBackoff         time.Duration `yaml:"max_backoff"`
+	Multiplier         float64       `yaml:"multiplier"`
+	Jitter             *float64      `yaml:"jitter"`          // fraction of the backoff, 0 to 1; nil for the default
+	AttemptTimeout     time.Duration `yaml:"attempt_timeout"` // defaults to the hook timeout
+	Deadline           time.Duration `yaml:"deadline"`        // bounds all attempts, 0 for none
+	RetryableExitCodes []int         `yaml:"retryable_exit_codes"`
+}
+
+// Default retry policy values
+const (
+	DefaultRetryMaxAttempts    = 3
+	DefaultRetryInitialBackoff = 1 * time.Second
+	DefaultRetryMaxBackoff     = 30 * time.Second
+	DefaultRetryMultiplier     = 2.0
+	DefaultRetryJitter         = 0.2
+)
+
+// IsRetryableExitCode reports whether a failed attempt with the given exit
+// code may be retried. An empty RetryableExitCodes list retries any failure.
+func (p *RetryPolicy) IsRetryableExitCode(code int) bool {
+	if len(p.RetryableExitCodes) == 0 {
+		return true
+	}
+	for _, c := range p.RetryableExitCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// JitterFraction returns the jitter as a fraction of the backoff. An
+// explicit 0 turns jitter off; unset means DefaultRetryJitter.
+func (p *RetryPolicy) JitterFraction() float64 {
+	if p.Jitter == nil {
+		return DefaultRetryJitter
+	}
+	return *p.Jitter
+}
+
+// applyDefaults fills unset retry values, using hookTimeout for attempts
+func (p *RetryPolicy) applyDefaults(hookTimeout time.Duration) {
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = DefaultRetryMaxAttempts
+	}
+	if p.InitialBackoff == 0 {
+		p.InitialBackoff = DefaultRetryInitialBackoff
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = DefaultRetryMaxBackoff
+	}
+	if p.Multiplier == 0 {
+		p.Multiplier = DefaultRetryMultiplier
+	}
+	if p.Jitter == nil {
+		jitter := DefaultRetryJitter
+		p.Jitter = &jitter
+	}
+	if p.AttemptTimeout == 0 {
+		p.AttemptTimeout = hookTimeout
+	}
+}
+
+// validate checks retry values that cannot be defaulted
+func (p *RetryPolicy) validate() error {
+	if p.MaxAttempts < 0 {
+		return fmt.Errorf("retry.max_attempts must not be negative")
+	}
+	if p.Multiplier != 0 && p.Multiplier < 1 {
+		return fmt.Errorf("retry.multiplier must be at least 1")
+	}
+	if p.Jitter != nil && (*p.Jitter < 0 || *p.Jitter > 1) {
+		return fmt.Errorf("retry.jitter must be between 0 and 1")
+	}
+	if p.InitialBackoff < 0 || p.MaxBackoff < 0 || p.AttemptTimeout < 0 || p.Deadline < 0 {
+		return fmt.Errorf("retry durations must not be negative")
+	}
+	return nil
+}
+
+// EventTypes lists the hook events: transitions in lifecycle order, then
+// the ensure checks
+var EventTypes = []string{"ToReady", "ToMaster", "ToSlave", "ToDestroy", "EnsureMaster", "EnsureSlave"}
+
+// TrackConfig configures the conditions that make the node ineligible to
+// be Master
+type TrackConfig struct {
+	Interfaces []TrackInterface `yaml:"interfaces"` // links whose carrier loss makes the node step down
+	UpDelay    time.Duration    `yaml:"up_delay"`   // how long a link must stay up before the node is eligible again
+	Targets    []TrackTarget    `yaml:"targets"`    // hosts the node must reach, e.g. the default gateway
+	Checks     []TrackCheck     `yaml:"checks"`     // health check commands
+}
+
+// Weights of tracked items follow keepalived: a positive weight is added to
+// the node's score while the item is up, a negative one while it is down,
+// and a zero weight makes the node ineligible while the item is down.
+
+// TrackInterface is a tracked link. It may be given as just its name.
+type TrackInterface struct {
+	Name   string `yaml:"name"`
+	Weight int    `yaml:"weight"`
+}
+
+// UnmarshalYAML accepts either an interface name or a mapping
+func (t *TrackInterface) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&t.Name)
+	}
+	type plain TrackInterface
+	return value.Decode((*plain)(t))
+}
+
+// TrackCheck is a command run periodically, healthy while it exits zero
+type TrackCheck struct {
+	Name     string        `yaml:"name"`
+	Command  string        `yaml:"command"` // absolute path
+	Args     []string      `yaml:"args"`
+	Interval time.Duration `yaml:"interval"`
+	Timeout  time.Duration `yaml:"timeout"`
+	Rise     int           `yaml:"rise"`
+	Fall     int           `yaml:"fall"`
+	Weight   int           `yaml:"weight"`
+}
+
+// setDefaults fills in the unset check settings
+func (c *TrackCheck) setDefaults() {
+	if c.Name == "" {
+		c.Name = filepath.Base(c.Command)
+	}
+	if c.Interval == 0 {
+		c.Interval = DefaultTrackTargetInterval
+	}
+	if c.Timeout == 0 {
+		c.Timeout = DefaultTrackTargetTimeout
+	}
+	if c.Rise == 0 {
+		c.Rise = DefaultTrackTargetRise
+	}
+	if c.Fall == 0 {
+		c.Fall = DefaultTrackTargetFall
+	}
+}
+
+// validate checks a health check after defaults are set
+func (c *TrackCheck) validate() error {
+	if !filepath.IsAbs(c.Command) {
+		return fmt.Errorf("command must be an absolute path")
+	}
+	if c.Interval < 0 || c.Timeout < 0 || c.Rise < 0 || c.Fall < 0 {
+		return fmt.Errorf("interval, timeout, rise and fall must not be negative")
+	}
+	if c.Timeout > c.Interval {
+		return fmt.Errorf("timeout must not exceed interval")
+	}
+	return nil
+}
+
+// TrackTarget is a host probed periodically. The node is ineligible while
+// the target is down.
+type TrackTarget struct {
+	Name      string        `yaml:"name"`
+	Address   string        `yaml:"address"`   // IP address
+	Type      string        `yaml:"type"`      // icmp | tcp
+	Port      int           `yaml:"port"`      // required for tcp
+	Interface string        `yaml:"interface"` // probe through this interface, empty for any
+	Interval  time.Duration `yaml:"interval"`
+	Timeout   time.Duration `yaml:"timeout"`
+	Rise      int           `yaml:"rise"` // consecutive successes before a down target is up
+	Fall      int           `yaml:"fall"` // consecutive failures before an up target is down
+	Weight    int           `yaml:"weight"`
+}
+
+// Tracking defaults
+const (
+	DefaultTrackUpDelay        = 5 * time.Second
+	DefaultTrackTargetInterval = 2 * time.Second
+	DefaultTrackTargetTimeout  = time.Second
+	DefaultTrackTargetRise     = 3
+	DefaultTrackTargetFall     = 3
+)
+
+// setDefaults fills in the unset probe settings
+func (t *TrackTarget) setDefaults() {
+	if t.Name == "" {
+		t.Name = t.Address
+	}
+	if t.Type == "" {
+		t.Type = "icmp"
+	}
+	if t.Interval == 0 {
+		t.Interval = DefaultTrackTargetInterval
+	}
+	if t.Timeout == 0 {
+		t.Timeout = DefaultTrackTargetTimeout
+	}
+	if t.Rise == 0 {
+		t.Rise = DefaultTrackTargetRise
+	}
+	if t.Fall == 0 {
+		t.Fall = DefaultTrackTargetFall
+	}
+}
+
+// validate checks a target after defaults are set
+func (t *TrackTarget) validate() error {
+	if net.ParseIP(t.Address) == nil {
+		return fmt.Errorf("invalid address: %q", t.Address)
+	}
+	switch t.Type {
+	case "icmp":
+	case "tcp":
+		if t.Port < 1 || t.Port > 65535 {
+			return fmt.Errorf("tcp targets need a port between 1 and 65535")
+		}
+	default:
+		return fmt.Errorf("invalid type: %s (must be icmp or tcp)", t.Type)
+	}
+	if t.Interval < 0 || t.Timeout < 0 || t.Rise < 0 || t.Fall < 0 {
+		return fmt.Errorf("interval, timeout, rise and fall must not be negative")
+	}
+	if t.Timeout > t.Interval {
+		return fmt.Errorf("timeout must not exceed interval")
+	}
+	return nil
+}
+
+// ScoreConfig configures the node's Master score and leadership balancing
+type ScoreConfig struct {
+	Priority int           `yaml:"priority"` // base score before track weights
+	Balance  bool          `yaml:"balance"`  // hand leadership to a voter with a higher score
+	Margin   int           `yaml:"margin"`   // lead another voter needs over the leader to take over
+	Interval time.Duration `yaml:"interval"` // how often the leader compares scores
+}
+
+// Score defaults
+const (
+	DefaultScorePriority = 100
+	DefaultScoreMargin   = 10
+	DefaultScoreInterval = 5 * time.Second
+)
+
+// AdminConfig configures the local admin API
+type AdminConfig struct {
+	Socket string `yaml:"socket"` // unix socket path, defaults to admin.sock in data_dir
+}
+
+// ShutdownConfig bounds the steps of a graceful shutdown
+type ShutdownConfig struct {
+	HandoffTimeout time.Duration `yaml:"handoff_timeout"` // wait for another node to take over leadership
+	Timeout        time.Duration `yaml:"timeout"`         // bounds the whole shutdown, handoff and hooks included
+}
+
+// Shutdown defaults
+const (
+	DefaultShutdownHandoffTimeout = 10 * time.Second
+	DefaultShutdownTimeout        = 60 * time.Second
+)
+
+// StorageConfig selects where Raft keeps its log, its stable state and its
+// snapshots, and how much of them it keeps
+type StorageConfig struct {
+	Backend           string        `yaml:"backend"`            // bolt | bbolt | inmem
+	RetainSnapshots   int           `yaml:"retain_snapshots"`   // snapshots kept in data_dir
+	TrailingLogs      uint64        `yaml:"trailing_logs"`      // log entries kept after a snapshot
+	SnapshotInterval  time.Duration `yaml:"snapshot_interval"`  // how often to check whether to snapshot
+	SnapshotThreshold uint64        `yaml:"snapshot_threshold"` // new log entries that trigger a snapshot
+}
+
+// Storage backends
+const (
+	StorageBolt  = "bolt"  // raft.db through boltdb
+	StorageBBolt = "bbolt" // raft.db through go.etcd.io/bbolt, the maintained fork
+	StorageInmem = "inmem" // memory only, the node rejoins as a fresh node on restart
+)
+
+// Storage defaults
+const (
+	DefaultStorageBackend           = StorageBolt
+	DefaultStorageRetainSnapshots   = 1
+	DefaultStorageTrailingLogs      = 10240
+	DefaultStorageSnapshotInterval  = 30 * time.Second
+	DefaultStorageSnapshotThreshold = 2
+)
+
 // LoggingConfig represents logging configuration
 type LoggingConfig struct {
 	Level  string `yaml:"level"`  // debug | info | warn | error
@@ -79,17 +496,29 @@ type LoggingConfig struct {
 
 // Load loads configuration from a YAML file
 func Load(filePath string) (*Config, error) {
-	data, err := os.ReadFile(filePath)
+	return LoadWithOverlay(filePath, Overlay{})
+}
+
+// LoadWithOverlay loads configuration from a YAML file with the overlay's
+// environment variables and assignments applied over it
+func LoadWithOverlay(filePath string, overlay Overlay) (*Config, error) {
+	l := newLoader()
+	if err := l.load(filePath); err != nil {
+		return nil, err
+	}
+	ignoredEnv, err := overlay.apply(l.root, l.sources)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return nil, fmt.Errorf("failed to apply overrides: %w", err)
 	}
-
 	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	if err := l.root.Decode(&cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
 
 	cfg.filePath = filePath
+	cfg.files = l.files
+	cfg.sources = l.sources
+	cfg.ignoredEnv = ignoredEnv
 
 	// Set defaults
 	if cfg.Hooks.Timeout == 0 {
@@ -98,44 +527,173 @@ func Load(filePath string) (*Config, error) {
 	if cfg.Hooks.OnFailure == "" {
 		cfg.Hooks.OnFailure = "abort"
 	}
+	if cfg.Hooks.KillSignal == "" {
+		cfg.Hooks.KillSignal = "SIGTERM"
+	}
+	if cfg.Hooks.KillGracePeriod == 0 {
+		cfg.Hooks.KillGracePeriod = 5 * time.Second
+	}
+	if cfg.Hooks.History.MaxRecords == 0 {
+		cfg.Hooks.History.MaxRecords = DefaultHistoryMaxRecords
+	}
+	if cfg.Hooks.History.MaxAge == 0 {
+		cfg.Hooks.History.MaxAge = DefaultHistoryMaxAge
+	}
+	if cfg.Hooks.History.OutputTail == 0 {
+		cfg.Hooks.History.OutputTail = DefaultHistoryOutputTail
+	}
+
+	if cfg.Track.UpDelay == 0 {
+		cfg.Track.UpDelay = DefaultTrackUpDelay
+	}
+	for i := range cfg.Track.Targets {
+		cfg.Track.Targets[i].setDefaults()
+	}
+	for i := range cfg.Track.Checks {
+		cfg.Track.Checks[i].setDefaults()
+	}
+	if cfg.Score.Priority == 0 {
+		cfg.Score.Priority = DefaultScorePriority
+	}
+	if cfg.Score.Margin == 0 {
+		cfg.Score.Margin = DefaultScoreMargin
+	}
+	if cfg.Score.Interval == 0 {
+		cfg.Score.Interval = DefaultScoreInterval
+	}
+	cfg.Storage = cfg.Storage.WithDefaults()
+	if cfg.Shutdown.HandoffTimeout == 0 {
+		cfg.Shutdown.HandoffTimeout = DefaultShutdownHandoffTimeout
+	}
+	if cfg.Shutdown.Timeout == 0 {
+		cfg.Shutdown.Timeout = DefaultShutdownTimeout
+	}
 
 	// Validate
 	if err := cfg.validate(); err != nil {
+		var verr *ValidationError
+		if errors.As(err, &verr) {
+			verr.IgnoredEnv = ignoredEnv
+		}
 		return nil, err
 	}
 
 	return &cfg, nil
 }
 
-// validate validates configuration
-func (c *Config) validate() error {
-	if c.Node.ID == "" {
-		return fmt.Errorf("node.id is required")
+// signals lists the signals accepted for kill_signal
+var signals = map[string]syscall.Signal{
+	"SIGTERM": syscall.SIGTERM,
+	"SIGINT":  syscall.SIGINT,
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+	"SIGKILL": syscall.SIGKILL,
+}
+
+// ParseSignal converts a signal name such as "SIGTERM" or "TERM" to a signal.
+// An empty name yields zero, meaning the default signal.
+func ParseSignal(name string) (syscall.Signal, error) {
+	if name == "" {
+		return 0, nil
+	}
+	upper := strings.ToUpper(name)
+	if !strings.HasPrefix(upper, "SIG") {
+		upper = "SIG" + upper
+	}
+	sig, ok := signals[upper]
+	if !ok {
+		return 0, fmt.Errorf("unsupported signal: %s", name)
+	}
+	return sig, nil
+}
+
+// ParseUmask parses an octal umask such as "0027". An empty string yields
+// -1, meaning the daemon's umask is kept.
+func ParseUmask(value string) (int, error) {
+	if value == "" {
+		return -1, nil
 	}
-	if c.Node.RaftAddr == "" {
-		return fmt.Errorf("node.raft_addr is required")
+	umask, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || umask > 0777 {
+		return 0, fmt.Errorf("invalid umask: %s (must be octal, e.g. 0027)", value)
 	}
-	if c.Node.DataDir == "" {
-		return fmt.Errorf("node.data_dir is required")
+	return int(umask), nil
+}
+
+// ParseByteSize parses a size such as "512K", "64M" or "1G" into bytes.
+// An empty string yields zero, meaning no limit.
+func ParseByteSize(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
 	}
 
-	if len(c.Cluster.Nodes) == 0 {
-		return fmt.Errorf("cluster.nodes must have at least one entry")
+	multiplier := int64(1)
+	number := strings.ToUpper(strings.TrimSuffix(strings.TrimSpace(value), "B"))
+	switch {
+	case strings.HasSuffix(number, "K"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(number, "M"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(number, "G"):
+		multiplier = 1 << 30
+	}
+	if multiplier != 1 {
+		number = number[:len(number)-1]
 	}
 
-	// Validate log level
-	validLevels := map[string]bool{"debug": true, "info": true, "warn": true, "error": true}
-	if !validLevels[strings.ToLower(c.Logging.Level)] {
-		return fmt.Errorf("invalid log level: %s (must be debug, info, warn, or error)", c.Logging.Level)
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size: %s (e.g. 64M)", value)
 	}
+	return n * multiplier, nil
+}
 
-	// Validate log format
-	validFormats := map[string]bool{"json": true, "text": true}
-	if !validFormats[strings.ToLower(c.Logging.Format)] {
-		return fmt.Errorf("invalid log format: %s (must be json or text)", c.Logging.Format)
+// ParseCPUQuota parses a CPU share such as "50%" into a fraction of one CPU.
+// An empty string yields zero, meaning no quota.
+func ParseCPUQuota(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	percent, ok := strings.CutSuffix(strings.TrimSpace(value), "%")
+	if !ok {
+		return 0, fmt.Errorf("invalid cpu quota: %s (e.g. 50%%)", value)
 	}
+	n, err := strconv.ParseFloat(percent, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid cpu quota: %s (e.g. 50%%)", value)
+	}
+	return n / 100, nil
+}
 
-	return nil
+// capabilities lists the Linux capabilities a hook may request
+var capabilities = map[string]uintptr{
+	"CAP_CHOWN":            0,
+	"CAP_DAC_OVERRIDE":     1,
+	"CAP_FOWNER":           3,
+	"CAP_KILL":             5,
+	"CAP_SETGID":           6,
+	"CAP_SETUID":           7,
+	"CAP_NET_BIND_SERVICE": 10,
+	"CAP_NET_BROADCAST":    11,
+	"CAP_NET_ADMIN":        12,
+	"CAP_NET_RAW":          13,
+	"CAP_SYS_ADMIN":        21,
+}
+
+// ParseCapability converts a capability name such as "CAP_NET_ADMIN" or
+// "net_admin" to its number
+func ParseCapability(name string) (uintptr, error) {
+	upper := strings.ToUpper(name)
+	if !strings.HasPrefix(upper, "CAP_") {
+		upper = "CAP_" + upper
+	}
+	capability, ok := capabilities[upper]
+	if !ok {
+		return 0, fmt.Errorf("unsupported capability: %s", name)
+	}
+	return capability, nil
 }
 
 // GetClusterPeers returns all peer addresses excluding the current node
@@ -149,21 +707,56 @@ func (c *Config) GetClusterPeers() []string {
 	return peers
 }
 
-// GetHookByEventType returns the hook definition for a given event type
-func (c *Config) GetHookByEventType(eventType string) (*HookDefinition, error) {
-	var hookDef *HookDefinition
+// Files returns the configuration files read, in the order they were merged
+func (c *Config) Files() []string {
+	return c.files
+}
+
+// IgnoredEnv returns the VIPSWITCH_* environment variables that name no
+// setting and were ignored, for the caller to warn about
+func (c *Config) IgnoredEnv() []string {
+	return c.ignoredEnv
+}
+
+// AdminSocketPath returns the admin API socket, by default inside data_dir
+func (c *Config) AdminSocketPath() string {
+	if c.Admin.Socket != "" {
+		return c.Admin.Socket
+	}
+	return filepath.Join(c.Node.DataDir, "admin.sock")
+}
+
+// HookHistoryPath returns the hook history journal inside data_dir
+func (c *Config) HookHistoryPath() string {
+	return filepath.Join(c.Node.DataDir, "hook-history.jsonl")
+}
+
+// hookDefinition returns the raw hook definition for an event type
+func (c *Config) hookDefinition(eventType string) (*HookDefinition, error) {
 	switch eventType {
 	case "ToMaster":
-		hookDef = &c.Hooks.ToMaster
+		return &c.Hooks.ToMaster, nil
 	case "ToSlave":
-		hookDef = &c.Hooks.ToSlave
+		return &c.Hooks.ToSlave, nil
 	case "ToReady":
-		hookDef = &c.Hooks.ToReady
+		return &c.Hooks.ToReady, nil
 	case "ToDestroy":
-		hookDef = &c.Hooks.ToDestroy
+		return &c.Hooks.ToDestroy, nil
+	case "EnsureMaster":
+		return &c.Hooks.EnsureMaster, nil
+	case "EnsureSlave":
+		return &c.Hooks.EnsureSlave, nil
 	default:
 		return nil, fmt.Errorf("unknown event type: %s", eventType)
 	}
+}
+
+// GetHookByEventType returns the hook definition for a given event type
+func (c *Config) GetHookByEventType(eventType string) (*HookDefinition, error) {
+	hookDef, err := c.hookDefinition(eventType)
+	if err != nil {
+		return nil, err
+	}
 
 	// Set defaults from global hooks config if not specified
 	if hookDef.Timeout == 0 {
@@ -172,6 +765,13 @@ func (c *Config) GetHookByEventType(eventType string) (*HookDefinition, error) {
 	if hookDef.OnFailure == "" {
 		hookDef.OnFailure = c.Hooks.OnFailure
 	}
+	if hookDef.KillSignal == "" {
+		hookDef.KillSignal = c.Hooks.KillSignal
+	}
+	if hookDef.KillGracePeriod == 0 {
+		hookDef.KillGracePeriod = c.Hooks.KillGracePeriod
+	}
+	hookDef.Retry.applyDefaults(hookDef.Timeout)
 
 	return hookDef, nil
 }
@@ -17,7 +17,9 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -661,3 +663,482 @@ func TestValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestGetHookByEventType_RetryDefaults(t *testing.T) {
+	jitter := 0.5
+	cfg := &Config{
+		Hooks: HooksConfig{
+			Timeout: 60 * time.Second,
+			ToMaster: HookDefinition{
+				Command: "/usr/local/bin/on-master.sh",
+				Timeout: 30 * time.Second,
+				Retry: RetryPolicy{
+					MaxAttempts: 5,
+					Jitter:      &jitter,
+				},
+			},
+		},
+	}
+
+	hookDef, err := cfg.GetHookByEventType("ToMaster")
+	if err != nil {
+		t.Fatalf("GetHookByEventType() unexpected error: %v", err)
+	}
+
+	retry := hookDef.Retry
+	if retry.MaxAttempts != 5 {
+		t.Errorf("MaxAttempts = %v, want 5", retry.MaxAttempts)
+	}
+	if got := retry.JitterFraction(); got != 0.5 {
+		t.Errorf("JitterFraction() = %v, want 0.5", got)
+	}
+	if retry.InitialBackoff != DefaultRetryInitialBackoff {
+		t.Errorf("InitialBackoff = %v, want %v", retry.InitialBackoff, DefaultRetryInitialBackoff)
+	}
+	if retry.MaxBackoff != DefaultRetryMaxBackoff {
+		t.Errorf("MaxBackoff = %v, want %v", retry.MaxBackoff, DefaultRetryMaxBackoff)
+	}
+	if retry.Multiplier != DefaultRetryMultiplier {
+		t.Errorf("Multiplier = %v, want %v", retry.Multiplier, DefaultRetryMultiplier)
+	}
+	if retry.AttemptTimeout != 30*time.Second {
+		t.Errorf("AttemptTimeout = %v, want the hook timeout 30s", retry.AttemptTimeout)
+	}
+}
+
+func TestRetryPolicy_IsRetryableExitCode(t *testing.T) {
+	empty := RetryPolicy{}
+	if !empty.IsRetryableExitCode(1) {
+		t.Error("IsRetryableExitCode(1) = false with no list, want true")
+	}
+
+	listed := RetryPolicy{RetryableExitCodes: []int{75, 111}}
+	if !listed.IsRetryableExitCode(75) {
+		t.Error("IsRetryableExitCode(75) = false, want true")
+	}
+	if listed.IsRetryableExitCode(1) {
+		t.Error("IsRetryableExitCode(1) = true, want false")
+	}
+}
+
+func TestLoad_RetryJitter(t *testing.T) {
+	tests := []struct {
+		name  string
+		retry string
+		want  float64
+	}{
+		{"unset", "max_attempts: 2", DefaultRetryJitter},
+		{"explicit zero", "jitter: 0", 0},
+		{"set", "jitter: 0.5", 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			content := `
+node:
+  id: node1
+  raft_addr: 127.0.0.1:10001
+  data_dir: ./data/node1
+cluster:
+  nodes:
+    - id: node1
+      addr: 127.0.0.1:10001
+hooks:
+  ToMaster:
+    command: /bin/true
+    retry:
+      ` + tt.retry + `
+logging:
+  level: info
+  format: json
+`
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+
+			cfg, err := Load(path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			hookDef, err := cfg.GetHookByEventType("ToMaster")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := hookDef.Retry.JitterFraction(); got != tt.want {
+				t.Errorf("JitterFraction() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_InvalidRetryPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+node:
+  id: node1
+  raft_addr: 127.0.0.1:10001
+  data_dir: ./data/node1
+cluster:
+  nodes:
+    - id: node1
+      addr: 127.0.0.1:10001
+hooks:
+  ToMaster:
+    command: /bin/true
+    retry:
+      jitter: 1.5
+logging:
+  level: info
+  format: json
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	_, err := Load(path)
+	if err == nil {
+		t.Fatal("Load() expected error for invalid jitter, got nil")
+	}
+	if !strings.Contains(err.Error(), "hooks.ToMaster: retry.jitter") {
+		t.Errorf("Load() error = %v, want to mention hooks.ToMaster: retry.jitter", err)
+	}
+}
+
+func TestParseSignal(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    syscall.Signal
+		wantErr bool
+	}{
+		{name: "", want: 0},
+		{name: "SIGTERM", want: syscall.SIGTERM},
+		{name: "term", want: syscall.SIGTERM},
+		{name: "SIGKILL", want: syscall.SIGKILL},
+		{name: "SIGINT", want: syscall.SIGINT},
+		{name: "SIGBOGUS", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSignal(tt.name)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseSignal(%q) expected error, got nil", tt.name)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("ParseSignal(%q) unexpected error: %v", tt.name, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSignal(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseUmask(t *testing.T) {
+	if umask, err := ParseUmask(""); err != nil || umask != -1 {
+		t.Errorf("ParseUmask(\"\") = %v, %v, want -1, nil", umask, err)
+	}
+	if umask, err := ParseUmask("0027"); err != nil || umask != 027 {
+		t.Errorf("ParseUmask(\"0027\") = %o, %v, want 27, nil", umask, err)
+	}
+	for _, bad := range []string{"0999", "abc", "01000"} {
+		if _, err := ParseUmask(bad); err == nil {
+			t.Errorf("ParseUmask(%q) expected error, got nil", bad)
+		}
+	}
+}
+
+func TestParseCapability(t *testing.T) {
+	if capability, err := ParseCapability("CAP_NET_ADMIN"); err != nil || capability != 12 {
+		t.Errorf("ParseCapability(CAP_NET_ADMIN) = %v, %v, want 12, nil", capability, err)
+	}
+	if capability, err := ParseCapability("net_raw"); err != nil || capability != 13 {
+		t.Errorf("ParseCapability(net_raw) = %v, %v, want 13, nil", capability, err)
+	}
+	if _, err := ParseCapability("CAP_BOGUS"); err == nil {
+		t.Error("ParseCapability(CAP_BOGUS) expected error, got nil")
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{value: "", want: 0},
+		{value: "4096", want: 4096},
+		{value: "512K", want: 512 << 10},
+		{value: "64M", want: 64 << 20},
+		{value: "64MB", want: 64 << 20},
+		{value: "1g", want: 1 << 30},
+		{value: "-1M", wantErr: true},
+		{value: "lots", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseByteSize(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseByteSize(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseByteSize(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCPUQuota(t *testing.T) {
+	if quota, err := ParseCPUQuota(""); err != nil || quota != 0 {
+		t.Errorf("ParseCPUQuota(\"\") = %v, %v, want 0, nil", quota, err)
+	}
+	if quota, err := ParseCPUQuota("50%"); err != nil || quota != 0.5 {
+		t.Errorf("ParseCPUQuota(50%%) = %v, %v, want 0.5, nil", quota, err)
+	}
+	if quota, err := ParseCPUQuota("200%"); err != nil || quota != 2 {
+		t.Errorf("ParseCPUQuota(200%%) = %v, %v, want 2, nil", quota, err)
+	}
+	for _, bad := range []string{"50", "0%", "x%"} {
+		if _, err := ParseCPUQuota(bad); err == nil {
+			t.Errorf("ParseCPUQuota(%q) expected error, got nil", bad)
+		}
+	}
+}
+
+func TestLoad_HistoryAndAdminDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+node:
+  id: node1
+  raft_addr: 127.0.0.1:10001
+  data_dir: /var/lib/vip-switch
+cluster:
+  nodes:
+    - id: node1
+      addr: 127.0.0.1:10001
+hooks:
+  history:
+    max_records: 50
+logging:
+  level: info
+  format: json
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+
+	history := cfg.Hooks.History
+	if history.MaxRecords != 50 || history.MaxAge != DefaultHistoryMaxAge || history.OutputTail != DefaultHistoryOutputTail {
+		t.Errorf("Hooks.History = %+v, want max_records 50 and defaults", history)
+	}
+	if got := cfg.HookHistoryPath(); got != "/var/lib/vip-switch/hook-history.jsonl" {
+		t.Errorf("HookHistoryPath() = %v, want /var/lib/vip-switch/hook-history.jsonl", got)
+	}
+	if got := cfg.AdminSocketPath(); got != "/var/lib/vip-switch/admin.sock" {
+		t.Errorf("AdminSocketPath() = %v, want /var/lib/vip-switch/admin.sock", got)
+	}
+
+	cfg.Admin.Socket = "/run/vip-switch.sock"
+	if got := cfg.AdminSocketPath(); got != "/run/vip-switch.sock" {
+		t.Errorf("AdminSocketPath() = %v, want /run/vip-switch.sock", got)
+	}
+}
+
+func TestLoad_InvalidTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+node:
+  id: node1
+  raft_addr: 127.0.0.1:10001
+  data_dir: ./data/node1
+cluster:
+  nodes:
+    - id: node1
+      addr: 127.0.0.1:10001
+hooks:
+  ToSlave:
+    command: /bin/true
+    args: ["{{.LeaderAddress}}"]
+logging:
+  level: info
+  format: json
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	_, err := Load(path)
+	if err == nil || !strings.Contains(err.Error(), "hooks.ToSlave: invalid template") {
+		t.Errorf("Load() error = %v, want hooks.ToSlave: invalid template", err)
+	}
+}
+
+func TestLoad_EnsureValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		vip     string
+		ensure  string
+		wantErr bool
+	}{
+		{"disabled", "", "interval: 0s", false},
+		{"native check", "address: 192.168.1.100/32\n  interface: eth0", "interval: 30s\n    native_check: true", false},
+		{"native check without interface", "address: 192.168.1.100/32", "interval: 30s\n    native_check: true", true},
+		{"native check with bad address", "address: 192.168.1.100\n  interface: eth0", "native_check: true", true},
+		{"negative interval", "", "interval: -1s", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			content := `
+node:
+  id: node1
+  raft_addr: 127.0.0.1:10001
+  data_dir: /var/lib/vip-switch
+cluster:
+  nodes:
+    - id: node1
+      addr: 127.0.0.1:10001
+vip:
+  ` + tt.vip + `
+hooks:
+  ensure:
+    ` + tt.ensure + `
+logging:
+  level: info
+  format: json
+`
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+
+			_, err := Load(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_TrackTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets string
+		wantErr bool
+	}{
+		{"icmp defaults", `- address: 192.168.1.1`, false},
+		{"tcp", "- name: web\n      address: 10.0.0.10\n      type: tcp\n      port: 443", false},
+		{"tcp without port", "- address: 10.0.0.10\n      type: tcp", true},
+		{"unknown type", "- address: 10.0.0.10\n      type: http", true},
+		{"hostname", `- address: gateway.local`, true},
+		{"timeout exceeds interval", "- address: 10.0.0.10\n      interval: 1s\n      timeout: 2s", true},
+		{"duplicate name", "- address: 10.0.0.10\n    - address: 10.0.0.10", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			content := `
+node:
+  id: node1
+  raft_addr: 127.0.0.1:10001
+  data_dir: /var/lib/vip-switch
+cluster:
+  nodes:
+    - id: node1
+      addr: 127.0.0.1:10001
+track:
+  targets:
+    ` + tt.targets + `
+logging:
+  level: info
+  format: json
+`
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+
+			cfg, err := Load(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil || tt.name != "icmp defaults" {
+				return
+			}
+
+			target := cfg.Track.Targets[0]
+			want := TrackTarget{
+				Name:     "192.168.1.1",
+				Address:  "192.168.1.1",
+				Type:     "icmp",
+				Interval: DefaultTrackTargetInterval,
+				Timeout:  DefaultTrackTargetTimeout,
+				Rise:     DefaultTrackTargetRise,
+				Fall:     DefaultTrackTargetFall,
+			}
+			if target != want {
+				t.Errorf("Track.Targets[0] = %+v, want %+v", target, want)
+			}
+		})
+	}
+}
+
+func TestLoad_TrackInterfacesAndScore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+node:
+  id: node1
+  raft_addr: 127.0.0.1:10001
+  data_dir: /var/lib/vip-switch
+cluster:
+  nodes:
+    - id: node1
+      addr: 127.0.0.1:10001
+track:
+  interfaces:
+    - eth0
+    - name: eth1
+      weight: -20
+  checks:
+    - command: /usr/local/bin/check-disk
+      weight: -30
+score:
+  balance: true
+logging:
+  level: info
+  format: json
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+
+	want := []TrackInterface{{Name: "eth0"}, {Name: "eth1", Weight: -20}}
+	if !reflect.DeepEqual(cfg.Track.Interfaces, want) {
+		t.Errorf("Track.Interfaces = %+v, want %+v", cfg.Track.Interfaces, want)
+	}
+	if check := cfg.Track.Checks[0]; check.Name != "check-disk" || check.Interval != DefaultTrackTargetInterval {
+		t.Errorf("Track.Checks[0] = %+v, want name check-disk and default interval", check)
+	}
+	score := cfg.Score
+	if !score.Balance || score.Priority != DefaultScorePriority || score.Margin != DefaultScoreMargin || score.Interval != DefaultScoreInterval {
+		t.Errorf("Score = %+v, want balancing with defaults", score)
+	}
+	if cfg.Shutdown.HandoffTimeout != DefaultShutdownHandoffTimeout || cfg.Shutdown.Timeout != DefaultShutdownTimeout {
+		t.Errorf("Shutdown = %+v, want the defaults", cfg.Shutdown)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// ClusterSpec describes a cluster to generate configuration files for
+type ClusterSpec struct {
+	Nodes       []ClusterNode
+	VIP         VIPConfig
+	DataDir     string
+	HookCommand string // absolute path of the hook run for every event
+}
+
+// ParseClusterNodes parses comma separated id=addr entries, e.g.
+// node1=10.0.0.1:7946,node2=10.0.0.2:7946
+func ParseClusterNodes(value string) ([]ClusterNode, error) {
+	var nodes []ClusterNode
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		id, addr, ok := strings.Cut(item, "=")
+		if !ok || id == "" || addr == "" {
+			return nil, fmt.Errorf("invalid cluster node %q, want id=addr", item)
+		}
+		nodes = append(nodes, ClusterNode{ID: id, Addr: addr})
+	}
+	return nodes, nil
+}
+
+// generatedHook is a hook of a generated configuration
+type generatedHook struct {
+	Name      string
+	Timeout   string
+	OnFailure string
+}
+
+// generatedHooks are the hooks a generated configuration runs
+var generatedHooks = []generatedHook{
+	{"ToReady", "10s", "continue"},
+	{"ToMaster", "30s", "abort"},
+	{"ToSlave", "30s", "abort"},
+	{"ToDestroy", "10s", "continue"},
+}
+
+// generateTemplate is the configuration file written for each node. It
+// uses [[ ]] delimiters so that hook templates pass through unchanged.
+var generateTemplate = template.Must(template.New("config.yaml").Delims("[[", "]]").Funcs(template.FuncMap{
+	"quote": strconv.Quote,
+}).Parse(`# vip-switch configuration for [[.Node.ID]], generated by vip-switch init.
+# Check it with: vip-switch config validate <file>
+
+node:
+  id: [[quote .Node.ID]]
+  raft_addr: [[quote .Node.Addr]]
+  data_dir: [[quote .Spec.DataDir]]
+
+cluster:
+  nodes:
+[[- range .Spec.Nodes]]
+    - id: [[quote .ID]]
+      addr: [[quote .Addr]]
+[[- end]]
+
+vip:
+  address: [[quote .Spec.VIP.Address]]
+  interface: [[quote .Spec.VIP.Interface]]
+
+hooks:
+  enabled: true
+  timeout: 60s
+  on_failure: "abort"
+  ensure:
+    interval: 0s        # e.g. 30s to re-check the VIP while Master or Slave, 0 disables
+    native_check: true  # check vip.address on vip.interface directly
+[[- range .Hooks]]
+
+  [[.Name]]:
+    command: [[quote $.Spec.HookCommand]]
+    timeout: [[.Timeout]]
+    on_failure: [[quote .OnFailure]]
+    environment:
+      VIP_ADDRESS: "{{.VIP.Address}}"
+      VIP_INTERFACE: "{{.VIP.Interface}}"
+[[- end]]
+
+security:
+  sanitize_environment: false
+  validate_command_path: true
+  safe_dirs:
+    - [[quote .SafeDir]]
+
+logging:
+  level: "info"
+  format: "json"
+`))
+
+// Generate renders the configuration file of node nodeID
+func (s *ClusterSpec) Generate(nodeID string) ([]byte, error) {
+	if _, _, err := net.ParseCIDR(s.VIP.Address); err != nil {
+		return nil, fmt.Errorf("invalid VIP address: %w", err)
+	}
+	if s.VIP.Interface == "" {
+		return nil, fmt.Errorf("a VIP interface is required")
+	}
+	if !filepath.IsAbs(s.HookCommand) {
+		return nil, fmt.Errorf("hook command %s is not an absolute path", s.HookCommand)
+	}
+
+	data := struct {
+		Spec    *ClusterSpec
+		Node    ClusterNode
+		Hooks   []generatedHook
+		SafeDir string
+	}{Spec: s, Hooks: generatedHooks, SafeDir: filepath.Dir(s.HookCommand)}
+	found := false
+	for _, node := range s.Nodes {
+		if node.ID == nodeID {
+			data.Node = node
+			found = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("node %s is not in the cluster", nodeID)
+	}
+
+	var buf bytes.Buffer
+	if err := generateTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseClusterNodes(t *testing.T) {
+	nodes, err := ParseClusterNodes("node1=10.0.0.1:7946, node2=10.0.0.2:7946,")
+	if err != nil {
+		t.Fatalf("ParseClusterNodes() error = %v", err)
+	}
+	want := []ClusterNode{{ID: "node1", Addr: "10.0.0.1:7946"}, {ID: "node2", Addr: "10.0.0.2:7946"}}
+	if !reflect.DeepEqual(nodes, want) {
+		t.Errorf("ParseClusterNodes() = %v, want %v", nodes, want)
+	}
+
+	for _, value := range []string{"node1", "node1=", "=10.0.0.1:7946"} {
+		if _, err := ParseClusterNodes(value); err == nil {
+			t.Errorf("ParseClusterNodes(%q) expected error, got nil", value)
+		}
+	}
+}
+
+func TestClusterSpec_Generate(t *testing.T) {
+	spec := &ClusterSpec{
+		Nodes: []ClusterNode{
+			{ID: "node1", Addr: "10.0.0.1:7946"},
+			{ID: "node2", Addr: "10.0.0.2:7946"},
+			{ID: "node3", Addr: "10.0.0.3:7946"},
+		},
+		VIP:         VIPConfig{Address: "10.0.0.100/24", Interface: "eth0"},
+		DataDir:     "/var/lib/vip-switch",
+		HookCommand: "/bin/true",
+	}
+
+	dir := t.TempDir()
+	for _, node := range spec.Nodes {
+		content, err := spec.Generate(node.ID)
+		if err != nil {
+			t.Fatalf("Generate(%s) error = %v", node.ID, err)
+		}
+		path := filepath.Join(dir, node.ID+".yaml")
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+
+		cfg, err := Load(path)
+		if err != nil {
+			t.Fatalf("Load() of the generated %s config error = %v\n%s", node.ID, err, content)
+		}
+		if cfg.Node.ID != node.ID || cfg.Node.RaftAddr != node.Addr || !reflect.DeepEqual(cfg.Cluster.Nodes, spec.Nodes) {
+			t.Errorf("generated node = %+v, cluster = %v, want %s in %v", cfg.Node, cfg.Cluster.Nodes, node.ID, spec.Nodes)
+		}
+		if cfg.VIP != spec.VIP || !cfg.Hooks.Enabled || cfg.Hooks.ToMaster.Command != "/bin/true" {
+			t.Errorf("generated vip = %+v, hooks = %+v, want the spec's", cfg.VIP, cfg.Hooks.ToMaster)
+		}
+		if cfg.Hooks.ToMaster.Environment["VIP_ADDRESS"] != "{{.VIP.Address}}" {
+			t.Errorf("generated environment = %v, want hook templates kept", cfg.Hooks.ToMaster.Environment)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*ClusterSpec)
+		node   string
+	}{
+		{"unknown node", func(*ClusterSpec) {}, "node4"},
+		{"invalid vip", func(s *ClusterSpec) { s.VIP.Address = "10.0.0.100" }, "node1"},
+		{"no interface", func(s *ClusterSpec) { s.VIP.Interface = "" }, "node1"},
+		{"relative hook", func(s *ClusterSpec) { s.HookCommand = "hook.sh" }, "node1"},
+	}
+	for _, tt := range tests {
+		s := *spec
+		tt.modify(&s)
+		if _, err := s.Generate(tt.node); err == nil {
+			t.Errorf("Generate() with %s expected error, got nil", tt.name)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DropInDir is the directory, next to the configuration file, whose *.yaml
+// files are merged over it
+const DropInDir = "conf.d"
+
+// appendTag marks a list that extends the list of an earlier file instead
+// of replacing it
+const appendTag = "!append"
+
+// source is where a setting came from: a line in a file, or an environment
+// variable or flag overriding the files
+type source struct {
+	file     string
+	line     int
+	override string // e.g. "env VIPSWITCH_HOOKS_TIMEOUT"
+}
+
+// String formats the source as file:line or the override
+func (s source) String() string {
+	if s.override != "" {
+		return s.override
+	}
+	return fmt.Sprintf("%s:%d", s.file, s.line)
+}
+
+// loader reads a configuration file, the files it includes and the drop-in
+// directory into one document, recording the source of every setting
+type loader struct {
+	root    *yaml.Node        // merged mapping
+	sources map[string]source // by path, e.g. cluster.nodes[0].addr
+	files   []string          // files read, in merge order
+	loading map[string]bool   // files being read, to detect include cycles
+}
+
+func newLoader() *loader {
+	return &loader{
+		root:    &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"},
+		sources: make(map[string]source),
+		loading: make(map[string]bool),
+	}
+}
+
+// load merges the configuration file at path, then its drop-in directory
+func (l *loader) load(path string) error {
+	if err := l.mergeFile(path); err != nil {
+		return err
+	}
+
+	dropIns, err := filepath.Glob(filepath.Join(filepath.Dir(path), DropInDir, "*.yaml"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(dropIns)
+	for _, dropIn := range dropIns {
+		if err := l.mergeFile(dropIn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeFile merges the files a file includes, in the order listed, then the
+// file itself, so that its own settings override those it includes.
+// Included paths are relative to the file and may be globs.
+func (l *loader) mergeFile(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if l.loading[abs] {
+		return fmt.Errorf("failed to read config file: %s includes itself", path)
+	}
+	l.loading[abs] = true
+	defer delete(l.loading, abs)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config: %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		l.files = append(l.files, path)
+		return nil
+	}
+	top := doc.Content[0]
+	if top.Kind != yaml.MappingNode {
+		return fmt.Errorf("failed to parse config: %s: top level must be a mapping", path)
+	}
+
+	includes, err := takeIncludes(top)
+	if err != nil {
+		return fmt.Errorf("failed to parse config: %s: %w", path, err)
+	}
+	for _, include := range includes {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(path), include)
+		}
+		matches := []string{include}
+		if strings.ContainsAny(include, "*?[") {
+			if matches, err = filepath.Glob(include); err != nil {
+				return fmt.Errorf("failed to parse config: %s: include %s: %w", path, include, err)
+			}
+			sort.Strings(matches)
+		}
+		for _, match := range matches {
+			if err := l.mergeFile(match); err != nil {
+				return err
+			}
+		}
+	}
+
+	l.files = append(l.files, path)
+	l.merge(l.root, top, "", path)
+	return nil
+}
+
+// takeIncludes removes the include list from a file's top level mapping
+func takeIncludes(top *yaml.Node) ([]string, error) {
+	for i := 0; i+1 < len(top.Content); i += 2 {
+		if top.Content[i].Value != "include" {
+			continue
+		}
+		value := top.Content[i+1]
+		top.Content = append(top.Content[:i], top.Content[i+2:]...)
+
+		var includes []string
+		if value.Kind == yaml.ScalarNode {
+			return []string{value.Value}, nil
+		}
+		if err := value.Decode(&includes); err != nil {
+			return nil, fmt.Errorf("include: %w", err)
+		}
+		return includes, nil
+	}
+	return nil, nil
+}
+
+// merge merges the mapping src over dst. Mappings merge key by key; any
+// other value, lists included, replaces the earlier one, except that a list
+// tagged !append is added to the end of the earlier list.
+func (l *loader) merge(dst, src *yaml.Node, prefix, file string) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		path := joinPath(prefix, key.Value)
+
+		j := mappingIndex(dst, key.Value)
+		if j < 0 {
+			dst.Content = append(dst.Content, key, value)
+			l.record(key.Line, value, path, file)
+			continue
+		}
+
+		existing := dst.Content[j+1]
+		switch {
+		case existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			l.merge(existing, value, path, file)
+		case value.Tag == appendTag && existing.Kind == yaml.SequenceNode && value.Kind == yaml.SequenceNode:
+			for _, item := range value.Content {
+				l.record(item.Line, item, fmt.Sprintf("%s[%d]", path, len(existing.Content)), file)
+				existing.Content = append(existing.Content, item)
+			}
+		default:
+			forgetSources(l.sources, path)
+			dst.Content[j+1] = value
+			l.record(key.Line, value, path, file)
+		}
+	}
+}
+
+// record notes file as the source of the value at path and everything in it
+func (l *loader) record(line int, node *yaml.Node, path, file string) {
+	l.sources[path] = source{file: file, line: line}
+	if node.Tag == appendTag {
+		node.Tag = ""
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			l.record(node.Content[i].Line, node.Content[i+1], joinPath(path, node.Content[i].Value), file)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			l.record(item.Line, item, fmt.Sprintf("%s[%d]", path, i), file)
+		}
+	}
+}
+
+// forgetSources drops the sources of path and everything in it
+func forgetSources(sources map[string]source, path string) {
+	for p := range sources {
+		if p == path || strings.HasPrefix(p, path+".") || strings.HasPrefix(p, path+"[") {
+			delete(sources, p)
+		}
+	}
+}
+
+// joinPath appends key to a dotted path
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// mappingIndex returns the index of key in a mapping node, -1 if unset
+func mappingIndex(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// sourceOf returns the source of the setting at path, or of the closest
+// section holding it
+func sourceOf(sources map[string]source, path string) (source, bool) {
+	for path != "" {
+		if s, ok := sources[path]; ok {
+			return s, true
+		}
+		i := strings.LastIndexAny(path, ".[")
+		if i < 0 {
+			break
+		}
+		path = path[:i]
+	}
+	return source{}, false
+}
@@ -0,0 +1,222 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFiles writes files, keyed by path relative to dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+}
+
+const includeBase = `include:
+  - hooks/*.yaml
+node:
+  id: node1
+  raft_addr: 127.0.0.1:10001
+  data_dir: /var/lib/vip-switch
+cluster:
+  nodes:
+    - id: node1
+      addr: 127.0.0.1:10001
+hooks:
+  timeout: 10s
+track:
+  checks:
+    - command: /bin/true
+security:
+  safe_dirs: [/usr/local/bin]
+logging:
+  level: info
+  format: json
+`
+
+func TestLoad_Includes(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config.yaml": includeBase,
+		"hooks/master.yaml": `hooks:
+  kill_grace_period: 3s
+  ToMaster:
+    command: /bin/true
+    timeout: 5s
+`,
+		"hooks/slave.yaml": `hooks:
+  timeout: 20s
+  kill_grace_period: 4s
+  ToSlave:
+    command: /bin/true
+`,
+		"conf.d/10-cluster.yaml": `cluster:
+  nodes:
+    - id: node1
+      addr: 127.0.0.1:10001
+    - id: node2
+      addr: 127.0.0.1:10002
+`,
+		"conf.d/20-track.yaml": `track:
+  checks: !append
+    - command: /bin/false
+security:
+  safe_dirs: !append [/opt/vip-switch]
+`,
+		"conf.d/README": "not yaml",
+	})
+
+	cfg, err := Load(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+
+	// Mappings merge key by key
+	if cfg.Hooks.ToMaster.Timeout != 5*time.Second || cfg.Hooks.ToSlave.Command != "/bin/true" {
+		t.Errorf("Hooks = %+v, want ToMaster and ToSlave from the includes", cfg.Hooks)
+	}
+	// The including file overrides its includes, later includes earlier ones
+	if cfg.Hooks.Timeout != 10*time.Second {
+		t.Errorf("Hooks.Timeout = %v, want the including file's 10s", cfg.Hooks.Timeout)
+	}
+	if cfg.Hooks.KillGracePeriod != 4*time.Second {
+		t.Errorf("Hooks.KillGracePeriod = %v, want the later include's 4s", cfg.Hooks.KillGracePeriod)
+	}
+	// Lists replace, unless tagged !append
+	if len(cfg.Cluster.Nodes) != 2 {
+		t.Errorf("Cluster.Nodes = %+v, want the drop-in's two nodes", cfg.Cluster.Nodes)
+	}
+	if len(cfg.Track.Checks) != 2 || cfg.Track.Checks[1].Command != "/bin/false" {
+		t.Errorf("Track.Checks = %+v, want /bin/false appended", cfg.Track.Checks)
+	}
+	if want := []string{"/usr/local/bin", "/opt/vip-switch"}; !reflect.DeepEqual(cfg.Security.SafeDirs, want) {
+		t.Errorf("Security.SafeDirs = %v, want %v", cfg.Security.SafeDirs, want)
+	}
+
+	wantFiles := []string{"hooks/master.yaml", "hooks/slave.yaml", "config.yaml", "conf.d/10-cluster.yaml", "conf.d/20-track.yaml"}
+	for i, file := range wantFiles {
+		wantFiles[i] = filepath.Join(dir, file)
+	}
+	if !reflect.DeepEqual(cfg.files, wantFiles) {
+		t.Errorf("files = %v, want %v", cfg.files, wantFiles)
+	}
+
+	sources := map[string]string{
+		"node.id":                 "config.yaml:4",
+		"hooks.timeout":           "config.yaml:12",
+		"hooks.kill_grace_period": "hooks/slave.yaml:3",
+		"hooks.ToMaster.timeout":  "hooks/master.yaml:5",
+		"cluster.nodes[1].addr":   "conf.d/10-cluster.yaml:6",
+		"track.checks[0]":         "config.yaml:15",
+		"track.checks[1]":         "conf.d/20-track.yaml:3",
+	}
+	for path, want := range sources {
+		s, ok := cfg.sources[path]
+		if !ok {
+			t.Errorf("sources[%s] missing, want %s", path, want)
+			continue
+		}
+		if rel, _ := filepath.Rel(dir, s.file); rel+":"+strconv.Itoa(s.line) != want {
+			t.Errorf("sources[%s] = %s:%d, want %s", path, rel, s.line, want)
+		}
+	}
+
+	data, err := cfg.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error: %v", err)
+	}
+	for _, want := range []string{"kill_grace_period: 4s # hooks/slave.yaml:3", "- /opt/vip-switch # conf.d/20-track.yaml:5"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Marshal() = %s\nwant it to contain %q", data, want)
+		}
+	}
+}
+
+func TestLoad_IncludeProblems(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config.yaml": strings.Replace(includeBase, "hooks/*.yaml", "hooks.yaml", 1),
+		"hooks.yaml": `hooks:
+  ToMaster:
+    on_failure: skip
+`,
+	})
+
+	_, err := Load(filepath.Join(dir, "config.yaml"))
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Problems) != 1 {
+		t.Fatalf("Load() error = %v, want one problem", err)
+	}
+	if p := verr.Problems[0]; p.File != filepath.Join(dir, "hooks.yaml") || p.Line != 3 {
+		t.Errorf("problem at %s:%d, want hooks.yaml:3", p.File, p.Line)
+	}
+}
+
+func TestLoad_IncludeErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       map[string]string
+		errContains string
+	}{
+		{
+			name:        "missing include",
+			files:       map[string]string{"config.yaml": strings.Replace(includeBase, "hooks/*.yaml", "missing.yaml", 1)},
+			errContains: "failed to read config file",
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"config.yaml": strings.Replace(includeBase, "hooks/*.yaml", "a.yaml", 1),
+				"a.yaml":      "include: [config.yaml]\n",
+			},
+			errContains: "includes itself",
+		},
+		{
+			name: "invalid drop-in",
+			files: map[string]string{
+				"config.yaml":     includeBase,
+				"conf.d/bad.yaml": "- not a mapping\n",
+			},
+			errContains: "top level must be a mapping",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+
+			_, err := Load(filepath.Join(dir, "config.yaml"))
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("Load() error = %v, want to contain %q", err, tt.errContains)
+			}
+		})
+	}
+}
@@ -0,0 +1,372 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts the names of environment variables that override
+// settings, e.g. VIPSWITCH_HOOKS_TIMEOUT for hooks.timeout
+const EnvPrefix = "VIPSWITCH_"
+
+// Overlay holds settings layered over the configuration file. Environment
+// variables override the file and assignments override both.
+type Overlay struct {
+	Env []string // in os.Environ form; only VIPSWITCH_* variables are used
+	Set []string // key.path=value, e.g. hooks.ToMaster.timeout=30s
+}
+
+// apply sets the overlay's values in the merged files, recording itself as
+// the source of each setting it changes. It returns the environment
+// variables that name no setting, which are ignored so that a stray
+// variable in a service's environment does not stop the daemon.
+func (o Overlay) apply(root *yaml.Node, sources map[string]source) ([]string, error) {
+	env := make([]string, 0, len(o.Env))
+	for _, entry := range o.Env {
+		if strings.HasPrefix(entry, EnvPrefix) {
+			env = append(env, entry)
+		}
+	}
+	// Apply in a stable order so that a list and one of its fields resolve
+	// the same way every time
+	sort.Strings(env)
+	var ignored []string
+	for _, entry := range env {
+		name, value, _ := strings.Cut(entry, "=")
+		path, ok := envPath(name)
+		if !ok {
+			ignored = append(ignored, name)
+			continue
+		}
+		if err := setPath(root, path, value); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		forgetSources(sources, path)
+		sources[path] = source{override: "env " + name}
+	}
+
+	for _, assignment := range o.Set {
+		path, value, ok := strings.Cut(assignment, "=")
+		if !ok || path == "" {
+			return nil, fmt.Errorf("invalid assignment %q, want key.path=value", assignment)
+		}
+		if err := setPath(root, path, value); err != nil {
+			return nil, fmt.Errorf("--set %s: %w", path, err)
+		}
+		forgetSources(sources, path)
+		sources[path] = source{override: "--set " + path}
+	}
+	return ignored, nil
+}
+
+// envPath maps an environment variable to the setting it overrides, e.g.
+// VIPSWITCH_NODE_RAFT_ADDR to node.raft_addr. Words after a map, such as
+// VIPSWITCH_HOOKS_TOMASTER_ENVIRONMENT_API_URL, name the map key. It
+// reports false when the variable names no setting.
+func envPath(name string) (string, bool) {
+	words := strings.Split(strings.TrimPrefix(name, EnvPrefix), "_")
+	keys, ok := matchFields(reflect.TypeOf(Config{}), words)
+	if !ok {
+		return "", false
+	}
+	return strings.Join(keys, "."), true
+}
+
+// matchFields resolves words against the yaml keys of t
+func matchFields(t reflect.Type, words []string) ([]string, bool) {
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			key := yamlKey(t.Field(i))
+			if key == "" {
+				continue
+			}
+			fieldWords := strings.Split(strings.ToUpper(key), "_")
+			if len(words) < len(fieldWords) || !slices.Equal(words[:len(fieldWords)], fieldWords) {
+				continue
+			}
+			rest, ok := matchFields(t.Field(i).Type, words[len(fieldWords):])
+			if ok {
+				return append([]string{key}, rest...), true
+			}
+		}
+		return nil, false
+	case reflect.Map:
+		if len(words) == 0 {
+			return nil, false
+		}
+		return []string{strings.Join(words, "_")}, true
+	default:
+		return nil, len(words) == 0
+	}
+}
+
+// yamlKey returns the key a struct field is read from, empty for fields
+// not read from YAML
+func yamlKey(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	key, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if key == "-" {
+		return ""
+	}
+	return key
+}
+
+// pathSegment is one step of a key path: a key, optionally indexing a list
+type pathSegment struct {
+	key   string
+	index int // -1 without an index
+}
+
+// parsePath splits a path such as cluster.nodes[0].addr
+func parsePath(path string) ([]pathSegment, error) {
+	var segments []pathSegment
+	for _, part := range strings.Split(path, ".") {
+		segment := pathSegment{key: part, index: -1}
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			n, err := strconv.Atoi(strings.TrimSuffix(part[i+1:], "]"))
+			if err != nil || !strings.HasSuffix(part, "]") || n < 0 {
+				return nil, fmt.Errorf("invalid index in %s", part)
+			}
+			segment = pathSegment{key: part[:i], index: n}
+		}
+		if segment.key == "" {
+			return nil, fmt.Errorf("invalid key path %s", path)
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
+
+// pathType returns the type of the setting at path, checking that the path
+// names one
+func pathType(segments []pathSegment) (reflect.Type, error) {
+	t := reflect.TypeOf(Config{})
+	for _, segment := range segments {
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := structField(t, segment.key)
+			if !ok {
+				return nil, fmt.Errorf("unknown setting %s", segment.key)
+			}
+			t = field.Type
+		case reflect.Map:
+			t = t.Elem()
+		default:
+			return nil, fmt.Errorf("%s is not a section", segment.key)
+		}
+		if segment.index >= 0 {
+			if t.Kind() != reflect.Slice {
+				return nil, fmt.Errorf("%s is not a list", segment.key)
+			}
+			t = t.Elem()
+		}
+	}
+	return t, nil
+}
+
+// structField returns the field of t read from key
+func structField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		if yamlKey(t.Field(i)) == key {
+			return t.Field(i), true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// setPath sets the setting at path to value in the merged mapping, creating
+// the sections leading to it
+func setPath(root *yaml.Node, path, value string) error {
+	segments, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	t, err := pathType(segments)
+	if err != nil {
+		return err
+	}
+	node, err := valueNode(path, t, value)
+	if err != nil {
+		return err
+	}
+
+	parent := root
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		if parent.Kind != yaml.MappingNode {
+			return fmt.Errorf("%s is not a section", segment.key)
+		}
+
+		child := mappingChild(parent, segment.key)
+		if segment.index < 0 && last {
+			if child == nil {
+				parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: segment.key}, node)
+			} else {
+				*child = *node
+			}
+			return nil
+		}
+		if child == nil {
+			if segment.index >= 0 {
+				return fmt.Errorf("%s has no entry %d", segment.key, segment.index)
+			}
+			child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: segment.key}, child)
+		}
+		if segment.index >= 0 {
+			if child.Kind != yaml.SequenceNode || segment.index >= len(child.Content) {
+				return fmt.Errorf("%s has no entry %d", segment.key, segment.index)
+			}
+			child = child.Content[segment.index]
+			if last {
+				*child = *node
+				return nil
+			}
+		}
+		parent = child
+	}
+	return nil
+}
+
+// mappingChild returns the value for key in a mapping node, nil if unset
+func mappingChild(node *yaml.Node, key string) *yaml.Node {
+	value, _ := mappingValue(node, key)
+	return value
+}
+
+// valueNode parses an overlay value for a setting of type t. Values
+// starting with [ or { are YAML; other lists are comma separated, and
+// cluster.nodes takes id=addr entries, e.g. node1=10.0.0.1:7000,node2=...
+func valueNode(path string, t reflect.Type, value string) (*yaml.Node, error) {
+	if strings.HasPrefix(value, "[") || strings.HasPrefix(value, "{") {
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(value), &doc); err != nil {
+			return nil, fmt.Errorf("invalid value: %w", err)
+		}
+		return doc.Content[0], nil
+	}
+
+	switch t.Kind() {
+	case reflect.Slice:
+		list := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if path == "cluster.nodes" {
+			nodes, err := ParseClusterNodes(value)
+			if err != nil {
+				return nil, err
+			}
+			for _, node := range nodes {
+				list.Content = append(list.Content, &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{
+					{Kind: yaml.ScalarNode, Value: "id"}, {Kind: yaml.ScalarNode, Value: node.ID},
+					{Kind: yaml.ScalarNode, Value: "addr"}, {Kind: yaml.ScalarNode, Value: node.Addr},
+				}})
+			}
+			return list, nil
+		}
+		for _, item := range strings.Split(value, ",") {
+			item = strings.TrimSpace(item)
+			if item != "" {
+				list.Content = append(list.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: item})
+			}
+		}
+		return list, nil
+	case reflect.Struct, reflect.Map:
+		return nil, fmt.Errorf("a section needs a YAML mapping value")
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: value}, nil
+	}
+}
+
+// Marshal returns the configuration as YAML, with defaults filled in and a
+// comment giving the source of every value read from a file or overridden
+// by the environment or a flag. Values of headers that look like they carry
+// credentials are redacted.
+func (c *Config) Marshal() ([]byte, error) {
+	current := *c
+	current.fillHookDefaults()
+	current.redactHeaders()
+
+	var doc yaml.Node
+	if err := doc.Encode(&current); err != nil {
+		return nil, err
+	}
+	// Files are named relative to the configuration file's directory
+	dir := filepath.Dir(c.filePath)
+	describe := func(s source) string {
+		if rel, err := filepath.Rel(dir, s.file); err == nil && s.override == "" && !strings.HasPrefix(rel, "..") {
+			s.file = rel
+		}
+		return s.String()
+	}
+	annotate(&doc, "", c.sources, describe)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// annotate comments the values under node with their sources. Sections are
+// left alone, as their values carry the comments.
+func annotate(node *yaml.Node, path string, sources map[string]source, describe func(source) string) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			annotate(child, path, sources, describe)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			childPath := joinPath(path, key.Value)
+			if s, ok := sources[childPath]; ok {
+				switch value.Kind {
+				case yaml.ScalarNode:
+					value.LineComment = describe(s)
+				case yaml.SequenceNode:
+					key.LineComment = describe(s)
+				}
+			}
+			annotate(value, childPath, sources, describe)
+		}
+	case yaml.SequenceNode:
+		list := sources[path]
+		for i, child := range node.Content {
+			childPath := fmt.Sprintf("%s[%d]", path, i)
+			// Items appended by a later file, or set by a flag
+			if s, ok := sources[childPath]; ok && child.Kind == yaml.ScalarNode && (s.file != list.file || s.override != list.override) {
+				child.LineComment = describe(s)
+			}
+			annotate(child, childPath, sources, describe)
+		}
+	}
+}
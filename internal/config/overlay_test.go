@@ -0,0 +1,264 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnvPath(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   string
+		wantOK bool
+	}{
+		{"VIPSWITCH_NODE_ID", "node.id", true},
+		{"VIPSWITCH_NODE_RAFT_ADDR", "node.raft_addr", true},
+		{"VIPSWITCH_HOOKS_TIMEOUT", "hooks.timeout", true},
+		{"VIPSWITCH_HOOKS_TOMASTER_ON_FAILURE", "hooks.ToMaster.on_failure", true},
+		{"VIPSWITCH_HOOKS_TOMASTER_RETRY_MAX_ATTEMPTS", "hooks.ToMaster.retry.max_attempts", true},
+		{"VIPSWITCH_HOOKS_TOMASTER_ENVIRONMENT_API_URL", "hooks.ToMaster.environment.API_URL", true},
+		{"VIPSWITCH_CLUSTER_NODES", "cluster.nodes", true},
+		{"VIPSWITCH_SCORE_PRIORITY", "score.priority", true},
+		{"VIPSWITCH_NODE", "", false},
+		{"VIPSWITCH_HOOKS_TIMEOUTS", "", false},
+		{"VIPSWITCH_HOOKS_TOMASTER_ENVIRONMENT", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := envPath(tt.name)
+			if ok != tt.wantOK {
+				t.Fatalf("envPath() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("envPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+const overlayBase = `
+node:
+  id: node1
+  raft_addr: 127.0.0.1:10001
+  data_dir: /var/lib/vip-switch
+cluster:
+  nodes:
+    - id: node1
+      addr: 127.0.0.1:10001
+hooks:
+  timeout: 10s
+logging:
+  level: info
+  format: json
+`
+
+func TestLoadWithOverlay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(overlayBase), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		overlay     Overlay
+		errContains string
+		checkConfig func(t *testing.T, cfg *Config)
+	}{
+		{
+			name: "environment",
+			overlay: Overlay{Env: []string{
+				"HOME=/root",
+				"VIPSWITCH_NODE_ID=node2",
+				"VIPSWITCH_NODE_RAFT_ADDR=127.0.0.1:10002",
+				"VIPSWITCH_CLUSTER_NODES=node1=127.0.0.1:10001, node2=127.0.0.1:10002",
+				"VIPSWITCH_HOOKS_TIMEOUT=30s",
+				"VIPSWITCH_HOOKS_TOMASTER_ARGS=up,--fast",
+				"VIPSWITCH_HOOKS_TOMASTER_ENVIRONMENT_API_URL=http://127.0.0.1",
+				"VIPSWITCH_SCORE_BALANCE=true",
+			}},
+			checkConfig: func(t *testing.T, cfg *Config) {
+				if cfg.Node.ID != "node2" || cfg.Node.RaftAddr != "127.0.0.1:10002" {
+					t.Errorf("Node = %+v, want node2 at 127.0.0.1:10002", cfg.Node)
+				}
+				want := []ClusterNode{{ID: "node1", Addr: "127.0.0.1:10001"}, {ID: "node2", Addr: "127.0.0.1:10002"}}
+				if !reflect.DeepEqual(cfg.Cluster.Nodes, want) {
+					t.Errorf("Cluster.Nodes = %+v, want %+v", cfg.Cluster.Nodes, want)
+				}
+				if cfg.Hooks.Timeout != 30*time.Second {
+					t.Errorf("Hooks.Timeout = %v, want 30s", cfg.Hooks.Timeout)
+				}
+				if !reflect.DeepEqual(cfg.Hooks.ToMaster.Args, []string{"up", "--fast"}) {
+					t.Errorf("Hooks.ToMaster.Args = %v, want [up --fast]", cfg.Hooks.ToMaster.Args)
+				}
+				if got := cfg.Hooks.ToMaster.Environment["API_URL"]; got != "http://127.0.0.1" {
+					t.Errorf("Hooks.ToMaster.Environment[API_URL] = %v, want http://127.0.0.1", got)
+				}
+				if !cfg.Score.Balance {
+					t.Error("Score.Balance = false, want true")
+				}
+			},
+		},
+		{
+			name: "assignments override the environment",
+			overlay: Overlay{
+				Env: []string{"VIPSWITCH_HOOKS_TIMEOUT=30s"},
+				Set: []string{"hooks.timeout=45s", "cluster.nodes[0].addr=127.0.0.1:10009", "node.raft_addr=127.0.0.1:10009", "track.checks=[{command: /bin/true}]"},
+			},
+			checkConfig: func(t *testing.T, cfg *Config) {
+				if cfg.Hooks.Timeout != 45*time.Second {
+					t.Errorf("Hooks.Timeout = %v, want 45s", cfg.Hooks.Timeout)
+				}
+				if cfg.Cluster.Nodes[0].Addr != "127.0.0.1:10009" {
+					t.Errorf("Cluster.Nodes[0].Addr = %v, want 127.0.0.1:10009", cfg.Cluster.Nodes[0].Addr)
+				}
+				if len(cfg.Track.Checks) != 1 || cfg.Track.Checks[0].Name != "true" {
+					t.Errorf("Track.Checks = %+v, want one check named true", cfg.Track.Checks)
+				}
+			},
+		},
+		{
+			name:    "unknown variables are ignored",
+			overlay: Overlay{Env: []string{"VIPSWITCH_HOOKS_TIMEOUTS=30s", "VIPSWITCH_HOOKS_TIMEOUT=45s", "VIPSWITCH_VERSION=1.2"}},
+			checkConfig: func(t *testing.T, cfg *Config) {
+				if cfg.Hooks.Timeout != 45*time.Second {
+					t.Errorf("Hooks.Timeout = %v, want 45s", cfg.Hooks.Timeout)
+				}
+				if want := []string{"VIPSWITCH_HOOKS_TIMEOUTS", "VIPSWITCH_VERSION"}; !reflect.DeepEqual(cfg.IgnoredEnv(), want) {
+					t.Errorf("IgnoredEnv() = %v, want %v", cfg.IgnoredEnv(), want)
+				}
+			},
+		},
+		{
+			name:        "unknown key",
+			overlay:     Overlay{Set: []string{"hooks.tiemout=30s"}},
+			errContains: "unknown setting tiemout",
+		},
+		{
+			name:        "missing list entry",
+			overlay:     Overlay{Set: []string{"cluster.nodes[3].addr=127.0.0.1:10009"}},
+			errContains: "nodes has no entry 3",
+		},
+		{
+			name:        "malformed cluster nodes",
+			overlay:     Overlay{Env: []string{"VIPSWITCH_CLUSTER_NODES=node1"}},
+			errContains: "want id=addr",
+		},
+		{
+			name:        "invalid value reported by origin",
+			overlay:     Overlay{Env: []string{"VIPSWITCH_LOGGING_LEVEL=verbose"}},
+			errContains: "env VIPSWITCH_LOGGING_LEVEL: invalid log level: verbose",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := LoadWithOverlay(path, tt.overlay)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("LoadWithOverlay() error = %v, want to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadWithOverlay() unexpected error: %v", err)
+			}
+			tt.checkConfig(t, cfg)
+		})
+	}
+}
+
+func TestLoadWithOverlay_EmptyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := LoadWithOverlay(path, Overlay{Env: []string{
+		"VIPSWITCH_NODE_ID=node1",
+		"VIPSWITCH_NODE_RAFT_ADDR=127.0.0.1:10001",
+		"VIPSWITCH_NODE_DATA_DIR=/var/lib/vip-switch",
+		"VIPSWITCH_CLUSTER_NODES=node1=127.0.0.1:10001",
+		"VIPSWITCH_LOGGING_LEVEL=info",
+		"VIPSWITCH_LOGGING_FORMAT=text",
+	}})
+	if err != nil {
+		t.Fatalf("LoadWithOverlay() unexpected error: %v", err)
+	}
+	if cfg.Node.ID != "node1" {
+		t.Errorf("Node.ID = %v, want node1", cfg.Node.ID)
+	}
+}
+
+func TestConfig_Marshal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(overlayBase), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := LoadWithOverlay(path, Overlay{
+		Env: []string{"VIPSWITCH_HOOKS_TIMEOUT=30s"},
+		Set: []string{"score.priority=150"},
+	})
+	if err != nil {
+		t.Fatalf("LoadWithOverlay() unexpected error: %v", err)
+	}
+	cfg.Hooks.ToMaster.HTTP.Headers = map[string]string{"Authorization": "Bearer s3cr3t", "X-Source": "vip-switch"}
+	data, err := cfg.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		"timeout: 30s # env VIPSWITCH_HOOKS_TIMEOUT",
+		"priority: 150 # --set score.priority",
+		"max_records: 1000",
+		"id: node1 # config.yaml:3",
+		"Authorization: '[REDACTED]'",
+		"X-Source: vip-switch",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Marshal() = %s\nwant it to contain %q", data, want)
+		}
+	}
+	if got := cfg.Hooks.ToMaster.HTTP.Headers["Authorization"]; got != "Bearer s3cr3t" {
+		t.Errorf("Authorization = %q after Marshal(), want the configured value kept", got)
+	}
+}
+
+func TestLoadWithOverlay_IgnoredEnvOnInvalidConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(overlayBase), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	// The typo leaves logging.level alone, the invalid level is reported
+	// together with the variable that was ignored
+	_, err := LoadWithOverlay(path, Overlay{Env: []string{"VIPSWITCH_LOGING_LEVEL=debug"}, Set: []string{"logging.level=verbose"}})
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("LoadWithOverlay() error = %v, want a ValidationError", err)
+	}
+	if want := []string{"VIPSWITCH_LOGING_LEVEL"}; !reflect.DeepEqual(verr.IgnoredEnv, want) {
+		t.Errorf("IgnoredEnv = %v, want %v", verr.IgnoredEnv, want)
+	}
+}
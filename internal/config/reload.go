@@ -0,0 +1,137 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ReloadResult lists the settings a reload changed. Applied settings took
+// effect; the others keep their running values until the daemon restarts.
+type ReloadResult struct {
+	Applied         []string `json:"applied"`
+	RestartRequired []string `json:"restart_required"`
+}
+
+// reloadable describes a part of the configuration for Reload. Parts
+// without apply only take effect on restart.
+type reloadable struct {
+	name  string
+	get   func(c *Config) any
+	apply func(dst, src *Config)
+}
+
+var reloadables = []reloadable{
+	{"cluster", func(c *Config) any { return c.Cluster }, nil},
+	{"storage", func(c *Config) any { return c.Storage }, nil},
+	{"vip", func(c *Config) any { return c.VIP }, nil},
+	{"hooks", func(c *Config) any { return reloadableHooks(c.Hooks) }, func(dst, src *Config) {
+		hooks := src.Hooks
+		hooks.CgroupParent = dst.Hooks.CgroupParent
+		hooks.History = dst.Hooks.History
+		hooks.Ensure = dst.Hooks.Ensure
+		dst.Hooks = hooks
+	}},
+	{"hooks.cgroup_parent", func(c *Config) any { return c.Hooks.CgroupParent }, nil},
+	{"hooks.history", func(c *Config) any { return c.Hooks.History }, nil},
+	{"hooks.ensure", func(c *Config) any { return c.Hooks.Ensure }, nil},
+	{"track.interfaces", func(c *Config) any { return c.Track.Interfaces }, nil},
+	{"track.up_delay", func(c *Config) any { return c.Track.UpDelay }, nil},
+	{"track.targets", func(c *Config) any { return c.Track.Targets }, func(dst, src *Config) { dst.Track.Targets = src.Track.Targets }},
+	{"track.checks", func(c *Config) any { return c.Track.Checks }, func(dst, src *Config) { dst.Track.Checks = src.Track.Checks }},
+	{"score.priority", func(c *Config) any { return c.Score.Priority }, func(dst, src *Config) { dst.Score.Priority = src.Score.Priority }},
+	{"score.balance", func(c *Config) any { return c.Score.Balance }, func(dst, src *Config) { dst.Score.Balance = src.Score.Balance }},
+	{"score.margin", func(c *Config) any { return c.Score.Margin }, func(dst, src *Config) { dst.Score.Margin = src.Score.Margin }},
+	{"score.interval", func(c *Config) any { return c.Score.Interval }, nil},
+	{"security", func(c *Config) any { return c.Security }, func(dst, src *Config) { dst.Security = src.Security }},
+	{"admin", func(c *Config) any { return c.Admin }, nil},
+	{"shutdown", func(c *Config) any { return c.Shutdown }, func(dst, src *Config) { dst.Shutdown = src.Shutdown }},
+	{"logging.level", func(c *Config) any { return c.Logging.Level }, func(dst, src *Config) { dst.Logging.Level = src.Logging.Level }},
+	{"logging.format", func(c *Config) any { return c.Logging.Format }, nil},
+	{"logging.output", func(c *Config) any { return c.Logging.Output }, nil},
+}
+
+// reloadableHooks returns hooks without the settings only read at startup
+func reloadableHooks(hooks HooksConfig) HooksConfig {
+	hooks.CgroupParent = ""
+	hooks.History = HistoryConfig{}
+	hooks.Ensure = EnsureConfig{}
+	return hooks
+}
+
+// Reload compares next, a freshly loaded configuration, with c and returns
+// the configuration to run with: c with the changes that can be applied
+// while running taken from next. c itself is left untouched. Changing the
+// node's identity is rejected.
+func (c *Config) Reload(next *Config) (*Config, *ReloadResult, error) {
+	if next.Node.ID != c.Node.ID {
+		return nil, nil, fmt.Errorf("node.id cannot change while running (%q -> %q), restart the daemon instead", c.Node.ID, next.Node.ID)
+	}
+	if next.Node.RaftAddr != c.Node.RaftAddr {
+		return nil, nil, fmt.Errorf("node.raft_addr cannot change while running (%q -> %q), restart the daemon instead", c.Node.RaftAddr, next.Node.RaftAddr)
+	}
+	if next.Node.DataDir != c.Node.DataDir {
+		return nil, nil, fmt.Errorf("node.data_dir cannot change while running (%q -> %q), restart the daemon instead", c.Node.DataDir, next.Node.DataDir)
+	}
+
+	// Hook defaults are filled in on first use; fill them in copies of both
+	// sides so that they do not show up as changes
+	current, loaded := *c, *next
+	current.fillHookDefaults()
+	loaded.fillHookDefaults()
+
+	merged := current
+	result := &ReloadResult{Applied: []string{}, RestartRequired: []string{}}
+	for _, r := range reloadables {
+		changed, err := differ(r.get(&current), r.get(&loaded))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to compare %s: %w", r.name, err)
+		}
+		if !changed {
+			continue
+		}
+		if r.apply == nil {
+			result.RestartRequired = append(result.RestartRequired, r.name)
+			continue
+		}
+		r.apply(&merged, &loaded)
+		result.Applied = append(result.Applied, r.name)
+	}
+	return &merged, result, nil
+}
+
+// fillHookDefaults fills every hook definition with the global defaults
+func (c *Config) fillHookDefaults() {
+	for _, eventType := range EventTypes {
+		c.GetHookByEventType(eventType)
+	}
+}
+
+// differ reports whether a and b serialize differently. Hook definitions
+// hold parsed templates that cannot be compared directly.
+func differ(a, b any) (bool, error) {
+	x, err := yaml.Marshal(a)
+	if err != nil {
+		return false, err
+	}
+	y, err := yaml.Marshal(b)
+	if err != nil {
+		return false, err
+	}
+	return !bytes.Equal(x, y), nil
+}
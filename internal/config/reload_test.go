@@ -0,0 +1,174 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const reloadBase = `
+node:
+  id: node1
+  raft_addr: 127.0.0.1:10001
+  data_dir: /var/lib/vip-switch
+cluster:
+  nodes:
+    - id: node1
+      addr: 127.0.0.1:10001
+    - id: node2
+      addr: 127.0.0.1:10002
+hooks:
+  enabled: true
+  ToMaster:
+    command: /bin/true
+logging:
+  level: info
+  format: json
+`
+
+func loadReloadConfig(t *testing.T, content string) *Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	return cfg
+}
+
+func TestConfig_Reload(t *testing.T) {
+	tests := []struct {
+		name        string
+		replace     [][2]string
+		wantErr     string
+		wantApplied []string
+		wantRestart []string
+		checkConfig func(t *testing.T, cfg *Config)
+	}{
+		{
+			name:        "unchanged",
+			wantApplied: []string{},
+			wantRestart: []string{},
+		},
+		{
+			name:        "hook timeout and log level",
+			replace:     [][2]string{{"enabled: true", "enabled: true\n  timeout: 5s"}, {"level: info", "level: debug"}},
+			wantApplied: []string{"hooks", "logging.level"},
+			wantRestart: []string{},
+			checkConfig: func(t *testing.T, cfg *Config) {
+				if cfg.Hooks.Timeout != 5*time.Second {
+					t.Errorf("Hooks.Timeout = %v, want 5s", cfg.Hooks.Timeout)
+				}
+				if cfg.Logging.Level != "debug" {
+					t.Errorf("Logging.Level = %v, want debug", cfg.Logging.Level)
+				}
+			},
+		},
+		{
+			name:        "health check and priority",
+			replace:     [][2]string{{"logging:", "track:\n  checks:\n    - command: /usr/local/bin/check-disk\nscore:\n  priority: 150\nlogging:"}},
+			wantApplied: []string{"track.checks", "score.priority"},
+			wantRestart: []string{},
+			checkConfig: func(t *testing.T, cfg *Config) {
+				if len(cfg.Track.Checks) != 1 || cfg.Track.Checks[0].Name != "check-disk" {
+					t.Errorf("Track.Checks = %+v, want check-disk", cfg.Track.Checks)
+				}
+				if cfg.Score.Priority != 150 {
+					t.Errorf("Score.Priority = %v, want 150", cfg.Score.Priority)
+				}
+			},
+		},
+		{
+			name:        "shutdown timeouts",
+			replace:     [][2]string{{"logging:", "shutdown:\n  handoff_timeout: 3s\nlogging:"}},
+			wantApplied: []string{"shutdown"},
+			wantRestart: []string{},
+			checkConfig: func(t *testing.T, cfg *Config) {
+				if cfg.Shutdown.HandoffTimeout != 3*time.Second || cfg.Shutdown.Timeout != DefaultShutdownTimeout {
+					t.Errorf("Shutdown = %+v, want a 3s handoff timeout", cfg.Shutdown)
+				}
+			},
+		},
+		{
+			name:        "startup settings kept",
+			replace:     [][2]string{{"format: json", "format: text"}, {"enabled: true", "enabled: true\n  history:\n    max_records: 10"}},
+			wantApplied: []string{},
+			wantRestart: []string{"hooks.history", "logging.format"},
+			checkConfig: func(t *testing.T, cfg *Config) {
+				if cfg.Logging.Format != "json" {
+					t.Errorf("Logging.Format = %v, want json", cfg.Logging.Format)
+				}
+				if cfg.Hooks.History.MaxRecords != DefaultHistoryMaxRecords {
+					t.Errorf("Hooks.History.MaxRecords = %v, want %v", cfg.Hooks.History.MaxRecords, DefaultHistoryMaxRecords)
+				}
+			},
+		},
+		{
+			name:    "node id rejected",
+			replace: [][2]string{{"id: node1\n  raft_addr: 127.0.0.1:10001", "id: node2\n  raft_addr: 127.0.0.1:10002"}},
+			wantErr: "node.id cannot change",
+		},
+		{
+			name:    "raft addr rejected",
+			replace: [][2]string{{"127.0.0.1:10001", "127.0.0.1:10003"}},
+			wantErr: "node.raft_addr cannot change",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := loadReloadConfig(t, reloadBase)
+			// The running config has had its hook defaults filled in
+			current.GetHookByEventType("ToMaster")
+
+			content := reloadBase
+			for _, r := range tt.replace {
+				content = strings.ReplaceAll(content, r[0], r[1])
+			}
+			next := loadReloadConfig(t, content)
+
+			merged, result, err := current.Reload(next)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Reload() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Reload() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result.Applied, tt.wantApplied) {
+				t.Errorf("Reload() applied = %v, want %v", result.Applied, tt.wantApplied)
+			}
+			if !reflect.DeepEqual(result.RestartRequired, tt.wantRestart) {
+				t.Errorf("Reload() restart required = %v, want %v", result.RestartRequired, tt.wantRestart)
+			}
+			if tt.checkConfig != nil {
+				tt.checkConfig(t, merged)
+			}
+			if current.Hooks.Timeout != 60*time.Second {
+				t.Errorf("current Hooks.Timeout = %v, want it untouched", current.Hooks.Timeout)
+			}
+		})
+	}
+}
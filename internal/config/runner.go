@@ -0,0 +1,75 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"net"
+	"net/url"
+	"slices"
+)
+
+// Hook runners a hook definition can list
+const (
+	RunnerExec       = "exec"        // runs command with args
+	RunnerHTTP       = "http"        // posts the payload to http.url
+	RunnerNetlinkVIP = "netlink-vip" // adds or removes vip.address on vip.interface
+	RunnerBuiltinARP = "builtin-arp" // announces vip.address with gratuitous ARP
+)
+
+// Runners lists the hook runners in the order they are documented
+var Runners = []string{RunnerExec, RunnerHTTP, RunnerNetlinkVIP, RunnerBuiltinARP}
+
+// RunnerNames returns the runners of the hook in order: Runners when set,
+// otherwise exec when Command is set and none when it is not
+func (h *HookDefinition) RunnerNames() []string {
+	if len(h.Runners) > 0 {
+		return h.Runners
+	}
+	if h.Command != "" {
+		return []string{RunnerExec}
+	}
+	return nil
+}
+
+// validateRunners checks that the runners of a hook are known, listed
+// once and have what they need configured
+func (c *Config) validateRunners(v *validator, path string, hookDef *HookDefinition) {
+	key := path + ".runners"
+	for i, name := range hookDef.Runners {
+		if slices.Contains(hookDef.Runners[:i], name) {
+			v.add(key, "%s.runners: %s is listed twice", path, name)
+			continue
+		}
+		switch name {
+		case RunnerExec:
+			if hookDef.Command == "" {
+				v.add(key, "%s.runners: exec requires command", path)
+			}
+		case RunnerHTTP:
+			if u, err := url.Parse(hookDef.HTTP.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				v.add(path+".http.url", "%s.runners: http requires an http or https http.url", path)
+			}
+		case RunnerNetlinkVIP, RunnerBuiltinARP:
+			ip, _, err := net.ParseCIDR(c.VIP.Address)
+			if err != nil || c.VIP.Interface == "" {
+				v.add(key, "%s.runners: %s requires vip.address and vip.interface", path, name)
+			} else if name == RunnerBuiltinARP && ip.To4() == nil {
+				v.add(key, "%s.runners: builtin-arp requires an IPv4 vip.address", path)
+			}
+		default:
+			v.add(key, "%s.runners: unknown runner %s (must be exec, http, netlink-vip or builtin-arp)", path, name)
+		}
+	}
+}
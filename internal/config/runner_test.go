@@ -0,0 +1,94 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHookDefinition_RunnerNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		hookDef HookDefinition
+		want    []string
+	}{
+		{"command", HookDefinition{Command: "/bin/true"}, []string{RunnerExec}},
+		{"nothing", HookDefinition{}, nil},
+		{"listed", HookDefinition{Command: "/bin/true", Runners: []string{RunnerNetlinkVIP, RunnerExec}}, []string{RunnerNetlinkVIP, RunnerExec}},
+		{"listed without command", HookDefinition{Runners: []string{RunnerHTTP}}, []string{RunnerHTTP}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hookDef.RunnerNames(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RunnerNames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_RunnerProblems(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+node:
+  id: node1
+  raft_addr: 127.0.0.1:10001
+  data_dir: /var/lib/vip-switch
+cluster:
+  nodes:
+    - id: node1
+      addr: 127.0.0.1:10001
+vip:
+  address: 2001:db8::10/64
+  interface: eth0
+hooks:
+  ToMaster:
+    runners: [netlink-vip, builtin-arp, exec, exec]
+  ToSlave:
+    runners: [http, ssh]
+    http:
+      url: ftp://example.com/vip
+  ToDestroy:
+    runners: [netlink-vip, http]
+    http:
+      url: https://example.com/vip
+logging:
+  level: info
+  format: json
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	_, err := Load(path)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Load() error = %v, want a ValidationError", err)
+	}
+	want := []string{
+		path + ":15: hooks.ToMaster.runners: builtin-arp requires an IPv4 vip.address",
+		path + ":15: hooks.ToMaster.runners: exec requires command",
+		path + ":15: hooks.ToMaster.runners: exec is listed twice",
+		path + ":17: hooks.ToSlave.runners: unknown runner ssh (must be exec, http, netlink-vip or builtin-arp)",
+		path + ":19: hooks.ToSlave.runners: http requires an http or https http.url",
+	}
+	if got := verr.Lines(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines() =\n%v\nwant\n%v", got, want)
+	}
+}
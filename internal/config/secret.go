@@ -0,0 +1,140 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"vip-switch-go/internal/redact"
+)
+
+// ReadSecrets reads the files of EnvironmentFromFile, returning the
+// variables they define. A trailing newline is not part of a value.
+func (h *HookDefinition) ReadSecrets() (map[string]string, error) {
+	return readSecretFiles("environment_from_file", h.EnvironmentFromFile)
+}
+
+// ReadSecrets reads the files of HeadersFromFile, returning the headers
+// they define
+func (h *HTTPHook) ReadSecrets() (map[string]string, error) {
+	return readSecretFiles("http.headers_from_file", h.HeadersFromFile)
+}
+
+// readSecretFiles reads a secret from each file in files, keyed like files.
+// A trailing newline is not part of a value.
+func readSecretFiles(field string, files map[string]string) (map[string]string, error) {
+	secrets := make(map[string]string, len(files))
+	for name, path := range files {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", field, name, err)
+		}
+		secrets[name] = strings.TrimRight(string(content), "\r\n")
+	}
+	return secrets, nil
+}
+
+// ReadSecrets reads the secret files of every hook, returning their values
+// so that they can be redacted before any hook runs
+func (c *Config) ReadSecrets() ([]string, error) {
+	var values []string
+	for _, eventType := range EventTypes {
+		hookDef, err := c.hookDefinition(eventType)
+		if err != nil {
+			return nil, err
+		}
+		for _, read := range []func() (map[string]string, error){hookDef.ReadSecrets, hookDef.HTTP.ReadSecrets} {
+			secrets, err := read()
+			if err != nil {
+				return nil, fmt.Errorf("hooks.%s.%w", eventType, err)
+			}
+			for _, value := range secrets {
+				values = append(values, value)
+			}
+		}
+	}
+	return values, nil
+}
+
+// validateSecrets checks that secret files are given by absolute path, are
+// readable when hooks are enabled and do not shadow a plain value
+func (c *Config) validateSecrets(v *validator, path string, hookDef *HookDefinition) {
+	c.validateSecretFiles(v, path, "environment", hookDef.Environment, "environment_from_file", hookDef.EnvironmentFromFile)
+	c.validateSecretFiles(v, path, "http.headers", hookDef.HTTP.Headers, "http.headers_from_file", hookDef.HTTP.HeadersFromFile)
+}
+
+// validateSecretFiles checks the secret files of field, whose names may not
+// also be set in the plain values of plainField
+func (c *Config) validateSecretFiles(v *validator, path, plainField string, plain map[string]string, field string, files map[string]string) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		file := files[name]
+		key := path + "." + field + "." + name
+		if _, ok := plain[name]; ok {
+			v.add(key, "%s: %s is also set in %s", path, name, plainField)
+		}
+		if !filepath.IsAbs(file) {
+			v.add(key, "%s.%s.%s: %s is not an absolute path", path, field, name, file)
+			continue
+		}
+		if c.Hooks.Enabled {
+			if f, err := os.Open(file); err != nil {
+				v.add(key, "%s.%s.%s: %v", path, field, name, err)
+			} else {
+				f.Close()
+			}
+		}
+	}
+}
+
+// redactHeaders hides the values of sensitive HTTP headers. The header maps
+// are replaced, so c may be a shallow copy of a configuration in use.
+func (c *Config) redactHeaders() {
+	for _, eventType := range EventTypes {
+		hookDef, _ := c.hookDefinition(eventType)
+		headers := maps.Clone(hookDef.HTTP.Headers)
+		for name := range headers {
+			if sensitiveHeader(name) {
+				headers[name] = redact.Placeholder
+			}
+		}
+		hookDef.HTTP.Headers = headers
+	}
+}
+
+// sensitiveHeader reports whether a header likely carries a credential, so
+// that config show hides its value
+func sensitiveHeader(name string) bool {
+	name = strings.ToLower(name)
+	switch name {
+	case "authorization", "proxy-authorization", "cookie":
+		return true
+	}
+	for _, word := range []string{"token", "secret", "key", "password", "auth"} {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHookDefinition_ReadSecrets(t *testing.T) {
+	dir := t.TempDir()
+	token := filepath.Join(dir, "token")
+	if err := os.WriteFile(token, []byte("s3cr3t\n"), 0600); err != nil {
+		t.Fatalf("failed to write secret: %v", err)
+	}
+
+	hookDef := &HookDefinition{EnvironmentFromFile: map[string]string{"API_TOKEN": token}}
+	secrets, err := hookDef.ReadSecrets()
+	if err != nil {
+		t.Fatalf("ReadSecrets() error = %v", err)
+	}
+	if want := map[string]string{"API_TOKEN": "s3cr3t"}; !reflect.DeepEqual(secrets, want) {
+		t.Errorf("ReadSecrets() = %v, want %v", secrets, want)
+	}
+
+	hookDef.EnvironmentFromFile["MISSING"] = filepath.Join(dir, "missing")
+	if _, err := hookDef.ReadSecrets(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ReadSecrets() error = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestConfig_ReadSecrets(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"token": "s3cr3t\n", "key": "k3y-value"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write secret: %v", err)
+		}
+	}
+
+	cfg := &Config{}
+	cfg.Hooks.ToMaster.EnvironmentFromFile = map[string]string{"API_TOKEN": filepath.Join(dir, "token")}
+	cfg.Hooks.EnsureSlave.HTTP.HeadersFromFile = map[string]string{"X-Api-Key": filepath.Join(dir, "key")}
+	values, err := cfg.ReadSecrets()
+	if err != nil {
+		t.Fatalf("ReadSecrets() error = %v", err)
+	}
+	if want := []string{"s3cr3t", "k3y-value"}; !reflect.DeepEqual(values, want) {
+		t.Errorf("ReadSecrets() = %v, want %v", values, want)
+	}
+
+	cfg.Hooks.ToSlave.EnvironmentFromFile = map[string]string{"MISSING": filepath.Join(dir, "missing")}
+	if _, err := cfg.ReadSecrets(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ReadSecrets() error = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestSensitiveHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"Authorization", true},
+		{"proxy-authorization", true},
+		{"Cookie", true},
+		{"X-Api-Key", true},
+		{"X-Auth-Token", true},
+		{"Content-Type", false},
+		{"X-Request-Source", false},
+	}
+
+	for _, tt := range tests {
+		if got := sensitiveHeader(tt.name); got != tt.want {
+			t.Errorf("sensitiveHeader(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoad_SecretProblems(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	content := `
+node:
+  id: node1
+  raft_addr: 127.0.0.1:10001
+  data_dir: /var/lib/vip-switch
+cluster:
+  nodes:
+    - id: node1
+      addr: 127.0.0.1:10001
+hooks:
+  enabled: true
+  ToMaster:
+    command: /bin/true
+    environment:
+      API_TOKEN: plain
+    environment_from_file:
+      API_TOKEN: /etc/vip-switch/token
+      DNS_KEY: dns.key
+      MISSING: ` + filepath.Join(dir, "missing") + `
+    http:
+      headers:
+        Authorization: plain
+      headers_from_file:
+        Authorization: auth.txt
+logging:
+  level: info
+  format: json
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	_, err := Load(path)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Load() error = %v, want a ValidationError", err)
+	}
+	want := []string{
+		path + ":17: hooks.ToMaster: API_TOKEN is also set in environment",
+		path + ":17: hooks.ToMaster.environment_from_file.API_TOKEN: open /etc/vip-switch/token: no such file or directory",
+		path + ":18: hooks.ToMaster.environment_from_file.DNS_KEY: dns.key is not an absolute path",
+		path + ":19: hooks.ToMaster.environment_from_file.MISSING: open " + filepath.Join(dir, "missing") + ": no such file or directory",
+		path + ":24: hooks.ToMaster: Authorization is also set in http.headers",
+		path + ":24: hooks.ToMaster.http.headers_from_file.Authorization: auth.txt is not an absolute path",
+	}
+	if got := verr.Lines(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines() =\n%v\nwant\n%v", got, want)
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+// storageBackends lists the accepted storage.backend values
+var storageBackends = map[string]bool{StorageBolt: true, StorageBBolt: true, StorageInmem: true}
+
+// WithDefaults returns s with the defaults for unset settings, so that a
+// configuration built in code runs like a loaded one
+func (s StorageConfig) WithDefaults() StorageConfig {
+	if s.Backend == "" {
+		s.Backend = DefaultStorageBackend
+	}
+	if s.RetainSnapshots == 0 {
+		s.RetainSnapshots = DefaultStorageRetainSnapshots
+	}
+	if s.TrailingLogs == 0 {
+		s.TrailingLogs = DefaultStorageTrailingLogs
+	}
+	if s.SnapshotInterval == 0 {
+		s.SnapshotInterval = DefaultStorageSnapshotInterval
+	}
+	if s.SnapshotThreshold == 0 {
+		s.SnapshotThreshold = DefaultStorageSnapshotThreshold
+	}
+	return s
+}
+
+// validateStorage checks the storage backend and retention settings
+func (c *Config) validateStorage(v *validator) {
+	if c.Storage.Backend != "" && !storageBackends[c.Storage.Backend] {
+		v.add("storage.backend", "storage.backend: invalid value %s (must be bolt, bbolt or inmem)", c.Storage.Backend)
+	}
+	if c.Storage.RetainSnapshots < 0 {
+		v.add("storage.retain_snapshots", "storage.retain_snapshots must not be negative")
+	}
+	if c.Storage.SnapshotInterval < 0 {
+		v.add("storage.snapshot_interval", "storage.snapshot_interval must not be negative")
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStorageConfig_WithDefaults(t *testing.T) {
+	defaults := StorageConfig{
+		Backend:           DefaultStorageBackend,
+		RetainSnapshots:   DefaultStorageRetainSnapshots,
+		TrailingLogs:      DefaultStorageTrailingLogs,
+		SnapshotInterval:  DefaultStorageSnapshotInterval,
+		SnapshotThreshold: DefaultStorageSnapshotThreshold,
+	}
+	custom := StorageConfig{
+		Backend:           StorageInmem,
+		RetainSnapshots:   3,
+		TrailingLogs:      100,
+		SnapshotInterval:  time.Minute,
+		SnapshotThreshold: 50,
+	}
+
+	tests := []struct {
+		name    string
+		storage StorageConfig
+		want    StorageConfig
+	}{
+		{"empty", StorageConfig{}, defaults},
+		{"set", custom, custom},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.storage.WithDefaults(); got != tt.want {
+				t.Errorf("WithDefaults() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_StorageDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+node:
+  id: node1
+  raft_addr: 127.0.0.1:10001
+  data_dir: /var/lib/vip-switch
+cluster:
+  nodes:
+    - id: node1
+      addr: 127.0.0.1:10001
+storage:
+  backend: bbolt
+vip:
+  address: 192.168.1.100/32
+  interface: eth0
+logging:
+  level: info
+  format: json
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Storage.Backend != StorageBBolt {
+		t.Errorf("Storage.Backend = %v, want %v", cfg.Storage.Backend, StorageBBolt)
+	}
+	if cfg.Storage.RetainSnapshots != DefaultStorageRetainSnapshots {
+		t.Errorf("Storage.RetainSnapshots = %v, want %v", cfg.Storage.RetainSnapshots, DefaultStorageRetainSnapshots)
+	}
+	if cfg.Storage.SnapshotInterval != DefaultStorageSnapshotInterval {
+		t.Errorf("Storage.SnapshotInterval = %v, want %v", cfg.Storage.SnapshotInterval, DefaultStorageSnapshotInterval)
+	}
+}
+
+func TestLoad_StorageProblems(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+node:
+  id: node1
+  raft_addr: 127.0.0.1:10001
+  data_dir: /var/lib/vip-switch
+cluster:
+  nodes:
+    - id: node1
+      addr: 127.0.0.1:10001
+storage:
+  backend: leveldb
+  retain_snapshots: -1
+  snapshot_interval: -5s
+vip:
+  address: 192.168.1.100/32
+  interface: eth0
+logging:
+  level: info
+  format: json
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	_, err := Load(path)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Load() error = %v, want a ValidationError", err)
+	}
+	want := []string{
+		path + ":11: storage.backend: invalid value leveldb (must be bolt, bbolt or inmem)",
+		path + ":12: storage.retain_snapshots must not be negative",
+		path + ":13: storage.snapshot_interval must not be negative",
+	}
+	if got := verr.Lines(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines() =\n%v\nwant\n%v", got, want)
+	}
+}
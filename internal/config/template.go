@@ -16,31 +16,83 @@ package config
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
 	"text/template"
+	"text/template/parse"
 )
 
 // TemplateData provides data for template expansion
 type TemplateData struct {
-	NodeID   string
-	Event    string
-	RaftAddr string
+	NodeID        string
+	Event         string
+	RaftAddr      string
+	PreviousState string
+	NewState      string
+	LeaderID      string
+	LeaderAddr    string
+	Term          uint64
+	Peers         []string // addresses of the other cluster members
+	VIP           VIPConfig
+	Hostname      string
+	Timestamp     string // RFC 3339, UTC
 }
 
-// ExpandTemplate expands template variables in a string
-func ExpandTemplate(templateStr string, data TemplateData) (string, error) {
-	tmpl, err := template.New("hook").Parse(templateStr)
-	if err != nil {
-		return "", err
+// templateFuncs are the functions available to hook templates. None of them
+// has side effects or touches the filesystem.
+var templateFuncs = template.FuncMap{
+	"env":     os.Getenv,
+	"default": defaultValue,
+	"join":    func(sep string, elems []string) string { return strings.Join(elems, sep) },
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+	"sha256":  sha256Hex,
+}
+
+// defaultValue returns value, or def when value is empty. It is written to
+// be piped into: {{ env "SITE" | default "dc1" }}.
+func defaultValue(def, value string) string {
+	if value == "" {
+		return def
 	}
+	return value
+}
 
+// sha256Hex returns the hex encoded SHA-256 digest of s
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseTemplate parses a hook template with the template functions and
+// strict handling of missing keys
+func ParseTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+}
+
+// executeTemplate renders tmpl with data
+func executeTemplate(tmpl *template.Template, data TemplateData) (string, error) {
 	var buf bytes.Buffer
 	if err := tmpl.Execute(&buf, data); err != nil {
 		return "", err
 	}
-
 	return buf.String(), nil
 }
 
+// ExpandTemplate expands template variables in a string
+func ExpandTemplate(templateStr string, data TemplateData) (string, error) {
+	tmpl, err := ParseTemplate("hook", templateStr)
+	if err != nil {
+		return "", err
+	}
+	return executeTemplate(tmpl, data)
+}
+
 // ExpandEnvironment expands template variables in environment variables map
 func ExpandEnvironment(env map[string]string, data TemplateData) (map[string]string, error) {
 	result := make(map[string]string, len(env))
@@ -53,3 +105,264 @@ func ExpandEnvironment(env map[string]string, data TemplateData) (map[string]str
 	}
 	return result, nil
 }
+
+// hookTemplates holds the parsed templates of a hook definition
+type hookTemplates struct {
+	args []*template.Template
+	env  map[string]*template.Template
+}
+
+// compileTemplates parses the args and environment templates of a hook and
+// checks the fields they reference against TemplateData, so that unknown
+// fields are reported when the configuration is loaded
+func (h *HookDefinition) compileTemplates() error {
+	compiled, err := h.parseTemplates()
+	if err != nil {
+		return err
+	}
+	h.templates = compiled
+	return nil
+}
+
+// parseTemplates parses and checks the args and environment templates
+func (h *HookDefinition) parseTemplates() (*hookTemplates, error) {
+	compiled := &hookTemplates{env: make(map[string]*template.Template, len(h.Environment))}
+
+	for i, arg := range h.Args {
+		tmpl, err := ParseTemplate(fmt.Sprintf("args[%d]", i), arg)
+		if err != nil {
+			return nil, err
+		}
+		compiled.args = append(compiled.args, tmpl)
+	}
+	for key, value := range h.Environment {
+		tmpl, err := ParseTemplate("environment."+key, value)
+		if err != nil {
+			return nil, err
+		}
+		compiled.env[key] = tmpl
+	}
+
+	checks := append([]*template.Template{}, compiled.args...)
+	for _, key := range sortedKeys(compiled.env) {
+		checks = append(checks, compiled.env[key])
+	}
+	for _, tmpl := range checks {
+		if err := checkFields(tmpl); err != nil {
+			return nil, err
+		}
+	}
+	return compiled, nil
+}
+
+// checkFields walks the parse tree of tmpl and reports references to fields
+// TemplateData does not have. Other errors, such as indexing an empty Peers
+// list, depend on the data and are left to execution time.
+func checkFields(tmpl *template.Template) error {
+	if tmpl.Tree == nil || tmpl.Tree.Root == nil {
+		return nil
+	}
+	c := &fieldChecker{tmpl: tmpl, root: reflect.TypeOf(TemplateData{})}
+	return c.walk(tmpl.Tree.Root, c.root)
+}
+
+// fieldChecker follows the type of dot through a template. A nil type is
+// one that cannot be known before execution, such as a function result; no
+// fields are checked below it.
+type fieldChecker struct {
+	tmpl *template.Template
+	root reflect.Type
+}
+
+// walk checks node, evaluated with dot of type dot
+func (c *fieldChecker) walk(node parse.Node, dot reflect.Type) error {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return nil
+		}
+		for _, child := range node.Nodes {
+			if err := c.walk(child, dot); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		_, err := c.pipe(node.Pipe, dot)
+		return err
+	case *parse.IfNode:
+		return c.branch(&node.BranchNode, dot, dot)
+	case *parse.WithNode:
+		typ, err := c.pipe(node.Pipe, dot)
+		if err != nil {
+			return err
+		}
+		return c.branch(&node.BranchNode, typ, dot)
+	case *parse.RangeNode:
+		typ, err := c.pipe(node.Pipe, dot)
+		if err != nil {
+			return err
+		}
+		return c.branch(&node.BranchNode, elemType(typ), dot)
+	case *parse.TemplateNode:
+		_, err := c.pipe(node.Pipe, dot)
+		return err
+	}
+	return nil
+}
+
+// branch checks the pipeline and both lists of an if, with or range. The
+// list runs with dot of type inner, the else list with outer.
+func (c *fieldChecker) branch(node *parse.BranchNode, inner, outer reflect.Type) error {
+	if _, err := c.pipe(node.Pipe, outer); err != nil {
+		return err
+	}
+	if err := c.walk(node.List, inner); err != nil {
+		return err
+	}
+	return c.walk(node.ElseList, outer)
+}
+
+// pipe checks a pipeline and returns the type it yields, or nil if unknown
+func (c *fieldChecker) pipe(pipe *parse.PipeNode, dot reflect.Type) (reflect.Type, error) {
+	if pipe == nil {
+		return nil, nil
+	}
+	var typ reflect.Type
+	for _, cmd := range pipe.Cmds {
+		typ = nil
+		for _, arg := range cmd.Args {
+			argType, err := c.arg(arg, dot)
+			if err != nil {
+				return nil, err
+			}
+			if len(cmd.Args) == 1 {
+				typ = argType
+			}
+		}
+	}
+	return typ, nil
+}
+
+// arg checks an argument of a command and returns its type, or nil if
+// unknown
+func (c *fieldChecker) arg(node parse.Node, dot reflect.Type) (reflect.Type, error) {
+	switch node := node.(type) {
+	case *parse.DotNode:
+		return dot, nil
+	case *parse.FieldNode:
+		return c.fields(node, dot, node.Ident)
+	case *parse.VariableNode:
+		// Only $ is known before execution, it is the data itself
+		if node.Ident[0] != "$" {
+			return nil, nil
+		}
+		return c.fields(node, c.root, node.Ident[1:])
+	case *parse.ChainNode:
+		typ, err := c.arg(node.Node, dot)
+		if err != nil {
+			return nil, err
+		}
+		return c.fields(node, typ, node.Field)
+	case *parse.PipeNode:
+		return c.pipe(node, dot)
+	}
+	return nil, nil
+}
+
+// fields follows the field and method names from typ and reports the first
+// one typ does not have
+func (c *fieldChecker) fields(node parse.Node, typ reflect.Type, names []string) (reflect.Type, error) {
+	for _, name := range names {
+		if typ == nil {
+			return nil, nil
+		}
+		if method, ok := reflect.PointerTo(typ).MethodByName(name); ok {
+			typ = nil
+			if method.Type.NumOut() > 0 {
+				typ = method.Type.Out(0)
+			}
+			continue
+		}
+		for typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		if typ.Kind() != reflect.Struct {
+			return nil, nil
+		}
+		field, ok := typ.FieldByName(name)
+		if !ok || !field.IsExported() {
+			location, context := c.tmpl.ErrorContext(node)
+			return nil, fmt.Errorf("template: %s: at <%s>: no field %s in type %s", location, context, name, typ)
+		}
+		typ = field.Type
+	}
+	return typ, nil
+}
+
+// elemType returns the type of the elements a range over typ yields, or nil
+// if unknown
+func elemType(typ reflect.Type) reflect.Type {
+	if typ == nil {
+		return nil
+	}
+	switch typ.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Chan:
+		return typ.Elem()
+	}
+	return nil
+}
+
+// templatesOrParse returns the templates parsed by Load, or parses them now
+// for definitions that did not come from Load
+func (h *HookDefinition) templatesOrParse() (*hookTemplates, error) {
+	if h.templates != nil {
+		return h.templates, nil
+	}
+	return h.parseTemplates()
+}
+
+// ExpandArgs renders the hook's args templates with data
+func (h *HookDefinition) ExpandArgs(data TemplateData) ([]string, error) {
+	compiled, err := h.templatesOrParse()
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, 0, len(compiled.args))
+	for _, tmpl := range compiled.args {
+		arg, err := executeTemplate(tmpl, data)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
+
+// ExpandEnvironment renders the hook's environment templates with data
+func (h *HookDefinition) ExpandEnvironment(data TemplateData) (map[string]string, error) {
+	compiled, err := h.templatesOrParse()
+	if err != nil {
+		return nil, err
+	}
+
+	env := make(map[string]string, len(compiled.env))
+	for key, tmpl := range compiled.env {
+		value, err := executeTemplate(tmpl, data)
+		if err != nil {
+			return nil, err
+		}
+		env[key] = value
+	}
+	return env, nil
+}
+
+// sortedKeys returns the keys of m in order, for deterministic error reports
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -216,3 +216,99 @@ func TestExpandEnvironment(t *testing.T) {
 		})
 	}
 }
+
+func TestExpandTemplate_Functions(t *testing.T) {
+	t.Setenv("VIPTEST_SITE", "dc2")
+
+	data := TemplateData{
+		NodeID:   "node1",
+		Event:    "ToMaster",
+		Peers:    []string{"10.0.0.2:7946", "10.0.0.3:7946"},
+		VIP:      VIPConfig{Address: "10.0.0.100/32", Interface: "eth0"},
+		Term:     7,
+		Hostname: "host1",
+	}
+
+	tests := []struct {
+		name        string
+		templateStr string
+		want        string
+	}{
+		{name: "env", templateStr: `{{env "VIPTEST_SITE"}}`, want: "dc2"},
+		{name: "default unset", templateStr: `{{env "VIPTEST_UNSET" | default "dc1"}}`, want: "dc1"},
+		{name: "default set", templateStr: `{{env "VIPTEST_SITE" | default "dc1"}}`, want: "dc2"},
+		{name: "join", templateStr: `{{join "," .Peers}}`, want: "10.0.0.2:7946,10.0.0.3:7946"},
+		{name: "upper", templateStr: `{{upper .Event}}`, want: "TOMASTER"},
+		{name: "sha256", templateStr: `{{sha256 "abc"}}`, want: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{name: "vip", templateStr: `{{.VIP.Address}} dev {{.VIP.Interface}}`, want: "10.0.0.100/32 dev eth0"},
+		{name: "term", templateStr: `term-{{.Term}}`, want: "term-7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExpandTemplate(tt.templateStr, data)
+			if err != nil {
+				t.Fatalf("ExpandTemplate() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ExpandTemplate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHookDefinition_ExpandArgs(t *testing.T) {
+	hookDef := &HookDefinition{
+		Command: "/usr/local/bin/vip",
+		Args:    []string{"add", "{{.VIP.Address}}", "--prev={{.PreviousState}}"},
+	}
+	if err := hookDef.compileTemplates(); err != nil {
+		t.Fatalf("compileTemplates() unexpected error: %v", err)
+	}
+
+	args, err := hookDef.ExpandArgs(TemplateData{
+		PreviousState: "Slave",
+		VIP:           VIPConfig{Address: "10.0.0.100/32"},
+	})
+	if err != nil {
+		t.Fatalf("ExpandArgs() unexpected error: %v", err)
+	}
+	want := []string{"add", "10.0.0.100/32", "--prev=Slave"}
+	for i := range want {
+		if i >= len(args) || args[i] != want[i] {
+			t.Fatalf("ExpandArgs() = %v, want %v", args, want)
+		}
+	}
+}
+
+func TestHookDefinition_CompileTemplates(t *testing.T) {
+	tests := []struct {
+		name    string
+		hookDef HookDefinition
+		wantErr bool
+	}{
+		{name: "valid", hookDef: HookDefinition{Args: []string{"{{.NodeID}}"}, Environment: map[string]string{"A": "{{upper .Event}}"}}},
+		{name: "index is checked at run time", hookDef: HookDefinition{Args: []string{"{{index .Peers 0}}"}}},
+		{name: "unknown field in args", hookDef: HookDefinition{Args: []string{"{{.Leader}}"}}, wantErr: true},
+		{name: "unknown field in environment", hookDef: HookDefinition{Environment: map[string]string{"A": "{{.Nope}}"}}, wantErr: true},
+		{name: "unknown function", hookDef: HookDefinition{Args: []string{`{{exec "rm"}}`}}, wantErr: true},
+		{name: "syntax error", hookDef: HookDefinition{Args: []string{"{{.NodeID"}}, wantErr: true},
+		{name: "nested field", hookDef: HookDefinition{Args: []string{"{{.VIP.Address}}", "{{(.VIP).Interface}}"}}},
+		{name: "unknown nested field", hookDef: HookDefinition{Args: []string{"{{.VIP.Nope}}"}}, wantErr: true},
+		{name: "with moves dot", hookDef: HookDefinition{Args: []string{"{{with .VIP}}{{.Address}}{{else}}{{.NodeID}}{{end}}"}}},
+		{name: "unknown field under with", hookDef: HookDefinition{Args: []string{"{{with .VIP}}{{.NodeID}}{{end}}"}}, wantErr: true},
+		{name: "range over peers", hookDef: HookDefinition{Args: []string{"{{range .Peers}}{{.}} {{$.NodeID}}{{end}}"}}},
+		{name: "unknown field of root variable", hookDef: HookDefinition{Args: []string{"{{range .Peers}}{{$.Leader}}{{end}}"}}, wantErr: true},
+		{name: "unknown field in branch not taken", hookDef: HookDefinition{Args: []string{"{{if .LeaderID}}{{.Leader}}{{end}}"}}, wantErr: true},
+		{name: "function results are not checked", hookDef: HookDefinition{Args: []string{`{{$v := env "X"}}{{$v}}`}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.hookDef.compileTemplates()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("compileTemplates() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
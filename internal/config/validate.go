@@ -0,0 +1,286 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Problem is a single error found in a configuration
+type Problem struct {
+	Path    string // setting the problem is about, e.g. hooks.ToMaster.on_failure
+	File    string // file the setting was read from
+	Line    int    // line of the setting in File, 0 if unknown
+	Origin  string // environment variable or flag that set it, empty for the file
+	Message string
+}
+
+// String formats the problem as file:line: message, or origin: message for
+// a setting that did not come from a file
+func (p Problem) String() string {
+	switch {
+	case p.Origin != "":
+		return fmt.Sprintf("%s: %s", p.Origin, p.Message)
+	case p.File != "" && p.Line > 0:
+		return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Message)
+	case p.Line > 0:
+		return fmt.Sprintf("line %d: %s", p.Line, p.Message)
+	default:
+		return p.Message
+	}
+}
+
+// ValidationError lists every problem found in a configuration
+type ValidationError struct {
+	File       string
+	Problems   []Problem
+	IgnoredEnv []string // see Config.IgnoredEnv
+}
+
+// Error reports all problems, one per line
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return "invalid configuration: " + e.Problems[0].String()
+	}
+	lines := e.Lines()
+	return fmt.Sprintf("invalid configuration, %d problems:\n  %s", len(lines), strings.Join(lines, "\n  "))
+}
+
+// Lines returns the problems formatted as file:line: message
+func (e *ValidationError) Lines() []string {
+	lines := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		lines[i] = p.String()
+	}
+	return lines
+}
+
+// validator collects problems, locating them in the parsed YAML document
+type validator struct {
+	sources  map[string]source // nil when the configuration was not read from a file
+	problems []Problem
+}
+
+// add records a problem with the setting at path
+func (v *validator) add(path, format string, args ...any) {
+	problem := Problem{Path: path, Message: fmt.Sprintf(format, args...)}
+	if s, ok := sourceOf(v.sources, path); ok {
+		problem.File, problem.Line, problem.Origin = s.file, s.line, s.override
+	}
+	v.problems = append(v.problems, problem)
+}
+
+// mappingValue returns the value for key in a mapping node and the line of
+// the key
+func mappingValue(node *yaml.Node, key string) (*yaml.Node, int) {
+	if node.Kind != yaml.MappingNode {
+		return nil, 0
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1], node.Content[i].Line
+		}
+	}
+	return nil, 0
+}
+
+// failureStrategies lists the accepted on_failure values
+var failureStrategies = map[string]bool{"abort": true, "continue": true, "retry": true}
+
+// validate checks the configuration and reports every problem at once, in
+// file order
+func (c *Config) validate() error {
+	v := &validator{sources: c.sources}
+
+	if c.Node.ID == "" {
+		v.add("node.id", "node.id is required")
+	}
+	if c.Node.RaftAddr == "" {
+		v.add("node.raft_addr", "node.raft_addr is required")
+	}
+	if c.Node.DataDir == "" {
+		v.add("node.data_dir", "node.data_dir is required")
+	}
+
+	if len(c.Cluster.Nodes) == 0 {
+		v.add("cluster.nodes", "cluster.nodes must have at least one entry")
+	}
+	c.validateCluster(v)
+	c.validateStorage(v)
+
+	// Validate log level
+	validLevels := map[string]bool{"debug": true, "info": true, "warn": true, "error": true}
+	if !validLevels[strings.ToLower(c.Logging.Level)] {
+		v.add("logging.level", "invalid log level: %s (must be debug, info, warn, or error)", c.Logging.Level)
+	}
+
+	// Validate log format
+	validFormats := map[string]bool{"json": true, "text": true}
+	if !validFormats[strings.ToLower(c.Logging.Format)] {
+		v.add("logging.format", "invalid log format: %s (must be json or text)", c.Logging.Format)
+	}
+
+	if _, err := ParseSignal(c.Hooks.KillSignal); err != nil {
+		v.add("hooks.kill_signal", "hooks.kill_signal: %v", err)
+	}
+	if c.Hooks.OnFailure != "" && !failureStrategies[c.Hooks.OnFailure] {
+		v.add("hooks.on_failure", "hooks.on_failure: invalid value %s (must be abort, continue or retry)", c.Hooks.OnFailure)
+	}
+	if c.Hooks.History.MaxRecords < 0 || c.Hooks.History.MaxAge < 0 || c.Hooks.History.OutputTail < 0 {
+		v.add("hooks.history", "hooks.history values must not be negative")
+	}
+	if c.Track.UpDelay < 0 {
+		v.add("track.up_delay", "track.up_delay must not be negative")
+	}
+	for i, iface := range c.Track.Interfaces {
+		if iface.Name == "" {
+			v.add(fmt.Sprintf("track.interfaces[%d]", i), "track.interfaces must not contain empty names")
+		}
+	}
+	names := make(map[string]bool)
+	for i, target := range c.Track.Targets {
+		path := fmt.Sprintf("track.targets[%d]", i)
+		if err := target.validate(); err != nil {
+			v.add(path, "track.targets.%s: %v", target.Name, err)
+		}
+		if names[target.Name] {
+			v.add(path, "track.targets: duplicate name %s", target.Name)
+		}
+		names[target.Name] = true
+	}
+	for i, check := range c.Track.Checks {
+		path := fmt.Sprintf("track.checks[%d]", i)
+		if err := check.validate(); err != nil {
+			v.add(path, "track.checks.%s: %v", check.Name, err)
+		}
+		if names[check.Name] {
+			v.add(path, "track.checks: duplicate name %s", check.Name)
+		}
+		names[check.Name] = true
+	}
+	if c.Score.Margin < 0 || c.Score.Interval < 0 {
+		v.add("score", "score.margin and score.interval must not be negative")
+	}
+	if c.Shutdown.HandoffTimeout < 0 || c.Shutdown.Timeout < 0 {
+		v.add("shutdown", "shutdown.handoff_timeout and shutdown.timeout must not be negative")
+	}
+	if c.Hooks.Ensure.Interval < 0 {
+		v.add("hooks.ensure.interval", "hooks.ensure.interval must not be negative")
+	}
+	if c.Hooks.Ensure.NativeCheck {
+		if _, _, err := net.ParseCIDR(c.VIP.Address); err != nil {
+			v.add("vip.address", "hooks.ensure.native_check: invalid vip.address: %v", err)
+		}
+		if c.VIP.Interface == "" {
+			v.add("vip.interface", "hooks.ensure.native_check requires vip.interface")
+		}
+	}
+
+	for _, eventType := range EventTypes {
+		c.validateHook(v, eventType)
+	}
+
+	if len(v.problems) == 0 {
+		return nil
+	}
+	sort.SliceStable(v.problems, func(i, j int) bool { return v.problems[i].Line < v.problems[j].Line })
+	return &ValidationError{File: c.filePath, Problems: v.problems}
+}
+
+// validateCluster checks that cluster members are unique and include this
+// node at its raft_addr
+func (c *Config) validateCluster(v *validator) {
+	ids := make(map[string]bool)
+	addrs := make(map[string]bool)
+	self := -1
+	for i, node := range c.Cluster.Nodes {
+		path := fmt.Sprintf("cluster.nodes[%d]", i)
+		if node.ID == "" {
+			v.add(path, "%s.id is required", path)
+		} else if ids[node.ID] {
+			v.add(path+".id", "cluster.nodes: duplicate id %s", node.ID)
+		}
+		if node.Addr == "" {
+			v.add(path, "%s.addr is required", path)
+		} else if addrs[node.Addr] {
+			v.add(path+".addr", "cluster.nodes: duplicate addr %s", node.Addr)
+		}
+		ids[node.ID] = true
+		addrs[node.Addr] = true
+		if node.ID == c.Node.ID && self < 0 {
+			self = i
+		}
+	}
+
+	if c.Node.ID == "" || len(c.Cluster.Nodes) == 0 {
+		return
+	}
+	if self < 0 {
+		v.add("node.id", "node.id %s is not listed in cluster.nodes", c.Node.ID)
+		return
+	}
+	if addr := c.Cluster.Nodes[self].Addr; c.Node.RaftAddr != "" && addr != "" && addr != c.Node.RaftAddr {
+		v.add("node.raft_addr", "node.raft_addr %s does not match cluster.nodes[%d].addr %s", c.Node.RaftAddr, self, addr)
+	}
+}
+
+// validateHook checks a hook definition: values that must parse, its
+// templates and, with hooks enabled, its command
+func (c *Config) validateHook(v *validator, eventType string) {
+	hookDef, _ := c.hookDefinition(eventType)
+	path := "hooks." + eventType
+
+	if err := hookDef.Retry.validate(); err != nil {
+		v.add(path+".retry", "%s: %v", path, err)
+	}
+	if hookDef.OnFailure != "" && !failureStrategies[hookDef.OnFailure] {
+		v.add(path+".on_failure", "%s.on_failure: invalid value %s (must be abort, continue or retry)", path, hookDef.OnFailure)
+	}
+	if hookDef.KillSignal != "" {
+		if _, err := ParseSignal(hookDef.KillSignal); err != nil {
+			v.add(path+".kill_signal", "%s.kill_signal: %v", path, err)
+		}
+	}
+	if _, err := ParseUmask(hookDef.Umask); err != nil {
+		v.add(path+".umask", "%s.umask: %v", path, err)
+	}
+	for _, name := range hookDef.Capabilities {
+		if _, err := ParseCapability(name); err != nil {
+			v.add(path+".capabilities", "%s.capabilities: %v", path, err)
+		}
+	}
+	if err := hookDef.Limits.validate(); err != nil {
+		v.add(path+".limits", "%s.%v", path, err)
+	}
+	if hookDef.Group != "" && hookDef.User == "" {
+		v.add(path+".group", "%s: group requires user to be set", path)
+	}
+	if err := hookDef.compileTemplates(); err != nil {
+		v.add(path, "%s: invalid template: %v", path, err)
+	}
+	c.validateSecrets(v, path, hookDef)
+	c.validateRunners(v, path, hookDef)
+	if c.Hooks.Enabled && hookDef.Command != "" {
+		if _, err := exec.LookPath(hookDef.Command); err != nil {
+			v.add(path+".command", "%s.command: %s is not an executable file", path, hookDef.Command)
+		}
+	}
+}
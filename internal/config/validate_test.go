@@ -0,0 +1,151 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoad_ValidationProblems(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Problem
+	}{
+		{
+			name: "node not in cluster",
+			content: `
+node:
+  id: node3
+  raft_addr: 127.0.0.1:10003
+  data_dir: /var/lib/vip-switch
+cluster:
+  nodes:
+    - id: node1
+      addr: 127.0.0.1:10001
+logging:
+  level: info
+  format: json
+`,
+			want: []Problem{
+				{Path: "node.id", File: "config.yaml", Line: 3, Message: "node.id node3 is not listed in cluster.nodes"},
+			},
+		},
+		{
+			name: "raft_addr mismatch and duplicates",
+			content: `
+node:
+  id: node1
+  raft_addr: 127.0.0.1:10009
+  data_dir: /var/lib/vip-switch
+cluster:
+  nodes:
+    - id: node1
+      addr: 127.0.0.1:10001
+    - id: node1
+      addr: 127.0.0.1:10001
+logging:
+  level: info
+  format: json
+`,
+			want: []Problem{
+				{Path: "node.raft_addr", File: "config.yaml", Line: 4, Message: "node.raft_addr 127.0.0.1:10009 does not match cluster.nodes[0].addr 127.0.0.1:10001"},
+				{Path: "cluster.nodes[1].id", File: "config.yaml", Line: 10, Message: "cluster.nodes: duplicate id node1"},
+				{Path: "cluster.nodes[1].addr", File: "config.yaml", Line: 11, Message: "cluster.nodes: duplicate addr 127.0.0.1:10001"},
+			},
+		},
+		{
+			name: "hook problems",
+			content: `
+node:
+  id: node1
+  raft_addr: 127.0.0.1:10001
+  data_dir: /var/lib/vip-switch
+cluster:
+  nodes:
+    - id: node1
+      addr: 127.0.0.1:10001
+hooks:
+  enabled: true
+  on_failure: ignore
+  ToMaster:
+    command: /nonexistent/vip-up
+    on_failure: skip
+    args: ["{{ .Node }}"]
+logging:
+  level: verbose
+  format: json
+`,
+			want: []Problem{
+				{Path: "hooks.on_failure", File: "config.yaml", Line: 12, Message: "hooks.on_failure: invalid value ignore (must be abort, continue or retry)"},
+				{Path: "hooks.ToMaster", File: "config.yaml", Line: 13, Message: `hooks.ToMaster: invalid template: template: args[0]:1:3: at <.Node>: no field Node in type config.TemplateData`},
+				{Path: "hooks.ToMaster.command", File: "config.yaml", Line: 14, Message: "hooks.ToMaster.command: /nonexistent/vip-up is not an executable file"},
+				{Path: "hooks.ToMaster.on_failure", File: "config.yaml", Line: 15, Message: "hooks.ToMaster.on_failure: invalid value skip (must be abort, continue or retry)"},
+				{Path: "logging.level", File: "config.yaml", Line: 18, Message: "invalid log level: verbose (must be debug, info, warn, or error)"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+			for i := range tt.want {
+				tt.want[i].File = path
+			}
+
+			_, err := Load(path)
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Load() error = %v, want a ValidationError", err)
+			}
+			if verr.File != path {
+				t.Errorf("ValidationError.File = %v, want %v", verr.File, path)
+			}
+			if !reflect.DeepEqual(verr.Problems, tt.want) {
+				t.Errorf("ValidationError.Problems =\n%+v\nwant\n%+v", verr.Problems, tt.want)
+			}
+		})
+	}
+}
+
+func TestSourceOf(t *testing.T) {
+	cfg := loadReloadConfig(t, reloadBase)
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"node.id", 3},
+		{"cluster.nodes[1].addr", 11},
+		{"cluster.nodes[5].addr", 7},
+		{"hooks.ToMaster.command", 15},
+		{"hooks.ToSlave.command", 12},
+		{"score.priority", 0},
+	}
+	for _, tt := range tests {
+		s, _ := sourceOf(cfg.sources, tt.path)
+		if s.line != tt.want {
+			t.Errorf("sourceOf(%s) line = %d, want %d", tt.path, s.line, tt.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consensus
+
+import (
+	"time"
+
+	"vip-switch-go/internal/config"
+)
+
+// Consensus elects the node that may hold the VIP and keeps the cluster's
+// shared log. raft.Node implements it; state.Machine only depends on it.
+type Consensus interface {
+	// Start joins the node to the cluster, Shutdown leaves it
+	Start() error
+	Shutdown() error
+
+	// LeaderCh delivers true when the node gains leadership and false when
+	// it loses it. IsLeader reports the current leadership.
+	LeaderCh() <-chan bool
+	IsLeader() bool
+
+	// Leader and LeaderID identify the current leader by address and ID,
+	// empty when there is none. Term grows with every election.
+	Leader() string
+	LeaderID() string
+	Term() uint64
+	AppliedIndex() uint64
+
+	// Members lists the voters. AddVoter and RemoveServer change them and
+	// TransferLeadership hands leadership to another voter, all on the
+	// leader only.
+	Members() []config.ClusterNode
+	AddVoter(id, address string) error
+	RemoveServer(id string) error
+	TransferLeadership() error
+	TransferLeadershipTo(id, address string) error
+
+	// Apply appends cmd to the shared log and waits until it is applied
+	Apply(cmd []byte, timeout time.Duration) error
+}
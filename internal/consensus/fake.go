@@ -0,0 +1,245 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consensus
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"vip-switch-go/internal/config"
+)
+
+var _ Consensus = (*Fake)(nil)
+
+// ErrNotLeader is returned by Fake for operations that need leadership
+var ErrNotLeader = errors.New("node is not the leader")
+
+// Fake is a Consensus whose elections the test drives. Nothing happens on
+// its own: the node leads after Elect and follows after Follow, and
+// leadership changes are delivered on LeaderCh in order, the oldest dropped
+// once 64 wait undrained.
+type Fake struct {
+	id string
+
+	mu        sync.Mutex
+	leaderCh  chan bool
+	leaderID  string
+	term      uint64
+	members   []config.ClusterNode
+	log       [][]byte
+	transfers int
+}
+
+// NewFake creates a fake for the node id in a cluster of members. No node
+// leads until the test elects one.
+func NewFake(id string, members []config.ClusterNode) *Fake {
+	return &Fake{
+		id:       id,
+		leaderCh: make(chan bool, 64),
+		members:  slices.Clone(members),
+	}
+}
+
+// Elect makes the node the leader in a new term
+func (f *Fake) Elect() {
+	f.setLeader(f.id)
+}
+
+// Follow makes the node with leaderID the leader in a new term, or leaves
+// the cluster without a leader when leaderID is empty
+func (f *Fake) Follow(leaderID string) {
+	f.setLeader(leaderID)
+}
+
+// Transfers returns how often leadership was transferred away
+func (f *Fake) Transfers() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.transfers
+}
+
+// Log returns the commands applied so far
+func (f *Fake) Log() [][]byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.log)
+}
+
+// setLeader moves to a new term led by id, notifying a change of the
+// node's own leadership
+func (f *Fake) setLeader(id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.setLeaderLocked(id)
+}
+
+// setLeaderLocked is setLeader for a caller holding f.mu
+func (f *Fake) setLeaderLocked(id string) {
+	wasLeader := f.leaderID == f.id
+	f.leaderID = id
+	f.term++
+	if isLeader := id == f.id; isLeader != wasLeader {
+		f.notifyLocked(isLeader)
+	}
+}
+
+// notifyLocked delivers a leadership change without blocking. When nobody
+// drained the channel the oldest change is dropped, as hashicorp/raft drops
+// a stale one, so the latest always arrives. Only holders of f.mu send, so
+// the slot freed by the drop stays free.
+func (f *Fake) notifyLocked(isLeader bool) {
+	select {
+	case f.leaderCh <- isLeader:
+		return
+	default:
+	}
+	select {
+	case <-f.leaderCh:
+	default:
+	}
+	f.leaderCh <- isLeader
+}
+
+// Start does nothing, the test elects the leader
+func (f *Fake) Start() error {
+	return nil
+}
+
+// Shutdown gives up leadership
+func (f *Fake) Shutdown() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.leaderID == f.id {
+		f.setLeaderLocked("")
+	}
+	return nil
+}
+
+// LeaderCh delivers the node's leadership changes
+func (f *Fake) LeaderCh() <-chan bool {
+	return f.leaderCh
+}
+
+// IsLeader reports whether the node leads
+func (f *Fake) IsLeader() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.leaderID != "" && f.leaderID == f.id
+}
+
+// Leader returns the leader's address
+func (f *Fake) Leader() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, member := range f.members {
+		if member.ID == f.leaderID {
+			return member.Addr
+		}
+	}
+	return f.leaderID
+}
+
+// LeaderID returns the leader's ID
+func (f *Fake) LeaderID() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.leaderID
+}
+
+// Term returns the current term
+func (f *Fake) Term() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.term
+}
+
+// AppliedIndex returns the number of commands applied
+func (f *Fake) AppliedIndex() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return uint64(len(f.log))
+}
+
+// Members returns the voters
+func (f *Fake) Members() []config.ClusterNode {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.members)
+}
+
+// AddVoter adds a voter, or updates its address
+func (f *Fake) AddVoter(id, address string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.leaderID != f.id {
+		return ErrNotLeader
+	}
+	f.members = slices.DeleteFunc(f.members, func(m config.ClusterNode) bool { return m.ID == id })
+	f.members = append(f.members, config.ClusterNode{ID: id, Addr: address})
+	return nil
+}
+
+// RemoveServer removes a voter
+func (f *Fake) RemoveServer(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.leaderID != f.id {
+		return ErrNotLeader
+	}
+	f.members = slices.DeleteFunc(f.members, func(m config.ClusterNode) bool { return m.ID == id })
+	return nil
+}
+
+// TransferLeadership hands leadership to the first other member
+func (f *Fake) TransferLeadership() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, member := range f.members {
+		if member.ID != f.id {
+			return f.transferLocked(member.ID)
+		}
+	}
+	return errors.New("no other voter to transfer leadership to")
+}
+
+// TransferLeadershipTo hands leadership to the member id
+func (f *Fake) TransferLeadershipTo(id, address string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.transferLocked(id)
+}
+
+// transferLocked hands leadership to id. The caller holds f.mu.
+func (f *Fake) transferLocked(id string) error {
+	if f.leaderID != f.id {
+		return ErrNotLeader
+	}
+	f.transfers++
+	f.setLeaderLocked(id)
+	return nil
+}
+
+// Apply appends cmd to the log
+func (f *Fake) Apply(cmd []byte, timeout time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.leaderID != f.id {
+		return ErrNotLeader
+	}
+	f.log = append(f.log, slices.Clone(cmd))
+	return nil
+}
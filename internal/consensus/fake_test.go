@@ -0,0 +1,143 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consensus
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"vip-switch-go/internal/config"
+)
+
+// testMembers is a three node cluster
+var testMembers = []config.ClusterNode{
+	{ID: "node1", Addr: "127.0.0.1:10001"},
+	{ID: "node2", Addr: "127.0.0.1:10002"},
+	{ID: "node3", Addr: "127.0.0.1:10003"},
+}
+
+// leaderChanges drains the leadership changes delivered so far
+func leaderChanges(f *Fake) []bool {
+	var changes []bool
+	for {
+		select {
+		case change := <-f.LeaderCh():
+			changes = append(changes, change)
+		default:
+			return changes
+		}
+	}
+}
+
+func TestFake_Elections(t *testing.T) {
+	f := NewFake("node1", testMembers)
+	if f.IsLeader() || f.LeaderID() != "" || f.Term() != 0 {
+		t.Fatalf("new fake: leader %v %q, term %d, want no leader in term 0", f.IsLeader(), f.LeaderID(), f.Term())
+	}
+
+	f.Elect()
+	if !f.IsLeader() || f.Leader() != "127.0.0.1:10001" || f.Term() != 1 {
+		t.Errorf("after Elect(): leader %v %q, term %d, want node1 in term 1", f.IsLeader(), f.Leader(), f.Term())
+	}
+
+	// Another node leading is not a change of the node's own leadership
+	f.Follow("node2")
+	f.Follow("node3")
+	f.Elect()
+	if got := leaderChanges(f); len(got) != 3 || !got[0] || got[1] || !got[2] {
+		t.Errorf("LeaderCh delivered %v, want true, false, true", got)
+	}
+	if f.Term() != 4 {
+		t.Errorf("Term() = %d, want 4", f.Term())
+	}
+
+	if err := f.TransferLeadership(); err != nil {
+		t.Fatalf("TransferLeadership() error = %v", err)
+	}
+	if f.LeaderID() != "node2" || f.Transfers() != 1 {
+		t.Errorf("after TransferLeadership(): leader %q, %d transfers, want node2 and 1", f.LeaderID(), f.Transfers())
+	}
+	if err := f.TransferLeadershipTo("node3", ""); !errors.Is(err, ErrNotLeader) {
+		t.Errorf("TransferLeadershipTo() as follower = %v, want %v", err, ErrNotLeader)
+	}
+}
+
+func TestFake_UndrainedLeaderCh(t *testing.T) {
+	f := NewFake("node1", testMembers)
+
+	// Nobody reads LeaderCh, yet elections neither block nor hold the lock
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			f.Elect()
+			f.Follow("node2")
+		}
+		f.Elect()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("elections blocked on an undrained LeaderCh")
+	}
+	if !f.IsLeader() {
+		t.Error("IsLeader() = false after the last Elect(), want true")
+	}
+
+	got := leaderChanges(f)
+	if len(got) != cap(f.leaderCh) || !got[len(got)-1] {
+		t.Errorf("LeaderCh delivered %v, want the latest %d changes ending in true", got, cap(f.leaderCh))
+	}
+}
+
+func TestFake_LeaderOnlyOperations(t *testing.T) {
+	f := NewFake("node1", testMembers)
+
+	tests := []struct {
+		name string
+		op   func() error
+	}{
+		{"Apply", func() error { return f.Apply([]byte("cmd"), 0) }},
+		{"AddVoter", func() error { return f.AddVoter("node4", "127.0.0.1:10004") }},
+		{"RemoveServer", func() error { return f.RemoveServer("node3") }},
+	}
+	for _, tt := range tests {
+		if err := tt.op(); !errors.Is(err, ErrNotLeader) {
+			t.Errorf("%s() as follower = %v, want %v", tt.name, err, ErrNotLeader)
+		}
+	}
+
+	f.Elect()
+	for _, tt := range tests {
+		if err := tt.op(); err != nil {
+			t.Errorf("%s() as leader = %v, want nil", tt.name, err)
+		}
+	}
+	if f.AppliedIndex() != 1 || string(f.Log()[0]) != "cmd" {
+		t.Errorf("log = %q, want [cmd]", f.Log())
+	}
+	ids := []string{}
+	for _, member := range f.Members() {
+		ids = append(ids, member.ID)
+	}
+	if len(ids) != 3 || ids[2] != "node4" {
+		t.Errorf("Members() = %v, want node1, node2, node4", ids)
+	}
+
+	if err := f.Shutdown(); err != nil || f.IsLeader() {
+		t.Errorf("Shutdown() = %v, leader %v, want the node to give up leadership", err, f.IsLeader())
+	}
+}
@@ -0,0 +1,319 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hook
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Limits are resource limits applied to a hook's process tree
+type Limits struct {
+	MemoryMax int64   // bytes, 0 for no limit
+	CPUWeight int     // 1 to 10000, 0 for the default
+	CPUQuota  float64 // fraction of one CPU, 0 for no quota
+	PidsMax   int     // 0 for no limit
+	NoFile    uint64  // RLIMIT_NOFILE, 0 to inherit
+}
+
+// needsCgroup reports whether any limit is enforced through a cgroup
+func (l *Limits) needsCgroup() bool {
+	return l.MemoryMax > 0 || l.CPUWeight > 0 || l.CPUQuota > 0 || l.PidsMax > 0
+}
+
+const (
+	cgroupMountPoint = "/sys/fs/cgroup"
+	cgroupHooksDir   = "vip-switch-hooks"
+	cgroupCPUPeriod  = 100000 // microseconds
+)
+
+// cgroupControllers are enabled for hook groups
+var cgroupControllers = []string{"memory", "pids", "cpu"}
+
+// errNoCgroup2 reports that cgroup v2 is not mounted, in which case hooks
+// fall back to rlimits
+var errNoCgroup2 = errors.New("cgroup v2 is not mounted at " + cgroupMountPoint)
+
+// delegateXattrs mark a cgroup that systemd delegated with Delegate=yes
+var delegateXattrs = []string{"trusted.delegate", "user.delegate"}
+
+// cgroupManager creates a transient cgroup v2 group for every limited hook
+// run. The parent directory is set up lazily on first use.
+type cgroupManager struct {
+	parent string // configured parent, empty to use a delegated subtree
+	once   sync.Once
+	dir    string // resolved parent directory
+	err    error  // non-nil when cgroups are unavailable
+	seq    atomic.Uint64
+}
+
+// newCgroupManager creates a manager using parent, or a subtree of the
+// daemon's own cgroup when parent is empty and that cgroup was delegated
+func newCgroupManager(parent string) *cgroupManager {
+	return &cgroupManager{parent: parent}
+}
+
+// init resolves and prepares the parent directory once
+func (m *cgroupManager) init() error {
+	m.once.Do(func() {
+		if m.parent != "" {
+			m.dir, m.err = m.parent, prepareParent(m.parent)
+			return
+		}
+		m.dir, m.err = setupDelegatedParent()
+	})
+	return m.err
+}
+
+// setupDelegatedParent creates the hooks subtree under the daemon's cgroup,
+// provided systemd delegated that cgroup to the daemon. A cgroup v2 group
+// cannot hand controllers to children while it contains processes itself,
+// so the daemon first moves into a "supervisor" leaf, as systemd's
+// delegation model expects. Without delegation the daemon would rewrite a
+// group it does not own, so it refuses.
+func setupDelegatedParent() (string, error) {
+	var fs unix.Statfs_t
+	if err := unix.Statfs(cgroupMountPoint, &fs); err != nil || fs.Type != unix.CGROUP2_SUPER_MAGIC {
+		return "", errNoCgroup2
+	}
+
+	own, err := ownCgroup()
+	if err != nil {
+		return "", err
+	}
+	base := filepath.Join(cgroupMountPoint, own)
+	if !delegated(base) {
+		return "", fmt.Errorf("cgroup %s is not delegated to vip-switch: set hooks.cgroup_parent, or run the daemon in a systemd unit with Delegate=yes", own)
+	}
+
+	supervisor := filepath.Join(base, "supervisor")
+	if err := os.MkdirAll(supervisor, 0755); err != nil {
+		return "", fmt.Errorf("failed to create supervisor cgroup: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(supervisor, "cgroup.procs"), []byte("0"), 0644); err != nil {
+		return "", fmt.Errorf("failed to move daemon into supervisor cgroup: %w", err)
+	}
+	if err := enableControllers(base); err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(base, cgroupHooksDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create hooks cgroup: %w", err)
+	}
+	return dir, prepareParent(dir)
+}
+
+// delegated reports whether systemd marked the cgroup at dir as delegated,
+// which it does from version 251 on
+func delegated(dir string) bool {
+	buf := make([]byte, 8)
+	for _, name := range delegateXattrs {
+		if n, err := unix.Getxattr(dir, name, buf); err == nil && string(buf[:n]) == "1" {
+			return true
+		}
+	}
+	return false
+}
+
+// prepareParent enables the hook controllers for children of dir
+func prepareParent(dir string) error {
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("cgroup parent unavailable: %w", err)
+	}
+	return enableControllers(dir)
+}
+
+// enableControllers enables the hook controllers in dir's subtree
+func enableControllers(dir string) error {
+	var enable []string
+	for _, c := range cgroupControllers {
+		enable = append(enable, "+"+c)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cgroup.subtree_control"), []byte(strings.Join(enable, " ")), 0644); err != nil {
+		return fmt.Errorf("failed to enable cgroup controllers in %s: %w", dir, err)
+	}
+	return nil
+}
+
+// ownCgroup returns the daemon's cgroup v2 path from /proc/self/cgroup
+func ownCgroup() (string, error) {
+	f, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if path, ok := strings.CutPrefix(scanner.Text(), "0::"); ok {
+			return path, nil
+		}
+	}
+	return "", errors.New("no cgroup v2 entry in /proc/self/cgroup")
+}
+
+// hookCgroup is the transient group of a single hook run
+type hookCgroup struct {
+	path string
+	dir  *os.File
+}
+
+// create makes a transient group named after eventType and applies limits
+func (m *cgroupManager) create(eventType string, limits *Limits) (*hookCgroup, error) {
+	if err := m.init(); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(m.dir, fmt.Sprintf("%s-%d-%d", eventType, os.Getpid(), m.seq.Add(1)))
+	if err := os.Mkdir(path, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create hook cgroup: %w", err)
+	}
+
+	cg := &hookCgroup{path: path}
+	if err := cg.apply(limits); err != nil {
+		cg.remove()
+		return nil, err
+	}
+
+	dir, err := os.Open(path)
+	if err != nil {
+		cg.remove()
+		return nil, fmt.Errorf("failed to open hook cgroup: %w", err)
+	}
+	cg.dir = dir
+	return cg, nil
+}
+
+// apply writes the limits into the group's interface files
+func (c *hookCgroup) apply(limits *Limits) error {
+	files := map[string]string{}
+	if limits.MemoryMax > 0 {
+		files["memory.max"] = strconv.FormatInt(limits.MemoryMax, 10)
+		// Without this the limit only pushes the hook into swap
+		files["memory.swap.max"] = "0"
+	}
+	if limits.CPUWeight > 0 {
+		files["cpu.weight"] = strconv.Itoa(limits.CPUWeight)
+	}
+	if limits.CPUQuota > 0 {
+		files["cpu.max"] = fmt.Sprintf("%d %d", int64(limits.CPUQuota*cgroupCPUPeriod), cgroupCPUPeriod)
+	}
+	if limits.PidsMax > 0 {
+		files["pids.max"] = strconv.Itoa(limits.PidsMax)
+	}
+
+	for name, value := range files {
+		if err := os.WriteFile(filepath.Join(c.path, name), []byte(value), 0644); err != nil {
+			if name == "memory.swap.max" && errors.Is(err, os.ErrNotExist) {
+				continue // kernel built without swap accounting
+			}
+			return fmt.Errorf("failed to set %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// fd returns the directory descriptor used to start the hook in the group
+func (c *hookCgroup) fd() int {
+	return int(c.dir.Fd())
+}
+
+// eventCount returns a counter from a cgroup events file such as memory.events
+func (c *hookCgroup) eventCount(file, key string) uint64 {
+	data, err := os.ReadFile(filepath.Join(c.path, file))
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, ok := strings.CutPrefix(line, key+" "); ok {
+			n, _ := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+			return n
+		}
+	}
+	return 0
+}
+
+// oomKilled reports whether the OOM killer fired inside the group
+func (c *hookCgroup) oomKilled() bool {
+	return c.eventCount("memory.events", "oom_kill") > 0
+}
+
+// pidsLimited reports whether a fork failed because of pids.max
+func (c *hookCgroup) pidsLimited() bool {
+	return c.eventCount("pids.events", "max") > 0
+}
+
+// remove kills anything left in the group and deletes it
+func (c *hookCgroup) remove() {
+	if c.dir != nil {
+		c.dir.Close()
+	}
+
+	// cgroup.kill exists from Linux 5.14 on
+	os.WriteFile(filepath.Join(c.path, "cgroup.kill"), []byte("1"), 0644)
+
+	for i := 0; i < 20; i++ {
+		err := syscall.Rmdir(c.path)
+		if err == nil || errors.Is(err, syscall.ENOENT) {
+			return
+		}
+		if !errors.Is(err, syscall.EBUSY) {
+			// Not a cgroup filesystem, e.g. a test directory
+			os.RemoveAll(c.path)
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// applyRlimits sets rlimits on a started hook. Open files are always limited
+// this way; memory and pids only when no cgroup enforces them. The limits
+// are set with prlimit once the hook was started, so they are best effort:
+// the hook may already have opened files or forked. As fallbacks they are
+// weaker still: RLIMIT_AS caps address space rather than resident memory,
+// and RLIMIT_NPROC counts every process of the hook's user and does not
+// apply to root at all.
+func applyRlimits(pid int, limits *Limits, inCgroup bool) error {
+	set := func(resource int, value uint64) error {
+		lim := &unix.Rlimit{Cur: value, Max: value}
+		return unix.Prlimit(pid, resource, lim, nil)
+	}
+
+	var errs []error
+	if limits.NoFile > 0 {
+		errs = append(errs, set(unix.RLIMIT_NOFILE, limits.NoFile))
+	}
+	if !inCgroup {
+		if limits.MemoryMax > 0 {
+			errs = append(errs, set(unix.RLIMIT_AS, uint64(limits.MemoryMax)))
+		}
+		if limits.PidsMax > 0 {
+			errs = append(errs, set(unix.RLIMIT_NPROC, uint64(limits.PidsMax)))
+		}
+	}
+	return errors.Join(errs...)
+}
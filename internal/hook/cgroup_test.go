@@ -0,0 +1,144 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hook
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// readCgroupFile returns the trimmed contents of a file in a test group
+func readCgroupFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", name, err)
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func TestCgroupManager_Create(t *testing.T) {
+	// A plain directory stands in for the cgroup parent
+	parent := t.TempDir()
+	manager := newCgroupManager(parent)
+
+	cg, err := manager.create("ToMaster", &Limits{
+		MemoryMax: 64 << 20,
+		CPUWeight: 50,
+		CPUQuota:  0.5,
+		PidsMax:   32,
+	})
+	if err != nil {
+		t.Fatalf("create() unexpected error: %v", err)
+	}
+
+	if got := readCgroupFile(t, parent, "cgroup.subtree_control"); got != "+memory +pids +cpu" {
+		t.Errorf("cgroup.subtree_control = %q, want +memory +pids +cpu", got)
+	}
+
+	want := map[string]string{
+		"memory.max":      "67108864",
+		"memory.swap.max": "0",
+		"cpu.weight":      "50",
+		"cpu.max":         "50000 100000",
+		"pids.max":        "32",
+	}
+	for name, value := range want {
+		if got := readCgroupFile(t, cg.path, name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+
+	if !strings.HasPrefix(filepath.Base(cg.path), "ToMaster-") {
+		t.Errorf("group name = %s, want ToMaster- prefix", filepath.Base(cg.path))
+	}
+
+	cg.remove()
+	if _, err := os.Stat(cg.path); !os.IsNotExist(err) {
+		t.Errorf("group %s still exists after remove()", cg.path)
+	}
+}
+
+func TestCgroupManager_UnavailableParent(t *testing.T) {
+	manager := newCgroupManager(filepath.Join(t.TempDir(), "missing"))
+	if _, err := manager.create("ToMaster", &Limits{PidsMax: 1}); err == nil {
+		t.Error("create() expected error for a missing parent, got nil")
+	}
+}
+
+func TestDelegated(t *testing.T) {
+	dir := t.TempDir()
+	if delegated(dir) {
+		t.Fatal("delegated() = true without xattr, want false")
+	}
+	if err := unix.Setxattr(dir, "user.delegate", []byte("1"), 0); err != nil {
+		t.Skipf("user xattrs unsupported: %v", err)
+	}
+	if !delegated(dir) {
+		t.Error("delegated() = false, want true")
+	}
+}
+
+func TestHookCgroup_Events(t *testing.T) {
+	cg := &hookCgroup{path: t.TempDir()}
+	if cg.oomKilled() || cg.pidsLimited() {
+		t.Fatal("events reported without events files")
+	}
+
+	os.WriteFile(filepath.Join(cg.path, "memory.events"), []byte("low 0\nhigh 0\nmax 3\noom 1\noom_kill 1\n"), 0644)
+	os.WriteFile(filepath.Join(cg.path, "pids.events"), []byte("max 0\n"), 0644)
+
+	if !cg.oomKilled() {
+		t.Error("oomKilled() = false, want true")
+	}
+	if cg.pidsLimited() {
+		t.Error("pidsLimited() = true, want false")
+	}
+}
+
+func TestLimits_NeedsCgroup(t *testing.T) {
+	if (&Limits{NoFile: 64}).needsCgroup() {
+		t.Error("needsCgroup() = true for an rlimit-only limit")
+	}
+	if !(&Limits{MemoryMax: 1 << 20}).needsCgroup() {
+		t.Error("needsCgroup() = false with memory_max set")
+	}
+}
+
+func TestRun_NoFileLimit(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "nofile")
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	// The limit is applied just after start, so give prlimit a moment
+	_, err := NewExecutor(logger).Run(context.Background(), &Invocation{
+		Command:   "sh",
+		Args:      []string{"-c", "sleep 0.2; ulimit -n > " + out},
+		EventType: "TestEvent",
+		Limits:    &Limits{NoFile: 128},
+	})
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	if got := readCgroupFile(t, filepath.Dir(out), "nofile"); got != "128" {
+		t.Errorf("ulimit -n = %s, want 128", got)
+	}
+}
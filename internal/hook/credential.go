@@ -0,0 +1,104 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hook
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// lookupCredential resolves a user and optional group, given as names or
+// numeric IDs, to a process credential. Without a group the user's primary
+// group is used. Supplementary groups are those of the user.
+func lookupCredential(userName, groupName string) (*syscall.Credential, error) {
+	u, err := user.Lookup(userName)
+	if err != nil {
+		if _, numErr := strconv.ParseUint(userName, 10, 32); numErr != nil {
+			return nil, fmt.Errorf("unknown user %q: %w", userName, err)
+		}
+		if u, err = user.LookupId(userName); err != nil {
+			return nil, fmt.Errorf("unknown user %q: %w", userName, err)
+		}
+	}
+
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid uid for user %q: %w", userName, err)
+	}
+
+	gidStr := u.Gid
+	if groupName != "" {
+		g, err := user.LookupGroup(groupName)
+		if err != nil {
+			if _, numErr := strconv.ParseUint(groupName, 10, 32); numErr != nil {
+				return nil, fmt.Errorf("unknown group %q: %w", groupName, err)
+			}
+			if g, err = user.LookupGroupId(groupName); err != nil {
+				return nil, fmt.Errorf("unknown group %q: %w", groupName, err)
+			}
+		}
+		gidStr = g.Gid
+	}
+
+	gid, err := strconv.ParseUint(gidStr, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gid for user %q: %w", userName, err)
+	}
+
+	cred := &syscall.Credential{
+		Uid: uint32(uid),
+		Gid: uint32(gid),
+	}
+
+	groupIDs, err := u.GroupIds()
+	if err == nil {
+		for _, id := range groupIDs {
+			if g, err := strconv.ParseUint(id, 10, 32); err == nil {
+				cred.Groups = append(cred.Groups, uint32(g))
+			}
+		}
+	}
+
+	return cred, nil
+}
+
+// inheritedEnvironment returns the daemon variables named in names. A name
+// ending in "*" matches every variable with that prefix.
+func inheritedEnvironment(names []string) []string {
+	if len(names) == 0 {
+		return nil
+	}
+
+	var env []string
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		for _, name := range names {
+			if prefix, ok := strings.CutSuffix(name, "*"); ok {
+				if strings.HasPrefix(key, prefix) {
+					env = append(env, kv)
+					break
+				}
+			} else if key == name {
+				env = append(env, kv)
+				break
+			}
+		}
+	}
+	return env
+}
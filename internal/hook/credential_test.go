@@ -0,0 +1,207 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hook
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestLookupCredential(t *testing.T) {
+	tests := []struct {
+		name    string
+		user    string
+		group   string
+		wantUID uint32
+		wantGID uint32
+		wantErr bool
+	}{
+		{name: "user name", user: "root", wantUID: 0, wantGID: 0},
+		{name: "numeric user", user: "0", wantUID: 0, wantGID: 0},
+		{name: "numeric group", user: "root", group: "0", wantUID: 0, wantGID: 0},
+		{name: "unknown user", user: "no_such_user_xyz", wantErr: true},
+		{name: "unknown group", user: "root", group: "no_such_group_xyz", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cred, err := lookupCredential(tt.user, tt.group)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("lookupCredential() expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("lookupCredential() unexpected error: %v", err)
+			}
+			if cred.Uid != tt.wantUID || cred.Gid != tt.wantGID {
+				t.Errorf("lookupCredential() = %d:%d, want %d:%d", cred.Uid, cred.Gid, tt.wantUID, tt.wantGID)
+			}
+		})
+	}
+}
+
+func TestInheritedEnvironment(t *testing.T) {
+	t.Setenv("VIPTEST_ONE", "1")
+	t.Setenv("VIPTEST_TWO", "2")
+	t.Setenv("OTHER_VIPTEST", "3")
+
+	env := inheritedEnvironment([]string{"VIPTEST_ONE", "MISSING_VAR"})
+	if len(env) != 1 || env[0] != "VIPTEST_ONE=1" {
+		t.Errorf("inheritedEnvironment() = %v, want [VIPTEST_ONE=1]", env)
+	}
+
+	env = inheritedEnvironment([]string{"VIPTEST_*"})
+	if len(env) != 2 {
+		t.Errorf("inheritedEnvironment() with prefix = %v, want 2 entries", env)
+	}
+
+	if env := inheritedEnvironment(nil); env != nil {
+		t.Errorf("inheritedEnvironment(nil) = %v, want nil", env)
+	}
+}
+
+// worldWritableDir returns a temp directory any user can write to
+func worldWritableDir(t *testing.T) string {
+	t.Helper()
+	// t.TempDir nests inside a 0700 directory other users cannot traverse
+	dir, err := os.MkdirTemp("", "vip-switch-hook-")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	if err := os.Chmod(dir, 0777); err != nil {
+		t.Fatalf("failed to chmod temp dir: %v", err)
+	}
+	return dir
+}
+
+func TestRun_AsUnprivilegedUser(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("requires root to switch users")
+	}
+	nobody, err := user.Lookup("nobody")
+	if err != nil {
+		t.Skip("user nobody does not exist")
+	}
+
+	cred, err := lookupCredential("nobody", "")
+	if err != nil {
+		t.Fatalf("lookupCredential() unexpected error: %v", err)
+	}
+
+	dir := worldWritableDir(t)
+	out := filepath.Join(dir, "id")
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	_, err = NewExecutor(logger).Run(context.Background(), &Invocation{
+		Command:    "sh",
+		Args:       []string{"-c", "id -u > id; grep CapAmb /proc/self/status >> id"},
+		EventType:  "TestEvent",
+		Credential: cred,
+		WorkingDir: dir,
+	})
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if lines[0] != nobody.Uid {
+		t.Errorf("hook ran as uid %s, want %s", lines[0], nobody.Uid)
+	}
+	if len(lines) < 2 || !strings.HasSuffix(lines[1], "0000000000000000") {
+		t.Errorf("hook has ambient capabilities without declaring any: %v", lines)
+	}
+}
+
+func TestRun_AmbientCapabilities(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("requires root to switch users")
+	}
+	if _, err := user.Lookup("nobody"); err != nil {
+		t.Skip("user nobody does not exist")
+	}
+
+	cred, err := lookupCredential("nobody", "")
+	if err != nil {
+		t.Fatalf("lookupCredential() unexpected error: %v", err)
+	}
+
+	dir := worldWritableDir(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	_, err = NewExecutor(logger).Run(context.Background(), &Invocation{
+		Command:     "sh",
+		Args:        []string{"-c", "grep CapAmb /proc/self/status > caps"},
+		EventType:   "TestEvent",
+		Credential:  cred,
+		AmbientCaps: []uintptr{12}, // CAP_NET_ADMIN
+		WorkingDir:  dir,
+	})
+	if err != nil {
+		t.Skipf("ambient capabilities unavailable here: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "caps"))
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	// CAP_NET_ADMIN is bit 12
+	if !strings.HasSuffix(strings.TrimSpace(string(data)), "0000000000001000") {
+		t.Errorf("CapAmb = %q, want only CAP_NET_ADMIN", strings.TrimSpace(string(data)))
+	}
+}
+
+func TestRun_Umask(t *testing.T) {
+	dir := t.TempDir()
+	umask := 0077
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	_, err := NewExecutor(logger).Run(context.Background(), &Invocation{
+		Command:    "sh",
+		Args:       []string{"-c", "touch created"},
+		EventType:  "TestEvent",
+		WorkingDir: dir,
+		Umask:      &umask,
+	})
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "created"))
+	if err != nil {
+		t.Fatalf("failed to stat created file: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("created file mode = %o, want 600", perm)
+	}
+
+	// The daemon's own umask must be restored
+	old := syscall.Umask(0022)
+	syscall.Umask(old)
+	if old == umask {
+		t.Errorf("process umask left at %o after the hook started", old)
+	}
+}
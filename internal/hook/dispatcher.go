@@ -0,0 +1,303 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	metrics "github.com/hashicorp/go-metrics/compat"
+)
+
+var (
+	// ErrSuperseded is returned for events dropped or canceled because a
+	// later event made them obsolete
+	ErrSuperseded = errors.New("hook event superseded")
+	// ErrDispatcherClosed is returned for events submitted after ToDestroy
+	ErrDispatcherClosed = errors.New("hook dispatcher closed")
+)
+
+// RunFunc runs the hook for an event
+type RunFunc func(ctx context.Context, eventType string, tr Transition) error
+
+// dispatchEvent is a submitted hook event
+type dispatchEvent struct {
+	eventType  string
+	tr         Transition
+	ctx        context.Context
+	cancel     context.CancelFunc
+	result     chan error
+	superseded bool
+}
+
+// Dispatcher runs hook events one at a time on a single worker. It
+// guarantees that ToReady runs before any ToMaster or ToSlave and that
+// ToDestroy runs last. Queued role events are coalesced so that only the
+// latest runs, and a ToSlave or ToDestroy cancels an in-flight ToMaster.
+type Dispatcher struct {
+	run    RunFunc
+	logger *slog.Logger
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	queue    []*dispatchEvent
+	current  *dispatchEvent
+	lastRole string // last role event that ran without being superseded
+	ready    bool   // ToReady has run
+	closed   bool   // ToDestroy was submitted or Stop was called
+	done     chan struct{}
+}
+
+// NewDispatcher creates a dispatcher that runs events with run
+func NewDispatcher(run RunFunc, logger *slog.Logger) *Dispatcher {
+	d := &Dispatcher{
+		run:    run,
+		logger: logger,
+		done:   make(chan struct{}),
+	}
+	d.cond = sync.NewCond(&d.mu)
+	return d
+}
+
+// Start starts the worker
+func (d *Dispatcher) Start() {
+	go d.loop()
+}
+
+// Stop drops queued events, cancels the running one and waits for the
+// worker to exit. Use Dispatch with ToDestroy for an orderly shutdown.
+func (d *Dispatcher) Stop() {
+	d.mu.Lock()
+	d.closed = true
+	d.dropQueued(func(*dispatchEvent) bool { return true }, ErrDispatcherClosed)
+	if d.current != nil {
+		d.current.cancel()
+	}
+	d.cond.Broadcast()
+	d.mu.Unlock()
+
+	<-d.done
+}
+
+// Dispatch submits an event and waits for its outcome
+func (d *Dispatcher) Dispatch(ctx context.Context, eventType string, tr Transition) error {
+	select {
+	case err := <-d.Submit(ctx, eventType, tr):
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Submit queues an event and returns a channel that receives its outcome.
+// Events made obsolete by later ones receive ErrSuperseded; a role event
+// matching the role hook that last ran receives nil without running.
+func (d *Dispatcher) Submit(ctx context.Context, eventType string, tr Transition) <-chan error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	result := make(chan error, 1)
+	if d.closed {
+		result <- ErrDispatcherClosed
+		return result
+	}
+
+	ev := &dispatchEvent{eventType: eventType, tr: tr, result: result}
+	ev.ctx, ev.cancel = context.WithCancel(ctx)
+
+	switch eventType {
+	case "ToMaster", "ToSlave":
+		if oldest := d.dropQueued(isRoleEvent, ErrSuperseded); oldest != nil {
+			// The hooks never saw the dropped states
+			ev.tr.PreviousState = oldest.tr.PreviousState
+		}
+		if eventType == "ToSlave" {
+			d.supersedeCurrent("ToMaster")
+		}
+		if d.isRedundant(eventType) {
+			d.logger.Debug("Skipping redundant hook event", "event_type", eventType)
+			ev.cancel()
+			result <- nil
+			return result
+		}
+	case "ToDestroy":
+		d.closed = true
+		d.dropQueued(isRoleEvent, ErrSuperseded)
+		d.supersedeCurrent("ToMaster")
+	}
+
+	d.queue = append(d.queue, ev)
+	d.cond.Broadcast()
+	return result
+}
+
+// Reassert re-runs the role hook that last ran, to repair drift between the
+// node's role and the VIP. The transition it runs has the same previous and
+// new state. It returns false without queuing anything while any hook is
+// queued or running, or when eventType is not the role hook that last ran.
+func (d *Dispatcher) Reassert(ctx context.Context, eventType string, tr Transition) (<-chan error, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed || !d.ready || d.busy() || d.lastRole != eventType {
+		return nil, false
+	}
+
+	ev := &dispatchEvent{eventType: eventType, tr: tr, result: make(chan error, 1)}
+	ev.ctx, ev.cancel = context.WithCancel(ctx)
+	d.queue = append(d.queue, ev)
+	d.cond.Broadcast()
+	return ev.result, true
+}
+
+// Busy reports whether a hook is queued or running
+func (d *Dispatcher) Busy() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.busy()
+}
+
+func (d *Dispatcher) busy() bool {
+	return d.current != nil || len(d.queue) > 0
+}
+
+// isRoleEvent reports whether ev is a ToMaster or ToSlave event
+func isRoleEvent(ev *dispatchEvent) bool {
+	return ev.eventType == "ToMaster" || ev.eventType == "ToSlave"
+}
+
+// isRedundant reports whether a role event would repeat the role hook that
+// is running or last ran
+func (d *Dispatcher) isRedundant(eventType string) bool {
+	if d.current != nil && isRoleEvent(d.current) {
+		return !d.current.superseded && d.current.eventType == eventType
+	}
+	return d.lastRole == eventType
+}
+
+// dropQueued removes the queued events matching match, completing them with
+// err, and returns the oldest one removed
+func (d *Dispatcher) dropQueued(match func(*dispatchEvent) bool, err error) *dispatchEvent {
+	var oldest *dispatchEvent
+	kept := d.queue[:0]
+	for _, ev := range d.queue {
+		if !match(ev) {
+			kept = append(kept, ev)
+			continue
+		}
+		if oldest == nil {
+			oldest = ev
+		}
+		d.logger.Info("Dropping superseded hook event", "event_type", ev.eventType)
+		metrics.IncrCounterWithLabels([]string{"hook", "superseded"}, 1, []metrics.Label{{Name: "event", Value: ev.eventType}})
+		ev.cancel()
+		ev.result <- err
+	}
+	d.queue = kept
+	return oldest
+}
+
+// supersedeCurrent cancels the running event if it is of eventType
+func (d *Dispatcher) supersedeCurrent(eventType string) {
+	if d.current == nil || d.current.eventType != eventType || d.current.superseded {
+		return
+	}
+	d.logger.Warn("Canceling superseded hook", "event_type", eventType)
+	metrics.IncrCounterWithLabels([]string{"hook", "superseded"}, 1, []metrics.Label{{Name: "event", Value: eventType}})
+	d.current.superseded = true
+	d.current.cancel()
+}
+
+// next blocks until an event may run and returns it, or returns nil once
+// the dispatcher is closed and drained
+func (d *Dispatcher) next() *dispatchEvent {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for {
+		if i := d.runnable(); i >= 0 {
+			ev := d.queue[i]
+			d.queue = append(d.queue[:i], d.queue[i+1:]...)
+			d.current = ev
+			return ev
+		}
+		if d.closed && len(d.queue) == 0 {
+			return nil
+		}
+		d.cond.Wait()
+	}
+}
+
+// runnable returns the index of the next event to run, or -1. Until
+// ToReady has run, role events wait behind it.
+func (d *Dispatcher) runnable() int {
+	if d.ready {
+		if len(d.queue) > 0 {
+			return 0
+		}
+		return -1
+	}
+
+	destroy := -1
+	for i, ev := range d.queue {
+		switch ev.eventType {
+		case "ToReady":
+			return i
+		case "ToDestroy":
+			destroy = i
+		}
+	}
+	return destroy
+}
+
+// loop runs events until the dispatcher is closed and drained
+func (d *Dispatcher) loop() {
+	defer close(d.done)
+
+	for {
+		ev := d.next()
+		if ev == nil {
+			return
+		}
+
+		err := d.run(ev.ctx, ev.eventType, ev.tr)
+		d.finish(ev, err)
+	}
+}
+
+// finish records the outcome of a run and delivers it
+func (d *Dispatcher) finish(ev *dispatchEvent, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.current = nil
+	ev.cancel()
+
+	switch {
+	case ev.eventType == "ToReady":
+		d.ready = true
+	case isRoleEvent(ev) && !ev.superseded:
+		d.lastRole = ev.eventType
+	}
+
+	if ev.superseded && err != nil {
+		err = fmt.Errorf("%w: %w", ErrSuperseded, err)
+	}
+	ev.result <- err
+	d.cond.Broadcast()
+}
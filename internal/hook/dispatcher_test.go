@@ -0,0 +1,254 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hook
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingRunner records the events it runs. Events listed in block wait
+// until released or canceled.
+type recordingRunner struct {
+	mu      sync.Mutex
+	ran     []string
+	started chan string
+	block   map[string]chan struct{}
+}
+
+func newRecordingRunner(blocked ...string) *recordingRunner {
+	r := &recordingRunner{
+		started: make(chan string, 16),
+		block:   make(map[string]chan struct{}),
+	}
+	for _, event := range blocked {
+		r.block[event] = make(chan struct{})
+	}
+	return r
+}
+
+func (r *recordingRunner) run(ctx context.Context, eventType string, tr Transition) error {
+	r.mu.Lock()
+	r.ran = append(r.ran, eventType+":"+tr.PreviousState+"->"+tr.NewState)
+	r.mu.Unlock()
+	r.started <- eventType
+
+	if release, ok := r.block[eventType]; ok {
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (r *recordingRunner) events() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Join(r.ran, " ")
+}
+
+// waitStarted waits for the runner to start eventType
+func (r *recordingRunner) waitStarted(t *testing.T, eventType string) {
+	t.Helper()
+	select {
+	case got := <-r.started:
+		if got != eventType {
+			t.Fatalf("started %s, want %s", got, eventType)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("%s did not start", eventType)
+	}
+}
+
+// waitResult waits for an event outcome
+func waitResult(t *testing.T, result <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(2 * time.Second):
+		t.Fatal("no result")
+		return nil
+	}
+}
+
+func newTestDispatcher(t *testing.T, runner *recordingRunner) *Dispatcher {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := NewDispatcher(runner.run, logger)
+	d.Start()
+	t.Cleanup(d.Stop)
+	return d
+}
+
+func trans(from, to string) Transition {
+	return Transition{PreviousState: from, NewState: to}
+}
+
+func TestDispatcher_RoleEventsWaitForReady(t *testing.T) {
+	runner := newRecordingRunner()
+	d := newTestDispatcher(t, runner)
+	ctx := context.Background()
+
+	master := d.Submit(ctx, "ToMaster", trans("Ready", "Master"))
+	select {
+	case <-runner.started:
+		t.Fatal("ToMaster ran before ToReady")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := d.Dispatch(ctx, "ToReady", trans("", "Ready")); err != nil {
+		t.Fatalf("Dispatch(ToReady) unexpected error: %v", err)
+	}
+	if err := waitResult(t, master); err != nil {
+		t.Fatalf("ToMaster unexpected error: %v", err)
+	}
+
+	if got, want := runner.events(), "ToReady:->Ready ToMaster:Ready->Master"; got != want {
+		t.Errorf("ran %q, want %q", got, want)
+	}
+}
+
+func TestDispatcher_CoalescesQueuedRoleEvents(t *testing.T) {
+	runner := newRecordingRunner("ToReady")
+	d := newTestDispatcher(t, runner)
+	ctx := context.Background()
+
+	ready := d.Submit(ctx, "ToReady", trans("", "Ready"))
+	runner.waitStarted(t, "ToReady")
+
+	first := d.Submit(ctx, "ToSlave", trans("Ready", "Slave"))
+	second := d.Submit(ctx, "ToMaster", trans("Slave", "Master"))
+
+	if err := waitResult(t, first); !errors.Is(err, ErrSuperseded) {
+		t.Errorf("first event error = %v, want ErrSuperseded", err)
+	}
+
+	close(runner.block["ToReady"])
+	waitResult(t, ready)
+	if err := waitResult(t, second); err != nil {
+		t.Fatalf("ToMaster unexpected error: %v", err)
+	}
+
+	// The coalesced event starts from the state the hooks last saw
+	if got, want := runner.events(), "ToReady:->Ready ToMaster:Ready->Master"; got != want {
+		t.Errorf("ran %q, want %q", got, want)
+	}
+}
+
+func TestDispatcher_SlaveCancelsInFlightMaster(t *testing.T) {
+	runner := newRecordingRunner("ToMaster")
+	d := newTestDispatcher(t, runner)
+	ctx := context.Background()
+
+	d.Dispatch(ctx, "ToReady", trans("", "Ready"))
+	<-runner.started
+
+	master := d.Submit(ctx, "ToMaster", trans("Ready", "Master"))
+	runner.waitStarted(t, "ToMaster")
+
+	slave := d.Submit(ctx, "ToSlave", trans("Master", "Slave"))
+	if err := waitResult(t, master); !errors.Is(err, ErrSuperseded) {
+		t.Errorf("ToMaster error = %v, want ErrSuperseded", err)
+	}
+	if err := waitResult(t, slave); err != nil {
+		t.Errorf("ToSlave unexpected error: %v", err)
+	}
+}
+
+func TestDispatcher_SkipsRedundantRoleEvent(t *testing.T) {
+	runner := newRecordingRunner()
+	d := newTestDispatcher(t, runner)
+	ctx := context.Background()
+
+	d.Dispatch(ctx, "ToReady", trans("", "Ready"))
+	d.Dispatch(ctx, "ToSlave", trans("Ready", "Slave"))
+	if err := d.Dispatch(ctx, "ToSlave", trans("Slave", "Slave")); err != nil {
+		t.Errorf("redundant ToSlave error = %v, want nil", err)
+	}
+
+	if got, want := runner.events(), "ToReady:->Ready ToSlave:Ready->Slave"; got != want {
+		t.Errorf("ran %q, want %q", got, want)
+	}
+}
+
+func TestDispatcher_DestroyRunsLast(t *testing.T) {
+	runner := newRecordingRunner("ToReady")
+	d := newTestDispatcher(t, runner)
+	ctx := context.Background()
+
+	ready := d.Submit(ctx, "ToReady", trans("", "Ready"))
+	runner.waitStarted(t, "ToReady")
+
+	master := d.Submit(ctx, "ToMaster", trans("Ready", "Master"))
+	destroy := d.Submit(ctx, "ToDestroy", trans("Ready", "Destroy"))
+
+	if err := waitResult(t, master); !errors.Is(err, ErrSuperseded) {
+		t.Errorf("queued ToMaster error = %v, want ErrSuperseded", err)
+	}
+	if err := waitResult(t, d.Submit(ctx, "ToSlave", trans("Ready", "Slave"))); !errors.Is(err, ErrDispatcherClosed) {
+		t.Errorf("ToSlave after ToDestroy error = %v, want ErrDispatcherClosed", err)
+	}
+
+	close(runner.block["ToReady"])
+	waitResult(t, ready)
+	if err := waitResult(t, destroy); err != nil {
+		t.Fatalf("ToDestroy unexpected error: %v", err)
+	}
+
+	if got, want := runner.events(), "ToReady:->Ready ToDestroy:Ready->Destroy"; got != want {
+		t.Errorf("ran %q, want %q", got, want)
+	}
+}
+
+func TestDispatcher_Reassert(t *testing.T) {
+	runner := newRecordingRunner("ToSlave")
+	d := newTestDispatcher(t, runner)
+	ctx := context.Background()
+
+	d.Dispatch(ctx, "ToReady", trans("", "Ready"))
+	<-runner.started
+	slave := d.Submit(ctx, "ToSlave", trans("Ready", "Slave"))
+	runner.waitStarted(t, "ToSlave")
+
+	if _, ok := d.Reassert(ctx, "ToSlave", trans("Slave", "Slave")); ok {
+		t.Error("Reassert() queued while a hook was running")
+	}
+	close(runner.block["ToSlave"])
+	waitResult(t, slave)
+
+	if _, ok := d.Reassert(ctx, "ToMaster", trans("Master", "Master")); ok {
+		t.Error("Reassert() queued a role that did not run last")
+	}
+	result, ok := d.Reassert(ctx, "ToSlave", trans("Slave", "Slave"))
+	if !ok {
+		t.Fatal("Reassert() did not queue the last role hook")
+	}
+	if err := waitResult(t, result); err != nil {
+		t.Fatalf("Reassert() unexpected error: %v", err)
+	}
+
+	if got, want := runner.events(), "ToReady:->Ready ToSlave:Ready->Slave ToSlave:Slave->Slave"; got != want {
+		t.Errorf("ran %q, want %q", got, want)
+	}
+}
@@ -15,137 +15,493 @@
 package hook
 
 import (
-	"bufio"
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
-	"io"
 	"log/slog"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 )
 
 // Executor handles secure command execution
 type Executor struct {
-	logger *slog.Logger
+	logger       *slog.Logger
+	cgroups      *cgroupManager
+	fallbackOnce sync.Once
+
+	mu      sync.Mutex
+	running map[int]struct{} // process groups of running hooks
 }
 
 // NewExecutor creates a new command executor
 func NewExecutor(logger *slog.Logger) *Executor {
 	return &Executor{
-		logger: logger,
+		logger:  logger,
+		cgroups: newCgroupManager(""),
+		running: make(map[int]struct{}),
+	}
+}
+
+// KillAll sends SIGKILL to the process groups of all running hooks, for a
+// daemon that exits without waiting for them
+func (e *Executor) KillAll() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for pgid := range e.running {
+		syscall.Kill(-pgid, syscall.SIGKILL)
 	}
 }
 
+// SetCgroupParent sets the cgroup v2 directory under which limited hooks get
+// their transient groups. By default a subtree of the daemon's cgroup is used.
+func (e *Executor) SetCgroupParent(parent string) {
+	e.cgroups = newCgroupManager(parent)
+}
+
+// Invocation describes a single hook process launch
+type Invocation struct {
+	Command         string
+	Args            []string
+	Env             []string
+	Secrets         []string // names of Env entries holding secret values
+	EventType       string
+	Stdin           []byte         // written to the child's stdin, nil for an empty stdin
+	KillSignal      syscall.Signal // sent to the process group on timeout or cancel, SIGTERM if zero
+	KillGracePeriod time.Duration  // time between KillSignal and SIGKILL
+
+	Credential  *syscall.Credential // run as this user, nil for the daemon's identity
+	AmbientCaps []uintptr           // capabilities kept across the identity change
+	WorkingDir  string
+	Umask       *int    // nil keeps the daemon's umask
+	Limits      *Limits // nil for no resource limits
+	OutputTail  int     // bytes of stdout and stderr kept in the result
+}
+
+// FailureReason classifies why a hook run failed
+type FailureReason string
+
+const (
+	ReasonNone        FailureReason = ""
+	ReasonStartFailed FailureReason = "start_failed"
+	ReasonExitCode    FailureReason = "exit_code"
+	ReasonSignal      FailureReason = "signal"
+	ReasonTimeout     FailureReason = "timeout"
+	ReasonCanceled    FailureReason = "canceled"
+	ReasonOOMKilled   FailureReason = "oom_killed"
+	ReasonPidsLimit   FailureReason = "pids_limit"
+
+	// Reasons of runners other than exec
+	ReasonRequestFailed FailureReason = "request_failed"
+	ReasonHTTPStatus    FailureReason = "http_status"
+	ReasonRunnerError   FailureReason = "runner_error"
+)
+
+var (
+	// ErrOOMKilled is wrapped by errors of hooks killed for exceeding memory_max
+	ErrOOMKilled = errors.New("hook exceeded its memory limit")
+	// ErrPidsLimit is wrapped by errors of hooks that failed after hitting pids_max
+	ErrPidsLimit = errors.New("hook exceeded its process limit")
+)
+
+// Result describes a finished hook process
+type Result struct {
+	ExitCode int           // -1 when the process was not started or did not exit normally
+	Signal   string        // terminating signal, empty when the process exited normally
+	Reason   FailureReason // empty on success
+	Duration time.Duration
+	Stdout   string // last OutputTail bytes of standard output
+	Stderr   string // last OutputTail bytes of standard error
+}
+
+// DefaultKillGracePeriod is used when an invocation sets no grace period
+const DefaultKillGracePeriod = 5 * time.Second
+
+// pipeDrainDelay is how long output pipes may stay open after the process
+// group was killed, e.g. by a grandchild that escaped into its own session
+const pipeDrainDelay = 1 * time.Second
+
 // Execute executes a command securely with streaming output
 func (e *Executor) Execute(ctx context.Context, command string, args []string, env []string, eventType string) error {
-	if command == "" {
-		return errors.New("command cannot be empty")
+	_, err := e.Run(ctx, &Invocation{
+		Command:   command,
+		Args:      args,
+		Env:       env,
+		EventType: eventType,
+	})
+	return err
+}
+
+// Run executes an invocation securely with streaming output. The returned
+// result is never nil, even when the command could not be started.
+//
+// The hook runs in its own process group. When ctx is done the whole group
+// receives KillSignal, followed by SIGKILL once the grace period expires, so
+// helpers forked by the hook cannot outlive it.
+func (e *Executor) Run(ctx context.Context, inv *Invocation) (*Result, error) {
+	result := &Result{ExitCode: -1, Reason: ReasonStartFailed}
+
+	if inv.Command == "" {
+		return result, errors.New("command cannot be empty")
 	}
 
+	eventType := inv.EventType
+
 	// Validate command path
-	cmdPath, err := exec.LookPath(command)
+	cmdPath, err := exec.LookPath(inv.Command)
 	if err != nil {
-		return fmt.Errorf("command not found: %w", err)
+		return result, fmt.Errorf("command not found: %w", err)
 	}
 
-	e.logger.Debug("Executing command", "command", cmdPath, "args", args, "event_type", eventType)
+	e.logger.Debug("Executing command", "command", cmdPath, "args", inv.Args, "event_type", eventType)
+
+	killSignal := inv.KillSignal
+	if killSignal == 0 {
+		killSignal = syscall.SIGTERM
+	}
+	grace := inv.KillGracePeriod
+	if grace <= 0 {
+		grace = DefaultKillGracePeriod
+	}
 
 	// Create command
-	cmd := exec.CommandContext(ctx, cmdPath, args...)
-	cmd.Env = env
+	cmd := exec.CommandContext(ctx, cmdPath, inv.Args...)
+	cmd.Env = inv.Env
+	if inv.Stdin != nil {
+		cmd.Stdin = bytes.NewReader(inv.Stdin)
+	}
 
-	// Setup stdout and stderr pipes
-	stdoutPipe, err := cmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("failed to create stdout pipe: %w", err)
+	// Stream output through writers so that exec owns the copying
+	// goroutines and WaitDelay can bound them
+	stdout := newOutputWriter(e.logger, eventType, "stdout")
+	stderr := newOutputWriter(e.logger, eventType, "stderr")
+	stdout.tailSize = inv.OutputTail
+	stderr.tailSize = inv.OutputTail
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	cmd.Dir = inv.WorkingDir
+
+	exited := make(chan struct{})
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid:     true,
+		Credential:  inv.Credential,
+		AmbientCaps: inv.AmbientCaps,
+	}
+	cmd.Cancel = func() error {
+		return e.terminateGroup(cmd.Process.Pid, killSignal, grace, exited, eventType)
 	}
+	cmd.WaitDelay = grace + pipeDrainDelay
 
-	stderrPipe, err := cmd.StderrPipe()
+	cg, err := e.prepareCgroup(cmd, inv)
 	if err != nil {
-		return fmt.Errorf("failed to create stderr pipe: %w", err)
+		close(exited)
+		return result, fmt.Errorf("failed to confine hook: %w", err)
+	}
+	if cg != nil {
+		defer cg.remove()
 	}
 
 	// Start command
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start command: %w", err)
+	start := time.Now()
+	if err := startWithUmask(cmd, inv.Umask); err != nil {
+		close(exited)
+		return result, fmt.Errorf("failed to start command: %w", err)
 	}
 
-	// Stream output in goroutines
-	var wg sync.WaitGroup
-	wg.Add(2)
+	e.mu.Lock()
+	e.running[cmd.Process.Pid] = struct{}{}
+	e.mu.Unlock()
 
-	go e.streamOutput(&wg, stdoutPipe, "stdout", eventType)
-	go e.streamOutput(&wg, stderrPipe, "stderr", eventType)
+	if inv.Limits != nil {
+		if err := applyRlimits(cmd.Process.Pid, inv.Limits, cg != nil); err != nil {
+			e.logger.Warn("Failed to apply hook rlimits", "event_type", eventType, "error", err)
+		}
+	}
 
 	// Wait for command to complete
 	err = cmd.Wait()
-	wg.Wait()
+	close(exited)
+	e.mu.Lock()
+	delete(e.running, cmd.Process.Pid)
+	e.mu.Unlock()
+	stdout.Flush()
+	stderr.Flush()
+
+	result.Duration = time.Since(start)
+	result.Stdout = string(stdout.tail)
+	result.Stderr = string(stderr.tail)
+	result.Reason = ReasonNone
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+		if status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			result.Signal = status.Signal().String()
+		}
+	}
+
+	if errors.Is(err, exec.ErrWaitDelay) {
+		// The hook itself succeeded but left processes holding its output open
+		e.logger.Warn("Hook output was still open after exit, closed it",
+			"event_type", eventType,
+			"wait_delay", cmd.WaitDelay,
+		)
+		err = nil
+	}
+
+	if err != nil && cg != nil {
+		// Limit violations take precedence, a timeout may be their consequence
+		if cg.oomKilled() {
+			result.Reason = ReasonOOMKilled
+			e.logger.Error("Hook was killed by the OOM killer",
+				"event_type", eventType,
+				"memory_max", inv.Limits.MemoryMax,
+			)
+			return result, fmt.Errorf("%w (memory_max %d bytes): %w", ErrOOMKilled, inv.Limits.MemoryMax, err)
+		}
+		if cg.pidsLimited() {
+			result.Reason = ReasonPidsLimit
+			e.logger.Error("Hook hit its process limit",
+				"event_type", eventType,
+				"pids_max", inv.Limits.PidsMax,
+			)
+			return result, fmt.Errorf("%w (pids_max %d): %w", ErrPidsLimit, inv.Limits.PidsMax, err)
+		}
+	}
+
+	if err != nil && ctx.Err() != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			result.Reason = ReasonTimeout
+			return result, fmt.Errorf("command timed out: %w", err)
+		}
+		result.Reason = ReasonCanceled
+		return result, fmt.Errorf("command canceled: %w", err)
+	}
 
 	// Check exit status
 	if exitErr, ok := err.(*exec.ExitError); ok {
 		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+			result.Reason = ReasonExitCode
+			if status.Signaled() {
+				result.Reason = ReasonSignal
+			}
 			e.logger.Error("Command exited with non-zero status",
 				"event_type", eventType,
 				"exit_code", status.ExitStatus(),
 				"signal", status.Signal(),
 			)
-			return fmt.Errorf("command exited with status %d: %w", status.ExitStatus(), exitErr)
+			return result, fmt.Errorf("command exited with status %d: %w", status.ExitStatus(), exitErr)
 		}
 	}
 
 	if err != nil {
-		if errors.Is(err, context.DeadlineExceeded) {
-			return fmt.Errorf("command timed out: %w", err)
+		result.Reason = ReasonExitCode
+		return result, fmt.Errorf("command failed: %w", err)
+	}
+
+	return result, nil
+}
+
+// prepareCgroup creates a transient cgroup for a limited hook and arranges
+// for the process to start inside it. It returns nil when the hook has no
+// cgroup limits or cgroup v2 is not mounted, in which case rlimits are used.
+// Any other problem, such as a cgroup that was not delegated, fails the hook
+// rather than running it unconfined.
+func (e *Executor) prepareCgroup(cmd *exec.Cmd, inv *Invocation) (*hookCgroup, error) {
+	if inv.Limits == nil || !inv.Limits.needsCgroup() {
+		return nil, nil
+	}
+
+	cg, err := e.cgroups.create(inv.EventType, inv.Limits)
+	if errors.Is(err, errNoCgroup2) {
+		e.fallbackOnce.Do(func() {
+			e.logger.Warn("cgroup v2 unavailable for hook limits, falling back to rlimits set after the hook started", "error", err)
+		})
+		if inv.Limits.CPUWeight > 0 || inv.Limits.CPUQuota > 0 {
+			e.logger.Warn("CPU limits cannot be enforced without cgroup v2", "event_type", inv.EventType)
 		}
-		if errors.Is(err, context.Canceled) {
-			return fmt.Errorf("command canceled: %w", err)
+		if inv.Limits.PidsMax > 0 {
+			e.logger.Warn("pids_max without cgroup v2 is RLIMIT_NPROC, which counts every process of the hook's user and does not bind root", "event_type", inv.EventType)
 		}
-		return fmt.Errorf("command failed: %w", err)
+		return nil, nil
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = cg.fd()
+	return cg, nil
+}
+
+// umaskMu serializes hook starts that change the process umask
+var umaskMu sync.Mutex
+
+// startWithUmask starts cmd with the given umask. The umask is process wide,
+// so it is only swapped for the duration of the fork; files created by other
+// goroutines in that window get the (usually stricter) hook umask.
+func startWithUmask(cmd *exec.Cmd, umask *int) error {
+	if umask == nil {
+		return cmd.Start()
+	}
+
+	umaskMu.Lock()
+	defer umaskMu.Unlock()
+
+	old := syscall.Umask(*umask)
+	defer syscall.Umask(old)
+
+	return cmd.Start()
+}
+
+// terminateGroup sends sig to the process group led by pid and escalates to
+// SIGKILL after grace unless the hook exits first
+func (e *Executor) terminateGroup(pid int, sig syscall.Signal, grace time.Duration, exited <-chan struct{}, eventType string) error {
+	e.logger.Warn("Terminating hook process group",
+		"event_type", eventType,
+		"pgid", pid,
+		"signal", sig.String(),
+		"grace_period", grace,
+	)
+
+	if err := syscall.Kill(-pid, sig); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return fmt.Errorf("failed to signal process group: %w", err)
+	}
+	if sig == syscall.SIGKILL {
+		return nil
+	}
+
+	go func() {
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+
+		select {
+		case <-exited:
+			// The leader is reaped, so the pgid may already belong to
+			// someone else; leftovers are cut off by WaitDelay instead
+		case <-timer.C:
+			e.logger.Warn("Hook ignored termination signal, killing process group",
+				"event_type", eventType,
+				"pgid", pid,
+			)
+			if err := syscall.Kill(-pid, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+				e.logger.Error("Failed to kill process group", "event_type", eventType, "pgid", pid, "error", err)
+			}
+		}
+	}()
 
 	return nil
 }
 
-// streamOutput streams command output to logs
-func (e *Executor) streamOutput(wg *sync.WaitGroup, pipe io.Reader, streamName, eventType string) {
-	defer wg.Done()
+// maxOutputLine caps a single logged output line, like bufio.Scanner's default
+const maxOutputLine = 64 * 1024
+
+// outputWriter logs hook output line by line and keeps the last tailSize
+// bytes written
+type outputWriter struct {
+	logger     *slog.Logger
+	eventType  string
+	streamName string
+	buf        []byte
+	tailSize   int
+	tail       []byte
+}
 
-	scanner := bufio.NewScanner(pipe)
-	for scanner.Scan() {
-		line := scanner.Text()
-		e.logger.Info("Hook output",
-			"event_type", eventType,
-			"stream", streamName,
-			"line", line,
-		)
+// newOutputWriter creates a writer that logs each line written to it
+func newOutputWriter(logger *slog.Logger, eventType, streamName string) *outputWriter {
+	return &outputWriter{
+		logger:     logger,
+		eventType:  eventType,
+		streamName: streamName,
 	}
+}
 
-	if err := scanner.Err(); err != nil {
-		e.logger.Error("Error reading output",
-			"event_type", eventType,
-			"stream", streamName,
-			"error", err,
-		)
+// Write logs every complete line in p and buffers the remainder
+func (w *outputWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	w.keepTail(p)
+
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.logLine(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+
+	if len(w.buf) >= maxOutputLine {
+		w.logLine(w.buf)
+		w.buf = w.buf[:0]
+	}
+
+	return len(p), nil
+}
+
+// keepTail appends p to the tail, dropping the oldest bytes beyond tailSize
+func (w *outputWriter) keepTail(p []byte) {
+	if w.tailSize <= 0 {
+		return
 	}
+	if len(p) >= w.tailSize {
+		w.tail = append(w.tail[:0], p[len(p)-w.tailSize:]...)
+		return
+	}
+	if excess := len(w.tail) + len(p) - w.tailSize; excess > 0 {
+		w.tail = append(w.tail[:0], w.tail[excess:]...)
+	}
+	w.tail = append(w.tail, p...)
+}
+
+// Flush logs any buffered partial line
+func (w *outputWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.logLine(w.buf)
+		w.buf = nil
+	}
+}
+
+// logLine logs a single line of output
+func (w *outputWriter) logLine(line []byte) {
+	w.logger.Info("Hook output",
+		"event_type", w.eventType,
+		"stream", w.streamName,
+		"line", string(bytes.TrimSuffix(line, []byte("\r"))),
+	)
+}
+
+// DefaultAllowedEnvPrefixes are the environment prefixes kept by SanitizeEnvironment
+var DefaultAllowedEnvPrefixes = []string{
+	"EVENT_",
+	"NODE_",
+	"VIP_",
+	"INTERFACE",
+	"PATH",
+	"HOME",
+	"USER",
+}
+
+// DefaultSafeDirs are the directories accepted by ValidateCommandPath
+var DefaultSafeDirs = []string{
+	"/usr/local/bin",
+	"/usr/bin",
+	"/bin",
+	"/usr/sbin",
+	"/sbin",
 }
 
 // SanitizeEnvironment sanitizes environment variables to prevent injection
 func SanitizeEnvironment(env map[string]string) []string {
-	sanitized := make([]string, 0, len(env))
+	return SanitizeEnvironmentWithPrefixes(env, DefaultAllowedEnvPrefixes)
+}
 
-	allowedPrefixes := []string{
-		"EVENT_",
-		"NODE_",
-		"VIP_",
-		"INTERFACE",
-		"PATH",
-		"HOME",
-		"USER",
-	}
+// SanitizeEnvironmentWithPrefixes keeps only variables whose names start
+// with one of allowedPrefixes, compared case-insensitively
+func SanitizeEnvironmentWithPrefixes(env map[string]string, allowedPrefixes []string) []string {
+	sanitized := make([]string, 0, len(env))
 
 	for key, value := range env {
 		if isEnvKeyAllowed(key, allowedPrefixes) {
@@ -161,7 +517,7 @@ func isEnvKeyAllowed(key string, allowedPrefixes []string) bool {
 	upperKey := strings.ToUpper(key)
 
 	for _, prefix := range allowedPrefixes {
-		if strings.HasPrefix(upperKey, prefix) {
+		if strings.HasPrefix(upperKey, strings.ToUpper(prefix)) {
 			return true
 		}
 	}
@@ -171,6 +527,11 @@ func isEnvKeyAllowed(key string, allowedPrefixes []string) bool {
 
 // ValidateCommandPath validates that a command path is safe
 func ValidateCommandPath(command string) error {
+	return ValidateCommandPathIn(command, DefaultSafeDirs)
+}
+
+// ValidateCommandPathIn validates that a command path lies within one of safeDirs
+func ValidateCommandPathIn(command string, safeDirs []string) error {
 	if strings.Contains(command, " ") || strings.Contains(command, "\t") {
 		return errors.New("command path cannot contain spaces or tabs")
 	}
@@ -181,16 +542,9 @@ func ValidateCommandPath(command string) error {
 	}
 
 	// Check if path is in safe directories
-	safeDirs := []string{
-		"/usr/local/bin",
-		"/usr/bin",
-		"/bin",
-		"/usr/sbin",
-		"/sbin",
-	}
-
 	for _, dir := range safeDirs {
-		if strings.HasPrefix(absPath, dir) {
+		dir = filepath.Clean(dir)
+		if absPath == dir || strings.HasPrefix(absPath, dir+string(filepath.Separator)) {
 			return nil
 		}
 	}
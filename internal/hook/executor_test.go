@@ -17,9 +17,13 @@ package hook
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 	"testing"
@@ -454,3 +458,238 @@ func TestSignalHandling(t *testing.T) {
 		}
 	}
 }
+
+func TestRun_Stdin(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	executor := NewExecutor(logger)
+
+	out := filepath.Join(t.TempDir(), "stdin.json")
+	_, err := executor.Run(context.Background(), &Invocation{
+		Command:   "sh",
+		Args:      []string{"-c", "cat > " + out},
+		EventType: "TestEvent",
+		Stdin:     []byte(`{"event":"TestEvent"}` + "\n"),
+	})
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read captured stdin: %v", err)
+	}
+	if string(data) != `{"event":"TestEvent"}`+"\n" {
+		t.Errorf("captured stdin = %q", data)
+	}
+}
+
+func TestRun_StdinIgnored(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	executor := NewExecutor(logger)
+
+	_, err := executor.Run(context.Background(), &Invocation{
+		Command:   "true",
+		EventType: "TestEvent",
+		Stdin:     []byte(`{"event":"TestEvent"}`),
+	})
+	if err != nil {
+		t.Errorf("Run() unexpected error when stdin is not read: %v", err)
+	}
+}
+
+// processAlive reports whether pid exists and is not a zombie
+func processAlive(pid int) bool {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return false
+	}
+	// The state follows the parenthesized command name
+	fields := strings.Fields(string(data[strings.LastIndexByte(string(data), ')')+1:]))
+	return len(fields) > 0 && fields[0] != "Z"
+}
+
+// readPID waits for a hook script to write a pid to path
+func readPID(t *testing.T, path string) int {
+	t.Helper()
+	for i := 0; i < 50; i++ {
+		data, err := os.ReadFile(path)
+		if err == nil && len(strings.TrimSpace(string(data))) > 0 {
+			pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+			if err != nil {
+				t.Fatalf("invalid pid %q: %v", data, err)
+			}
+			return pid
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("hook did not write %s", path)
+	return 0
+}
+
+func TestRun_TimeoutKillsProcessGroup(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	executor := NewExecutor(logger)
+
+	pidFile := filepath.Join(t.TempDir(), "sleeper.pid")
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := executor.Run(ctx, &Invocation{
+		Command:         "sh",
+		Args:            []string{"-c", "sleep 30 & echo $! > " + pidFile + "; wait"},
+		EventType:       "TestEvent",
+		KillGracePeriod: 500 * time.Millisecond,
+	})
+	elapsed := time.Since(start)
+
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Run() error = %v, want 'timed out'", err)
+	}
+	if elapsed > 2*time.Second {
+		t.Errorf("Run() took %v, want it to return shortly after the timeout", elapsed)
+	}
+
+	pid := readPID(t, pidFile)
+	for i := 0; i < 50 && processAlive(pid); i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if processAlive(pid) {
+		syscall.Kill(pid, syscall.SIGKILL)
+		t.Errorf("forked sleeper %d survived the hook timeout", pid)
+	}
+}
+
+func TestExecutor_KillAll(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	executor := NewExecutor(logger)
+
+	pidFile := filepath.Join(t.TempDir(), "sleeper.pid")
+	done := make(chan *Result)
+	go func() {
+		result, _ := executor.Run(context.Background(), &Invocation{
+			Command:   "sh",
+			Args:      []string{"-c", "trap '' TERM; sleep 30 & echo $! > " + pidFile + "; wait"},
+			EventType: "TestEvent",
+		})
+		done <- result
+	}()
+
+	pid := readPID(t, pidFile)
+	executor.KillAll()
+
+	select {
+	case result := <-done:
+		if result.Signal != "killed" {
+			t.Errorf("Run() signal = %q, want killed", result.Signal)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run() did not return after KillAll()")
+	}
+	for i := 0; i < 50 && processAlive(pid); i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if processAlive(pid) {
+		syscall.Kill(pid, syscall.SIGKILL)
+		t.Errorf("forked sleeper %d survived KillAll()", pid)
+	}
+}
+
+func TestRun_EscalatesToSIGKILL(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	executor := NewExecutor(logger)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	result, err := executor.Run(ctx, &Invocation{
+		Command:         "sh",
+		Args:            []string{"-c", "trap '' TERM; sleep 30 & wait; wait"},
+		EventType:       "TestEvent",
+		KillSignal:      syscall.SIGTERM,
+		KillGracePeriod: 300 * time.Millisecond,
+	})
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Fatal("Run() expected error, got nil")
+	}
+	if result.Signal != syscall.SIGKILL.String() {
+		t.Errorf("Result.Signal = %q, want %q", result.Signal, syscall.SIGKILL.String())
+	}
+	if elapsed > 3*time.Second {
+		t.Errorf("Run() took %v, want SIGKILL after the grace period", elapsed)
+	}
+}
+
+func TestRun_OrphanHoldingOutputDoesNotBlock(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	executor := NewExecutor(logger)
+
+	pidFile := filepath.Join(t.TempDir(), "orphan.pid")
+
+	start := time.Now()
+	result, err := executor.Run(context.Background(), &Invocation{
+		Command:         "sh",
+		Args:            []string{"-c", "sleep 30 & echo $! > " + pidFile + "; exit 0"},
+		EventType:       "TestEvent",
+		KillGracePeriod: 100 * time.Millisecond,
+	})
+	elapsed := time.Since(start)
+	syscall.Kill(readPID(t, pidFile), syscall.SIGKILL)
+
+	if err != nil {
+		t.Errorf("Run() unexpected error: %v", err)
+	}
+	if result.ExitCode != 0 {
+		t.Errorf("Result.ExitCode = %d, want 0", result.ExitCode)
+	}
+	if elapsed > 3*time.Second {
+		t.Errorf("Run() took %v, want the wait delay to release the output pipes", elapsed)
+	}
+}
+
+func TestOutputWriter(t *testing.T) {
+	var buf strings.Builder
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	w := newOutputWriter(logger, "TestEvent", "stdout")
+	w.Write([]byte("first\nsec"))
+	w.Write([]byte("ond\r\nthird"))
+	w.Flush()
+
+	out := buf.String()
+	for _, line := range []string{"line=first", "line=second", "line=third"} {
+		if !strings.Contains(out, line) {
+			t.Errorf("output log missing %q:\n%s", line, out)
+		}
+	}
+	if strings.Count(out, "Hook output") != 3 {
+		t.Errorf("expected 3 logged lines, got:\n%s", out)
+	}
+}
+
+func TestOutputWriter_Tail(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	w := newOutputWriter(logger, "TestEvent", "stdout")
+	w.tailSize = 8
+	w.Write([]byte("abc"))
+	w.Write([]byte("defgh"))
+	w.Write([]byte("ij"))
+	if got := string(w.tail); got != "cdefghij" {
+		t.Errorf("tail = %q, want %q", got, "cdefghij")
+	}
+
+	w.Write([]byte("0123456789"))
+	if got := string(w.tail); got != "23456789" {
+		t.Errorf("tail = %q, want %q", got, "23456789")
+	}
+
+	untracked := newOutputWriter(logger, "TestEvent", "stdout")
+	untracked.Write([]byte("data"))
+	if untracked.tail != nil {
+		t.Errorf("tail = %q without tailSize, want nil", untracked.tail)
+	}
+}
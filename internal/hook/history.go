@@ -0,0 +1,248 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hook
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HistoryRecord describes one hook run, including all of its attempts.
+// Environment values are never recorded, only their names.
+type HistoryRecord struct {
+	Event         string          `json:"event"`
+	PreviousState string          `json:"previous_state,omitempty"`
+	NewState      string          `json:"new_state,omitempty"`
+	Command       string          `json:"command"`
+	Args          []string        `json:"args,omitempty"`
+	EnvKeys       []string        `json:"env_keys,omitempty"`
+	Strategy      string          `json:"strategy"`
+	StartedAt     time.Time       `json:"started_at"`
+	FinishedAt    time.Time       `json:"finished_at"`
+	Success       bool            `json:"success"`
+	Error         string          `json:"error,omitempty"`
+	Attempts      []AttemptRecord `json:"attempts"`
+}
+
+// AttemptRecord describes a single attempt of a hook run
+type AttemptRecord struct {
+	Runner     string        `json:"runner,omitempty"`
+	Attempt    int           `json:"attempt"`
+	StartedAt  time.Time     `json:"started_at"`
+	DurationMS int64         `json:"duration_ms"`
+	ExitCode   int           `json:"exit_code"`
+	Signal     string        `json:"signal,omitempty"`
+	Reason     FailureReason `json:"reason,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Stdout     string        `json:"stdout,omitempty"`
+	Stderr     string        `json:"stderr,omitempty"`
+}
+
+// Duration returns how long the whole run took
+func (r *HistoryRecord) Duration() time.Duration {
+	return r.FinishedAt.Sub(r.StartedAt)
+}
+
+// HistoryFilter selects records from the history
+type HistoryFilter struct {
+	Event string    // empty for all events
+	Since time.Time // zero for no lower bound
+	Limit int       // most recent records only, 0 for all
+}
+
+// matches reports whether rec passes the filter's event and time bounds
+func (f *HistoryFilter) matches(rec *HistoryRecord) bool {
+	if f.Event != "" && !strings.EqualFold(rec.Event, f.Event) {
+		return false
+	}
+	return f.Since.IsZero() || !rec.StartedAt.Before(f.Since)
+}
+
+// History is an append-only journal of hook runs stored as JSON lines.
+// It is compacted once it grows past its retention limits.
+type History struct {
+	mu         sync.Mutex
+	path       string
+	maxRecords int
+	maxAge     time.Duration
+	count      int
+}
+
+// OpenHistory opens the journal at path, creating its directory, and drops
+// records beyond maxRecords or older than maxAge
+func OpenHistory(path string, maxRecords int, maxAge time.Duration) (*History, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	h := &History{
+		path:       path,
+		maxRecords: maxRecords,
+		maxAge:     maxAge,
+	}
+	if err := h.compact(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Path returns the journal file
+func (h *History) Path() string {
+	return h.path
+}
+
+// Append writes rec to the journal
+func (h *History) Append(rec *HistoryRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode history record: %w", err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Reopening for every record keeps appends correct across compactions,
+	// and hooks run far too rarely for this to matter
+	f, err := os.OpenFile(h.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	_, err = f.Write(append(data, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+
+	h.count++
+	// Allow some slack so that a full journal is not rewritten on every run
+	if h.maxRecords > 0 && h.count > h.maxRecords+h.maxRecords/4 {
+		return h.compactLocked()
+	}
+	return nil
+}
+
+// Query returns the records matching filter, oldest first
+func (h *History) Query(filter HistoryFilter) ([]HistoryRecord, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return ReadHistory(h.path, filter)
+}
+
+// compact rewrites the journal without records outside the retention limits
+func (h *History) compact() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.compactLocked()
+}
+
+// compactLocked is compact with h.mu held
+func (h *History) compactLocked() error {
+	records, err := ReadHistory(h.path, HistoryFilter{})
+	if err != nil {
+		return err
+	}
+
+	kept := records
+	if h.maxAge > 0 {
+		cutoff := time.Now().Add(-h.maxAge)
+		kept = make([]HistoryRecord, 0, len(records))
+		for _, rec := range records {
+			if !rec.StartedAt.Before(cutoff) {
+				kept = append(kept, rec)
+			}
+		}
+	}
+	if h.maxRecords > 0 && len(kept) > h.maxRecords {
+		kept = kept[len(kept)-h.maxRecords:]
+	}
+
+	h.count = len(kept)
+	if len(kept) == len(records) {
+		return nil
+	}
+
+	tmp := h.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to compact history: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for i := range kept {
+		if err = enc.Encode(&kept[i]); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, h.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to compact history: %w", err)
+	}
+	return nil
+}
+
+// ReadHistory reads the journal at path without opening it for writing.
+// Lines that cannot be decoded, such as one torn by a crash, are skipped.
+func ReadHistory(path string, filter HistoryFilter) ([]HistoryRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+
+	var records []HistoryRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var rec HistoryRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		if filter.matches(&rec) {
+			records = append(records, rec)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	if filter.Limit > 0 && len(records) > filter.Limit {
+		records = records[len(records)-filter.Limit:]
+	}
+	return records, nil
+}
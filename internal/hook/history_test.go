@@ -0,0 +1,120 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hook
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistory_AppendAndQuery(t *testing.T) {
+	history, err := OpenHistory(filepath.Join(t.TempDir(), "history", "hooks.jsonl"), 100, 0)
+	if err != nil {
+		t.Fatalf("OpenHistory() unexpected error: %v", err)
+	}
+
+	start := time.Now()
+	for i, event := range []string{"ToReady", "ToMaster", "ToSlave", "ToMaster"} {
+		rec := &HistoryRecord{Event: event, StartedAt: start.Add(time.Duration(i) * time.Minute)}
+		if err := history.Append(rec); err != nil {
+			t.Fatalf("Append() unexpected error: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter HistoryFilter
+		want   int
+	}{
+		{name: "all", filter: HistoryFilter{}, want: 4},
+		{name: "event", filter: HistoryFilter{Event: "ToMaster"}, want: 2},
+		{name: "event case-insensitive", filter: HistoryFilter{Event: "tomaster"}, want: 2},
+		{name: "since", filter: HistoryFilter{Since: start.Add(90 * time.Second)}, want: 2},
+		{name: "limit", filter: HistoryFilter{Limit: 1}, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := history.Query(tt.filter)
+			if err != nil {
+				t.Fatalf("Query() unexpected error: %v", err)
+			}
+			if len(records) != tt.want {
+				t.Errorf("Query() returned %d records, want %d", len(records), tt.want)
+			}
+		})
+	}
+
+	records, _ := history.Query(HistoryFilter{Limit: 1})
+	if len(records) == 1 && records[0].Event != "ToMaster" {
+		t.Errorf("Query() with limit = %s, want the most recent record", records[0].Event)
+	}
+}
+
+func TestHistory_Retention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hooks.jsonl")
+	history, err := OpenHistory(path, 4, 0)
+	if err != nil {
+		t.Fatalf("OpenHistory() unexpected error: %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		if err := history.Append(&HistoryRecord{Event: "ToMaster", StartedAt: time.Now()}); err != nil {
+			t.Fatalf("Append() unexpected error: %v", err)
+		}
+	}
+	records, _ := ReadHistory(path, HistoryFilter{})
+	if len(records) > 5 {
+		t.Errorf("history holds %d records, want at most 5 with max_records 4", len(records))
+	}
+
+	// Reopening applies both limits
+	old := &HistoryRecord{Event: "ToSlave", StartedAt: time.Now().Add(-48 * time.Hour)}
+	history.Append(old)
+	if _, err := OpenHistory(path, 4, 24*time.Hour); err != nil {
+		t.Fatalf("OpenHistory() unexpected error: %v", err)
+	}
+	records, _ = ReadHistory(path, HistoryFilter{})
+	if len(records) != 4 {
+		t.Errorf("history holds %d records after reopening, want 4", len(records))
+	}
+	for _, rec := range records {
+		if rec.Event == "ToSlave" {
+			t.Error("history kept a record older than max_age")
+		}
+	}
+}
+
+func TestReadHistory_SkipsTornLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hooks.jsonl")
+	data := `{"event":"ToMaster","started_at":"2026-01-01T00:00:00Z"}` + "\n" + `{"event":"ToSl`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("failed to write history: %v", err)
+	}
+
+	records, err := ReadHistory(path, HistoryFilter{})
+	if err != nil {
+		t.Fatalf("ReadHistory() unexpected error: %v", err)
+	}
+	if len(records) != 1 {
+		t.Errorf("ReadHistory() returned %d records, want 1", len(records))
+	}
+
+	if records, err := ReadHistory(filepath.Join(t.TempDir(), "missing"), HistoryFilter{}); err != nil || records != nil {
+		t.Errorf("ReadHistory() of a missing file = %v, %v, want nil, nil", records, err)
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hook
+
+import (
+	"encoding/json"
+	"time"
+
+	"vip-switch-go/internal/config"
+)
+
+// PayloadVersion is the schema version of the JSON document written to hook stdin.
+// It is bumped only for incompatible changes; new fields may be added at any time.
+const PayloadVersion = 1
+
+// Transition describes the state change that triggered a hook
+type Transition struct {
+	PreviousState string
+	NewState      string
+}
+
+// ClusterInfo provides the Raft view included in hook payloads
+type ClusterInfo interface {
+	Leader() string
+	LeaderID() string
+	Term() uint64
+	AppliedIndex() uint64
+	Members() []config.ClusterNode
+}
+
+// Payload is the JSON document written to the stdin of every hook
+type Payload struct {
+	Version       int       `json:"version"`
+	Event         string    `json:"event"`
+	PreviousState string    `json:"previous_state"`
+	NewState      string    `json:"new_state"`
+	NodeID        string    `json:"node_id"`
+	LeaderID      string    `json:"leader_id"`
+	LeaderAddr    string    `json:"leader_addr"`
+	Term          uint64    `json:"term"`
+	Fencing       Fencing   `json:"fencing"`
+	Members       []Member  `json:"members"`
+	VIP           VIP       `json:"vip"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// Fencing carries values hooks can use to reject stale actions. Token is the
+// Raft term, which only ever increases across leadership changes.
+type Fencing struct {
+	Token        uint64 `json:"token"`
+	AppliedIndex uint64 `json:"applied_index"`
+}
+
+// Member describes a single cluster member
+type Member struct {
+	ID      string `json:"id"`
+	Address string `json:"address"`
+	Leader  bool   `json:"leader"`
+}
+
+// VIP describes the virtual IP managed by the cluster
+type VIP struct {
+	Address   string `json:"address"`
+	Interface string `json:"interface"`
+}
+
+// buildPayload assembles the payload for an event. When no cluster view is
+// available the members are taken from the static configuration.
+func buildPayload(cfg *config.Config, cluster ClusterInfo, eventType string, tr Transition) Payload {
+	payload := Payload{
+		Version:       PayloadVersion,
+		Event:         eventType,
+		PreviousState: tr.PreviousState,
+		NewState:      tr.NewState,
+		NodeID:        cfg.Node.ID,
+		VIP: VIP{
+			Address:   cfg.VIP.Address,
+			Interface: cfg.VIP.Interface,
+		},
+		Timestamp: time.Now().UTC(),
+	}
+
+	nodes := cfg.Cluster.Nodes
+	if cluster != nil {
+		payload.LeaderID = cluster.LeaderID()
+		payload.LeaderAddr = cluster.Leader()
+		payload.Term = cluster.Term()
+		payload.Fencing = Fencing{
+			Token:        payload.Term,
+			AppliedIndex: cluster.AppliedIndex(),
+		}
+		if members := cluster.Members(); len(members) > 0 {
+			nodes = members
+		}
+	}
+
+	payload.Members = make([]Member, 0, len(nodes))
+	for _, node := range nodes {
+		payload.Members = append(payload.Members, Member{
+			ID:      node.ID,
+			Address: node.Addr,
+			Leader:  payload.LeaderID != "" && node.ID == payload.LeaderID,
+		})
+	}
+
+	return payload
+}
+
+// Marshal encodes the payload as a single JSON document terminated by a newline
+func (p Payload) Marshal() ([]byte, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
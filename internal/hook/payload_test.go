@@ -0,0 +1,136 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hook
+
+import (
+	"encoding/json"
+	"testing"
+
+	"vip-switch-go/internal/config"
+)
+
+type fakeCluster struct {
+	leader   string
+	leaderID string
+	term     uint64
+	applied  uint64
+	members  []config.ClusterNode
+}
+
+func (f *fakeCluster) Leader() string                { return f.leader }
+func (f *fakeCluster) LeaderID() string              { return f.leaderID }
+func (f *fakeCluster) Term() uint64                  { return f.term }
+func (f *fakeCluster) AppliedIndex() uint64          { return f.applied }
+func (f *fakeCluster) Members() []config.ClusterNode { return f.members }
+
+func testPayloadConfig() *config.Config {
+	return &config.Config{
+		Node: config.NodeConfig{ID: "node1", RaftAddr: "127.0.0.1:10001"},
+		Cluster: config.ClusterConfig{
+			Nodes: []config.ClusterNode{
+				{ID: "node1", Addr: "127.0.0.1:10001"},
+				{ID: "node2", Addr: "127.0.0.1:10002"},
+			},
+		},
+		VIP: config.VIPConfig{Address: "192.168.1.100/32", Interface: "eth0"},
+	}
+}
+
+func TestBuildPayload_WithCluster(t *testing.T) {
+	cluster := &fakeCluster{
+		leader:   "127.0.0.1:10002",
+		leaderID: "node2",
+		term:     7,
+		applied:  42,
+		members: []config.ClusterNode{
+			{ID: "node1", Addr: "127.0.0.1:10001"},
+			{ID: "node2", Addr: "127.0.0.1:10002"},
+			{ID: "node3", Addr: "127.0.0.1:10003"},
+		},
+	}
+
+	payload := buildPayload(testPayloadConfig(), cluster, "ToSlave", Transition{
+		PreviousState: "Master",
+		NewState:      "Slave",
+	})
+
+	if payload.Version != PayloadVersion {
+		t.Errorf("Version = %d, want %d", payload.Version, PayloadVersion)
+	}
+	if payload.Event != "ToSlave" || payload.PreviousState != "Master" || payload.NewState != "Slave" {
+		t.Errorf("unexpected event fields: %+v", payload)
+	}
+	if payload.NodeID != "node1" {
+		t.Errorf("NodeID = %v, want node1", payload.NodeID)
+	}
+	if payload.LeaderID != "node2" || payload.LeaderAddr != "127.0.0.1:10002" {
+		t.Errorf("leader = %v/%v, want node2/127.0.0.1:10002", payload.LeaderID, payload.LeaderAddr)
+	}
+	if payload.Term != 7 || payload.Fencing.Token != 7 || payload.Fencing.AppliedIndex != 42 {
+		t.Errorf("term/fencing = %d/%+v, want 7/{7 42}", payload.Term, payload.Fencing)
+	}
+	if len(payload.Members) != 3 {
+		t.Fatalf("len(Members) = %d, want 3", len(payload.Members))
+	}
+	for _, m := range payload.Members {
+		if m.Leader != (m.ID == "node2") {
+			t.Errorf("member %s Leader = %v", m.ID, m.Leader)
+		}
+	}
+	if payload.VIP.Address != "192.168.1.100/32" || payload.VIP.Interface != "eth0" {
+		t.Errorf("VIP = %+v", payload.VIP)
+	}
+	if payload.Timestamp.IsZero() {
+		t.Error("Timestamp is zero")
+	}
+}
+
+func TestBuildPayload_WithoutCluster(t *testing.T) {
+	payload := buildPayload(testPayloadConfig(), nil, "ToReady", Transition{NewState: "Ready"})
+
+	if payload.LeaderID != "" || payload.Term != 0 {
+		t.Errorf("expected no leader information, got %+v", payload)
+	}
+	if len(payload.Members) != 2 {
+		t.Errorf("len(Members) = %d, want 2 from static config", len(payload.Members))
+	}
+}
+
+func TestPayload_Marshal(t *testing.T) {
+	payload := buildPayload(testPayloadConfig(), nil, "ToMaster", Transition{
+		PreviousState: "Slave",
+		NewState:      "Master",
+	})
+
+	data, err := payload.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error: %v", err)
+	}
+	if data[len(data)-1] != '\n' {
+		t.Error("Marshal() output is not newline terminated")
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Marshal() produced invalid JSON: %v", err)
+	}
+
+	for _, key := range []string{"version", "event", "previous_state", "new_state", "node_id",
+		"leader_id", "term", "fencing", "members", "vip", "timestamp"} {
+		if _, ok := decoded[key]; !ok {
+			t.Errorf("payload is missing key %q", key)
+		}
+	}
+}
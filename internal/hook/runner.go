@@ -0,0 +1,175 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"vip-switch-go/internal/config"
+	"vip-switch-go/internal/vip"
+)
+
+// HookRunner runs one action of a hook, e.g. a command or an HTTP request.
+// The returned result describes the attempt and is never nil.
+type HookRunner interface {
+	ExecuteHook(ctx context.Context, event string, payload Payload) (*Result, error)
+}
+
+// Gratuitous ARP announcements the builtin-arp runner sends on ToMaster
+const (
+	arpCount    = 3
+	arpInterval = 200 * time.Millisecond
+)
+
+// namedRunner is a runner picked for a hook run
+type namedRunner struct {
+	name   string
+	runner HookRunner
+}
+
+// execRunner runs a prepared invocation, which carries the payload on stdin
+type execRunner struct {
+	executor *Executor
+	inv      *Invocation
+}
+
+// ExecuteHook runs the invocation, see Executor.Run
+func (r *execRunner) ExecuteHook(ctx context.Context, event string, payload Payload) (*Result, error) {
+	return r.executor.Run(ctx, r.inv)
+}
+
+// httpRunner posts the payload to the URL of a hook definition resolved
+// when the run started. A response other than 2xx fails the attempt.
+type httpRunner struct {
+	client     *http.Client
+	logger     *slog.Logger
+	request    config.HTTPHook
+	outputTail int
+}
+
+// ExecuteHook posts the payload, keeping the tail of the response body as
+// the attempt's stdout
+func (r *httpRunner) ExecuteHook(ctx context.Context, event string, payload Payload) (*Result, error) {
+	start := time.Now()
+	result := &Result{ExitCode: -1}
+	fail := func(reason FailureReason, err error) (*Result, error) {
+		result.Duration = time.Since(start)
+		result.Reason = reason
+		if ctx.Err() != nil {
+			result.Reason = contextReason(ctx)
+		}
+		return result, err
+	}
+
+	body, err := payload.Marshal()
+	if err != nil {
+		return fail(ReasonStartFailed, fmt.Errorf("failed to encode hook payload: %w", err))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.request.URL, bytes.NewReader(body))
+	if err != nil {
+		return fail(ReasonStartFailed, fmt.Errorf("invalid request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range r.request.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fail(ReasonRequestFailed, fmt.Errorf("request failed: %w", err))
+	}
+	defer resp.Body.Close()
+	output := newOutputWriter(r.logger, event, "response")
+	output.tailSize = r.outputTail
+	io.Copy(output, resp.Body)
+	output.Flush()
+	result.Stdout = string(output.tail)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fail(ReasonHTTPStatus, fmt.Errorf("request failed with status %s", resp.Status))
+	}
+	result.ExitCode = 0
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// vipRunner assigns the VIP on ToMaster and releases it on ToSlave and
+// ToDestroy through netlink. As an ensure hook it fails when the VIP is not
+// where the role wants it.
+type vipRunner struct{}
+
+// ExecuteHook adds, removes or checks the VIP of the payload
+func (vipRunner) ExecuteHook(ctx context.Context, event string, payload Payload) (*Result, error) {
+	start := time.Now()
+	iface, address := payload.VIP.Interface, payload.VIP.Address
+
+	var err error
+	switch event {
+	case "ToMaster":
+		err = vip.Add(iface, address)
+	case "ToSlave", "ToDestroy":
+		err = vip.Remove(iface, address)
+	case "EnsureMaster", "EnsureSlave":
+		var present bool
+		if present, err = vip.Present(iface, address); err == nil && present != (event == "EnsureMaster") {
+			err = fmt.Errorf("VIP %s assigned to %s: %v", address, iface, present)
+		}
+	}
+	return builtinResult(start, err), err
+}
+
+// arpRunner announces the VIP with gratuitous ARP on ToMaster and does
+// nothing on other events
+type arpRunner struct{}
+
+// ExecuteHook sends the announcements for the VIP of the payload
+func (arpRunner) ExecuteHook(ctx context.Context, event string, payload Payload) (*Result, error) {
+	start := time.Now()
+	var err error
+	if event == "ToMaster" {
+		err = vip.Announce(ctx, payload.VIP.Interface, payload.VIP.Address, arpCount, arpInterval)
+	}
+	return builtinResult(start, err), err
+}
+
+// builtinResult describes an attempt of a runner that starts no process
+func builtinResult(start time.Time, err error) *Result {
+	result := &Result{Duration: time.Since(start)}
+	if err != nil {
+		result.ExitCode = -1
+		result.Reason = ReasonRunnerError
+		if errors.Is(err, context.DeadlineExceeded) {
+			result.Reason = ReasonTimeout
+		} else if errors.Is(err, context.Canceled) {
+			result.Reason = ReasonCanceled
+		}
+	}
+	return result
+}
+
+// contextReason classifies why ctx is done
+func contextReason(ctx context.Context) FailureReason {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return ReasonTimeout
+	}
+	return ReasonCanceled
+}
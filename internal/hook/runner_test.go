@@ -0,0 +1,312 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"vip-switch-go/internal/config"
+)
+
+// recordingHookRunner records the events it runs and fails with err
+type recordingHookRunner struct {
+	mu     sync.Mutex
+	events []string
+	err    error
+}
+
+func (r *recordingHookRunner) ExecuteHook(ctx context.Context, event string, payload Payload) (*Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event+"/"+payload.Event)
+	if r.err != nil {
+		return &Result{ExitCode: -1, Reason: ReasonRunnerError}, r.err
+	}
+	return &Result{}, nil
+}
+
+// newRunnerTestSystem returns a system whose ToMaster hook lists runners
+func newRunnerTestSystem(runners ...string) *System {
+	cfg := testPayloadConfig()
+	cfg.Hooks = config.HooksConfig{
+		Enabled:   true,
+		Timeout:   5 * time.Second,
+		OnFailure: "abort",
+		ToMaster: config.HookDefinition{
+			Command: "/nonexistent/on-master.sh",
+			Runners: runners,
+		},
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	return NewSystem(cfg, logger)
+}
+
+func TestSystem_RegisteredExecRunner(t *testing.T) {
+	system := newRunnerTestSystem()
+	recorder := &recordingHookRunner{}
+	system.RegisterRunner(config.RunnerExec, recorder)
+
+	rec, err := system.Run(context.Background(), "ToMaster", Transition{NewState: "Master"})
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if want := []string{"ToMaster/ToMaster"}; !reflect.DeepEqual(recorder.events, want) {
+		t.Errorf("recorded %v, want %v", recorder.events, want)
+	}
+	if len(rec.Attempts) != 1 || rec.Attempts[0].Runner != config.RunnerExec {
+		t.Errorf("Attempts = %+v, want one exec attempt", rec.Attempts)
+	}
+}
+
+func TestSystem_RunnersInOrder(t *testing.T) {
+	errFailed := errors.New("failed")
+	tests := []struct {
+		name       string
+		onFailure  string
+		failSecond bool
+		wantErr    bool
+		wantEvents [3]int
+	}{
+		{"all succeed", "abort", false, false, [3]int{1, 1, 1}},
+		{"failure stops the run", "abort", true, true, [3]int{1, 1, 0}},
+		{"failure continues", "continue", true, false, [3]int{1, 1, 1}},
+		{"failure is retried", "retry", true, true, [3]int{1, 2, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			system := newRunnerTestSystem("first", "second", "third")
+			hookDef := &system.config.Load().Hooks.ToMaster
+			hookDef.OnFailure = tt.onFailure
+			hookDef.Retry = config.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}
+
+			runners := []*recordingHookRunner{{}, {}, {}}
+			if tt.failSecond {
+				runners[1].err = errFailed
+			}
+			for i, name := range []string{"first", "second", "third"} {
+				system.RegisterRunner(name, runners[i])
+			}
+
+			_, err := system.Run(context.Background(), "ToMaster", Transition{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for i, runner := range runners {
+				if got := len(runner.events); got != tt.wantEvents[i] {
+					t.Errorf("runner %d ran %d times, want %d", i, got, tt.wantEvents[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSystem_UnknownRunner(t *testing.T) {
+	system := newRunnerTestSystem("ssh")
+	if _, err := system.Run(context.Background(), "ToMaster", Transition{}); err == nil {
+		t.Error("Run() with an unknown runner = nil, want an error")
+	}
+}
+
+func TestHTTPRunner(t *testing.T) {
+	var (
+		status  = http.StatusOK
+		got     Payload
+		headers http.Header
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s, want POST", r.Method)
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("body is not a payload: %v", err)
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, "accepted\n")
+	}))
+	defer server.Close()
+
+	system := newRunnerTestSystem(config.RunnerHTTP)
+	cfg := system.config.Load()
+	cfg.Hooks.History.OutputTail = 64
+	cfg.Hooks.ToMaster.HTTP = config.HTTPHook{URL: server.URL, Headers: map[string]string{"Authorization": "Bearer token"}}
+
+	rec, err := system.Run(context.Background(), "ToMaster", Transition{NewState: "Master"})
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if got.Event != "ToMaster" || got.NewState != "Master" || got.VIP.Address != cfg.VIP.Address {
+		t.Errorf("posted payload = %+v, want the ToMaster payload", got)
+	}
+	if headers.Get("Content-Type") != "application/json" || headers.Get("Authorization") != "Bearer token" {
+		t.Errorf("headers = %v, want JSON content and the configured Authorization", headers)
+	}
+	if attempt := rec.Attempts[0]; attempt.Runner != config.RunnerHTTP || attempt.ExitCode != 0 || attempt.Stdout != "accepted\n" {
+		t.Errorf("attempt = %+v, want a successful http attempt keeping the response", attempt)
+	}
+
+	status = http.StatusServiceUnavailable
+	rec, err = system.Run(context.Background(), "ToMaster", Transition{})
+	if err == nil {
+		t.Fatal("Run() with a 503 response = nil, want an error")
+	}
+	if reason := rec.Attempts[0].Reason; reason != ReasonHTTPStatus {
+		t.Errorf("Reason = %q, want %q", reason, ReasonHTTPStatus)
+	}
+
+	server.Close()
+	rec, _ = system.Run(context.Background(), "ToMaster", Transition{})
+	if reason := rec.Attempts[0].Reason; reason != ReasonRequestFailed {
+		t.Errorf("Reason = %q after the server closed, want %q", reason, ReasonRequestFailed)
+	}
+}
+
+func TestHTTPRunner_ReloadBetweenAttempts(t *testing.T) {
+	var requests atomic.Int32
+	reloaded := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("a retry posted to the reloaded URL")
+	}))
+	defer reloaded.Close()
+
+	system := newRunnerTestSystem(config.RunnerHTTP)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first attempt fails while a reload points the hook elsewhere
+		if requests.Add(1) == 1 {
+			next := *system.config.Load()
+			next.Hooks.ToMaster.HTTP = config.HTTPHook{URL: reloaded.URL}
+			system.SetConfig(&next)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("retry headers = %v, want the ones the run started with", r.Header)
+		}
+	}))
+	defer server.Close()
+
+	hookDef := &system.config.Load().Hooks.ToMaster
+	hookDef.OnFailure = "retry"
+	hookDef.Retry = config.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}
+	hookDef.HTTP = config.HTTPHook{URL: server.URL, Headers: map[string]string{"Authorization": "Bearer token"}}
+
+	if _, err := system.Run(context.Background(), "ToMaster", Transition{}); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("first URL got %d requests, want 2", got)
+	}
+}
+
+func TestHTTPRunner_HeadersFromFile(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("Bearer tok-12345\n"), 0600); err != nil {
+		t.Fatalf("failed to write secret: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Source") != "vip-switch" {
+			t.Errorf("headers = %v, want the plain headers kept", r.Header)
+		}
+		io.WriteString(w, "got "+r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	system := newRunnerTestSystem(config.RunnerHTTP)
+	cfg := system.config.Load()
+	cfg.Hooks.History.OutputTail = 64
+	cfg.Hooks.ToMaster.HTTP = config.HTTPHook{
+		URL:             server.URL,
+		Headers:         map[string]string{"X-Source": "vip-switch"},
+		HeadersFromFile: map[string]string{"Authorization": tokenFile},
+	}
+
+	rec, err := system.Run(context.Background(), "ToMaster", Transition{})
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if got := rec.Attempts[0].Stdout; got != "got [REDACTED]" {
+		t.Errorf("attempt stdout = %q, want the header from the file, redacted", got)
+	}
+}
+
+func TestVIPRunner_Ensure(t *testing.T) {
+	payload := Payload{VIP: VIP{Address: "127.0.0.1/8", Interface: "lo"}}
+	tests := []struct {
+		event   string
+		wantErr bool
+	}{
+		{"EnsureMaster", false},
+		{"EnsureSlave", true},
+		{"ToReady", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.event, func(t *testing.T) {
+			result, err := vipRunner{}.ExecuteHook(context.Background(), tt.event, payload)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExecuteHook() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && result.Reason != ReasonRunnerError {
+				t.Errorf("Reason = %q, want %q", result.Reason, ReasonRunnerError)
+			}
+		})
+	}
+}
+
+func TestARPRunner_OnlyOnMaster(t *testing.T) {
+	// lo has no Ethernet address, so an announcement would fail
+	payload := Payload{VIP: VIP{Address: "127.0.0.1/8", Interface: "lo"}}
+	if _, err := (arpRunner{}).ExecuteHook(context.Background(), "ToSlave", payload); err != nil {
+		t.Errorf("ExecuteHook(ToSlave) = %v, want nil", err)
+	}
+	if _, err := (arpRunner{}).ExecuteHook(context.Background(), "ToMaster", payload); err == nil {
+		t.Error("ExecuteHook(ToMaster) on lo = nil, want an error")
+	}
+}
+
+func TestBuiltinResult(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want FailureReason
+	}{
+		{"success", nil, ReasonNone},
+		{"error", errors.New("failed"), ReasonRunnerError},
+		{"timeout", context.DeadlineExceeded, ReasonTimeout},
+		{"canceled", context.Canceled, ReasonCanceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := builtinResult(time.Now(), tt.err).Reason; got != tt.want {
+				t.Errorf("builtinResult() reason = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -16,71 +16,187 @@ package hook
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"os/exec"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	metrics "github.com/hashicorp/go-metrics/compat"
 	"vip-switch-go/internal/config"
+	"vip-switch-go/internal/redact"
 )
 
 // System manages hook execution
 type System struct {
-	config       *config.Config
-	logger       *slog.Logger
-	executor     *Executor
-	templateData config.TemplateData
+	config   atomic.Pointer[config.Config]
+	logger   *slog.Logger
+	executor *Executor
+	cluster  ClusterInfo
+	history  *History
+	secrets  *redact.Secrets
+	runners  map[string]HookRunner
+	client   *http.Client // shared by the http runners
+	hostname string
 }
 
 // NewSystem creates a new hook system
 func NewSystem(cfg *config.Config, logger *slog.Logger) *System {
-	return &System{
-		config:   cfg,
+	hostname, _ := os.Hostname()
+
+	executor := NewExecutor(logger)
+	if cfg.Hooks.CgroupParent != "" {
+		executor.SetCgroupParent(cfg.Hooks.CgroupParent)
+	}
+
+	s := &System{
 		logger:   logger,
-		executor: NewExecutor(logger),
-		templateData: config.TemplateData{
-			NodeID:   cfg.Node.ID,
-			RaftAddr: cfg.Node.RaftAddr,
-		},
+		executor: executor,
+		secrets:  redact.NewSecrets(),
+		hostname: hostname,
+	}
+	s.config.Store(cfg)
+	s.client = &http.Client{}
+	s.runners = map[string]HookRunner{
+		config.RunnerNetlinkVIP: vipRunner{},
+		config.RunnerBuiltinARP: arpRunner{},
+	}
+	return s
+}
+
+// RegisterRunner makes runner available to hook definitions under name. A
+// runner registered as exec or http replaces the execution of commands or
+// requests, e.g. in tests that record hooks instead of running them.
+func (s *System) RegisterRunner(name string, runner HookRunner) {
+	s.runners[name] = runner
+}
+
+// SetConfig replaces the configuration hooks are run with. Runs in
+// progress finish with the configuration they started with.
+func (s *System) SetConfig(cfg *config.Config) {
+	s.config.Store(cfg)
+}
+
+// SetHistory sets the journal every hook run is recorded in
+func (s *System) SetHistory(history *History) {
+	s.history = history
+}
+
+// SetSecrets replaces the set hook secrets are added to as they are read,
+// so that a logger redacting with the same set hides them too
+func (s *System) SetSecrets(secrets *redact.Secrets) {
+	s.secrets = secrets
+}
+
+// LoadSecrets reads the secret files of every hook in cfg and adds their
+// values to the redacted set, so that they are hidden from the first log
+// line on rather than once a hook has run
+func (s *System) LoadSecrets(cfg *config.Config) error {
+	values, err := cfg.ReadSecrets()
+	for _, value := range values {
+		s.secrets.Add(value)
 	}
+	return err
+}
+
+// SetClusterInfo sets the Raft view used to build hook payloads
+func (s *System) SetClusterInfo(cluster ClusterInfo) {
+	s.cluster = cluster
+}
+
+// KillRunning kills the hooks that are running, see Executor.KillAll
+func (s *System) KillRunning() {
+	s.executor.KillAll()
 }
 
 // ExecuteHook executes a hook by event type
-func (s *System) ExecuteHook(ctx context.Context, eventType string) error {
-	if !s.config.Hooks.Enabled {
+func (s *System) ExecuteHook(ctx context.Context, eventType string, tr Transition) error {
+	_, err := s.Run(ctx, eventType, tr)
+	return err
+}
+
+// Render resolves the invocation of a hook, with templates expanded and
+// security checks applied, without running it. It returns nil when no
+// command is configured for eventType.
+func (s *System) Render(eventType string, tr Transition) (*Invocation, error) {
+	cfg := s.config.Load()
+	hookDef, err := cfg.GetHookByEventType(eventType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hook definition: %w", err)
+	}
+	if hookDef.Command == "" {
+		return nil, nil
+	}
+	return s.prepareInvocation(cfg, buildPayload(cfg, s.cluster, eventType, tr), hookDef, &HistoryRecord{})
+}
+
+// Run executes a hook by event type like ExecuteHook and also returns the
+// record of the run. The record is nil when the hook was skipped.
+func (s *System) Run(ctx context.Context, eventType string, tr Transition) (*HistoryRecord, error) {
+	cfg := s.config.Load()
+	if !cfg.Hooks.Enabled {
 		s.logger.Debug("Hooks disabled, skipping", "event_type", eventType)
-		return nil
+		return nil, nil
 	}
 
-	hookDef, err := s.config.GetHookByEventType(eventType)
+	hookDef, err := cfg.GetHookByEventType(eventType)
 	if err != nil {
-		return fmt.Errorf("failed to get hook definition: %w", err)
+		return nil, fmt.Errorf("failed to get hook definition: %w", err)
 	}
 
-	if hookDef.Command == "" {
+	names := hookDef.RunnerNames()
+	if len(names) == 0 {
 		s.logger.Debug("No hook command configured", "event_type", eventType)
-		return nil
+		return nil, nil
 	}
 
-	s.logger.Info("Executing hook", "event_type", eventType, "command", hookDef.Command)
+	s.logger.Info("Executing hook", "event_type", eventType, "command", hookDef.Command, "runners", names)
 
-	// Set event type in template data
-	s.templateData.Event = eventType
-
-	// Expand environment variables
-	env, err := config.ExpandEnvironment(hookDef.Environment, s.templateData)
+	payload := buildPayload(cfg, s.cluster, eventType, tr)
+	rec := &HistoryRecord{
+		Event:         eventType,
+		PreviousState: tr.PreviousState,
+		NewState:      tr.NewState,
+		Command:       hookDef.Command,
+		Args:          hookDef.Args,
+		Strategy:      hookDef.OnFailure,
+		StartedAt:     time.Now(),
+	}
+	runners, err := s.pickRunners(cfg, payload, hookDef, rec)
 	if err != nil {
-		return fmt.Errorf("failed to expand environment variables: %w", err)
+		// A hook that cannot be prepared fails regardless of its strategy
+		s.recordHistory(rec, err)
+		return rec, err
 	}
 
-	// Create hook context
-	hookCtx, cancel := context.WithTimeout(ctx, hookDef.Timeout)
-	defer cancel()
-
-	// Build environment for hook
-	osEnv := buildOSEnv(env, s.templateData)
-
-	// Execute hook
-	err = s.executor.Execute(hookCtx, hookDef.Command, hookDef.Args, osEnv, eventType)
+	// Run the runners in order. Only the continue strategy goes on past a
+	// runner that failed.
+	var errs []error
+	for _, runner := range runners {
+		if hookDef.OnFailure == "retry" {
+			err = s.retryHook(ctx, &hookDef.Retry, runner, payload, rec)
+		} else {
+			hookCtx, cancel := context.WithTimeout(ctx, hookDef.Timeout)
+			_, err = s.runAttempt(hookCtx, runner, payload, rec)
+			cancel()
+		}
+		if err != nil {
+			errs = append(errs, err)
+			if hookDef.OnFailure != "continue" {
+				break
+			}
+		}
+	}
+	err = errors.Join(errs...)
+	s.recordHistory(rec, err)
 
 	if err != nil {
 		s.logger.Error("Hook execution failed", "event_type", eventType, "error", err)
@@ -88,61 +204,477 @@ func (s *System) ExecuteHook(ctx context.Context, eventType string) error {
 		// Handle based on failure strategy
 		switch hookDef.OnFailure {
 		case "abort":
-			return fmt.Errorf("hook failed with abort strategy: %w", err)
+			return rec, fmt.Errorf("hook failed with abort strategy: %w", err)
 		case "continue":
 			s.logger.Warn("Hook failed but continuing due to continue strategy", "event_type", eventType)
-			return nil
+			return rec, nil
 		case "retry":
-			return s.retryHook(hookCtx, hookDef, osEnv, eventType, 3)
+			return rec, fmt.Errorf("hook failed with retry strategy: %w", err)
 		default:
-			return fmt.Errorf("hook failed with unknown strategy '%s': %w", hookDef.OnFailure, err)
+			return rec, fmt.Errorf("hook failed with unknown strategy '%s': %w", hookDef.OnFailure, err)
 		}
 	}
 
 	s.logger.Info("Hook executed successfully", "event_type", eventType)
-	return nil
+	return rec, nil
+}
+
+// Check runs an ensure hook, which checks the VIP against the node's role,
+// and reports drift when the hook fails. The hook runs once regardless of
+// on_failure, and is recorded in the history only when it reports drift.
+func (s *System) Check(ctx context.Context, eventType string, tr Transition) (bool, error) {
+	cfg := s.config.Load()
+	if !cfg.Hooks.Enabled {
+		return false, nil
+	}
+
+	hookDef, err := cfg.GetHookByEventType(eventType)
+	if err != nil {
+		return false, fmt.Errorf("failed to get hook definition: %w", err)
+	}
+	if len(hookDef.RunnerNames()) == 0 {
+		return false, nil
+	}
+
+	payload := buildPayload(cfg, s.cluster, eventType, tr)
+	rec := &HistoryRecord{
+		Event:         eventType,
+		PreviousState: tr.PreviousState,
+		NewState:      tr.NewState,
+		Command:       hookDef.Command,
+		Args:          hookDef.Args,
+		StartedAt:     time.Now(),
+	}
+	runners, err := s.pickRunners(cfg, payload, hookDef, rec)
+	if err != nil {
+		s.recordHistory(rec, err)
+		return false, err
+	}
+
+	for _, runner := range runners {
+		hookCtx, cancel := context.WithTimeout(ctx, hookDef.Timeout)
+		_, err = s.runAttempt(hookCtx, runner, payload, rec)
+		cancel()
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		return false, nil
+	}
+	if ctx.Err() != nil {
+		// Canceled by the caller, the check did not complete
+		return false, ctx.Err()
+	}
+
+	s.recordHistory(rec, err)
+	return true, nil
 }
 
-// retryHook retries hook execution with exponential backoff
-func (s *System) retryHook(ctx context.Context, hookDef *config.HookDefinition, env []string, eventType string, maxRetries int) error {
+// pickRunners resolves the runners of a hook in order. The exec runner gets
+// the hook's invocation and the http runner its request, both fixed for the
+// whole run, unless a runner was registered under their name.
+func (s *System) pickRunners(cfg *config.Config, payload Payload, hookDef *config.HookDefinition, rec *HistoryRecord) ([]namedRunner, error) {
+	names := hookDef.RunnerNames()
+	runners := make([]namedRunner, 0, len(names))
+	for _, name := range names {
+		runner, ok := s.runners[name]
+		if !ok && name == config.RunnerExec {
+			inv, err := s.prepareInvocation(cfg, payload, hookDef, rec)
+			if err != nil {
+				return nil, err
+			}
+			runner, ok = &execRunner{executor: s.executor, inv: inv}, true
+		}
+		if !ok && name == config.RunnerHTTP {
+			secrets, err := hookDef.HTTP.ReadSecrets()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read secrets: %w", err)
+			}
+			headers := maps.Clone(hookDef.HTTP.Headers)
+			if headers == nil {
+				headers = make(map[string]string, len(secrets))
+			}
+			for header, value := range secrets {
+				s.secrets.Add(value)
+				headers[header] = value
+			}
+			runner, ok = &httpRunner{
+				client:     s.client,
+				logger:     s.logger,
+				request:    config.HTTPHook{URL: hookDef.HTTP.URL, Headers: headers},
+				outputTail: cfg.Hooks.History.OutputTail,
+			}, true
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown hook runner %s", name)
+		}
+		runners = append(runners, namedRunner{name: name, runner: runner})
+	}
+	return runners, nil
+}
+
+// prepareInvocation builds the invocation of a hook, recording its
+// environment keys and resolved command in rec
+func (s *System) prepareInvocation(cfg *config.Config, payload Payload, hookDef *config.HookDefinition, rec *HistoryRecord) (*Invocation, error) {
+	eventType := payload.Event
+	data := s.templateData(cfg, payload)
+
+	// Expand templates in args and environment variables
+	args, err := hookDef.ExpandArgs(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand args: %w", err)
+	}
+	rec.Args = args
+
+	env, err := hookDef.ExpandEnvironment(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand environment variables: %w", err)
+	}
+
+	secrets, err := hookDef.ReadSecrets()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets: %w", err)
+	}
+	for _, value := range secrets {
+		s.secrets.Add(value)
+	}
+
+	// Build environment for hook
+	security := &cfg.Security
+	var hookEnv, secretEnv []string
+	if security.SanitizeEnvironment {
+		prefixes := orDefault(security.AllowedEnvPrefixes, DefaultAllowedEnvPrefixes)
+		hookEnv = SanitizeEnvironmentWithPrefixes(env, prefixes)
+		secretEnv = SanitizeEnvironmentWithPrefixes(secrets, prefixes)
+	} else {
+		hookEnv = formatEnvironment(env)
+		secretEnv = formatEnvironment(secrets)
+	}
+	// Inherited variables come first so that configured values override them
+	osEnv := append(inheritedEnvironment(hookDef.InheritEnv), buildOSEnv(hookEnv, secretEnv, data)...)
+	rec.EnvKeys = envKeys(osEnv)
+
+	command := hookDef.Command
+	if security.ValidateCommandPath {
+		resolved, err := exec.LookPath(command)
+		if err != nil {
+			return nil, fmt.Errorf("command not found: %w", err)
+		}
+		if err := ValidateCommandPathIn(resolved, orDefault(security.SafeDirs, DefaultSafeDirs)); err != nil {
+			return nil, fmt.Errorf("hook command rejected: %w", err)
+		}
+		command = resolved
+		rec.Command = resolved
+	}
+
+	stdin, err := payload.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode hook payload: %w", err)
+	}
+
+	killSignal, err := config.ParseSignal(hookDef.KillSignal)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kill signal: %w", err)
+	}
+
+	inv := &Invocation{
+		Command:         command,
+		Args:            args,
+		Env:             osEnv,
+		Secrets:         envKeys(secretEnv),
+		EventType:       eventType,
+		Stdin:           stdin,
+		KillSignal:      killSignal,
+		KillGracePeriod: hookDef.KillGracePeriod,
+		WorkingDir:      hookDef.WorkingDir,
+		OutputTail:      cfg.Hooks.History.OutputTail,
+	}
+
+	if err := applyIdentity(inv, hookDef); err != nil {
+		return nil, fmt.Errorf("failed to prepare hook identity: %w", err)
+	}
+
+	if inv.Limits, err = buildLimits(&hookDef.Limits); err != nil {
+		return nil, fmt.Errorf("invalid hook limits: %w", err)
+	}
+
+	return inv, nil
+}
+
+// runAttempt runs a single attempt of a runner and records it in metrics
+// and rec. Attempts are numbered per runner.
+func (s *System) runAttempt(ctx context.Context, runner namedRunner, payload Payload, rec *HistoryRecord) (*Result, error) {
+	attempt := 1
+	for _, entry := range rec.Attempts {
+		if entry.Runner == runner.name {
+			attempt++
+		}
+	}
+	labels := []metrics.Label{{Name: "event", Value: payload.Event}, {Name: "runner", Value: runner.name}}
+	metrics.IncrCounterWithLabels([]string{"hook", "attempts"}, 1, labels)
+
+	start := time.Now()
+	result, err := runner.runner.ExecuteHook(ctx, payload.Event, payload)
+	if result == nil {
+		result = &Result{ExitCode: -1, Duration: time.Since(start)}
+	}
+	metrics.AddSampleWithLabels([]string{"hook", "duration"}, float32(result.Duration.Milliseconds()), labels)
+
+	entry := AttemptRecord{
+		Runner:     runner.name,
+		Attempt:    attempt,
+		StartedAt:  start,
+		DurationMS: result.Duration.Milliseconds(),
+		ExitCode:   result.ExitCode,
+		Signal:     result.Signal,
+		Reason:     result.Reason,
+		Stdout:     result.Stdout,
+		Stderr:     result.Stderr,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	rec.Attempts = append(rec.Attempts, entry)
+
+	if err != nil {
+		metrics.IncrCounterWithLabels([]string{"hook", "failures"}, 1, labels)
+		s.logger.Warn("Hook attempt failed",
+			"event_type", payload.Event,
+			"runner", runner.name,
+			"attempt", attempt,
+			"exit_code", result.ExitCode,
+			"reason", result.Reason,
+			"duration", result.Duration,
+			"error", err,
+		)
+		return result, err
+	}
+
+	s.logger.Debug("Hook attempt succeeded",
+		"event_type", payload.Event,
+		"runner", runner.name,
+		"attempt", attempt,
+		"duration", result.Duration,
+	)
+	return result, nil
+}
+
+// retryHook runs a runner of a hook according to its retry policy. Every
+// attempt gets a fresh attempt timeout; the policy deadline and ctx bound
+// the runner's whole run.
+func (s *System) retryHook(ctx context.Context, policy *config.RetryPolicy, runner namedRunner, payload Payload, rec *HistoryRecord) error {
+	if policy.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, policy.Deadline)
+		defer cancel()
+	}
+
+	eventType := payload.Event
+	backoff := policy.InitialBackoff
+
 	var lastErr error
+	attempt := 0
+	for attempt < policy.MaxAttempts {
+		attempt++
 
-	for i := 0; i < maxRetries; i++ {
-		if i > 0 {
-			backoff := time.Duration(i*i) * time.Second
-			s.logger.Info("Retrying hook", "event_type", eventType, "attempt", i+1, "backoff", backoff)
+		if attempt > 1 {
+			delay := jitterBackoff(backoff, policy.JitterFraction())
+			s.logger.Info("Retrying hook", "event_type", eventType, "attempt", attempt, "backoff", delay)
+			metrics.IncrCounterWithLabels([]string{"hook", "retries"}, 1, []metrics.Label{{Name: "event", Value: eventType}})
 
 			select {
-			case <-time.After(backoff):
+			case <-time.After(delay):
 			case <-ctx.Done():
-				return ctx.Err()
+				return fmt.Errorf("hook retry aborted after %d attempts: %w", attempt-1, errors.Join(ctx.Err(), lastErr))
+			}
+
+			backoff = time.Duration(float64(backoff) * policy.Multiplier)
+			if backoff > policy.MaxBackoff {
+				backoff = policy.MaxBackoff
 			}
 		}
 
-		err := s.executor.Execute(ctx, hookDef.Command, hookDef.Args, env, eventType)
+		attemptCtx, cancel := context.WithTimeout(ctx, policy.AttemptTimeout)
+		result, err := s.runAttempt(attemptCtx, runner, payload, rec)
+		cancel()
 		if err == nil {
 			return nil
 		}
-
 		lastErr = err
-		s.logger.Warn("Hook retry failed", "event_type", eventType, "attempt", i+1, "error", err)
+
+		if ctx.Err() != nil {
+			break
+		}
+		if result.ExitCode >= 0 && !policy.IsRetryableExitCode(result.ExitCode) {
+			s.logger.Warn("Hook exit code is not retryable", "event_type", eventType, "exit_code", result.ExitCode)
+			break
+		}
 	}
 
-	return fmt.Errorf("hook failed after %d retries: %w", maxRetries, lastErr)
+	return fmt.Errorf("hook failed after %d attempts: %w", attempt, lastErr)
 }
 
-// buildOSEnv builds OS environment variables for hook
-func buildOSEnv(hookEnv map[string]string, data config.TemplateData) []string {
-	env := make([]string, 0, len(hookEnv)+2)
+// recordHistory completes rec with the outcome of the run and appends it to
+// the history. A failure to record is logged but never fails the hook.
+func (s *System) recordHistory(rec *HistoryRecord, err error) {
+	rec.FinishedAt = time.Now()
+	rec.Success = err == nil
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	s.redact(rec)
+
+	if s.history == nil {
+		return
+	}
+	if err := s.history.Append(rec); err != nil {
+		s.logger.Warn("Failed to record hook history", "event_type", rec.Event, "error", err)
+	}
+}
+
+// redact hides secret values a hook may have echoed in its arguments,
+// output or errors before rec is kept
+func (s *System) redact(rec *HistoryRecord) {
+	// Args is shared with the invocation
+	rec.Args = slices.Clone(rec.Args)
+	s.secrets.Strings(rec.Args)
+	rec.Error = s.secrets.String(rec.Error)
+	for i := range rec.Attempts {
+		attempt := &rec.Attempts[i]
+		attempt.Error = s.secrets.String(attempt.Error)
+		attempt.Stdout = s.secrets.String(attempt.Stdout)
+		attempt.Stderr = s.secrets.String(attempt.Stderr)
+	}
+}
+
+// jitterBackoff spreads a backoff by up to +/- jitter of its value
+func jitterBackoff(backoff time.Duration, jitter float64) time.Duration {
+	if jitter <= 0 {
+		return backoff
+	}
+	delta := float64(backoff) * jitter * (2*rand.Float64() - 1)
+	return backoff + time.Duration(delta)
+}
+
+// applyIdentity sets the user, umask and capabilities a hook runs with
+func applyIdentity(inv *Invocation, hookDef *config.HookDefinition) error {
+	if hookDef.User != "" {
+		cred, err := lookupCredential(hookDef.User, hookDef.Group)
+		if err != nil {
+			return err
+		}
+		inv.Credential = cred
+	}
+
+	umask, err := config.ParseUmask(hookDef.Umask)
+	if err != nil {
+		return err
+	}
+	if umask >= 0 {
+		inv.Umask = &umask
+	}
+
+	for _, name := range hookDef.Capabilities {
+		capability, err := config.ParseCapability(name)
+		if err != nil {
+			return err
+		}
+		inv.AmbientCaps = append(inv.AmbientCaps, capability)
+	}
+
+	return nil
+}
+
+// templateData returns the data hook templates are rendered with, taken
+// from the payload so that both describe the same moment
+func (s *System) templateData(cfg *config.Config, payload Payload) config.TemplateData {
+	data := config.TemplateData{
+		NodeID:        payload.NodeID,
+		Event:         payload.Event,
+		RaftAddr:      cfg.Node.RaftAddr,
+		PreviousState: payload.PreviousState,
+		NewState:      payload.NewState,
+		LeaderID:      payload.LeaderID,
+		LeaderAddr:    payload.LeaderAddr,
+		Term:          payload.Term,
+		Peers:         []string{},
+		VIP:           cfg.VIP,
+		Hostname:      s.hostname,
+		Timestamp:     payload.Timestamp.Format(time.RFC3339),
+	}
+	for _, member := range payload.Members {
+		if member.ID != payload.NodeID {
+			data.Peers = append(data.Peers, member.Address)
+		}
+	}
+	return data
+}
+
+// buildLimits converts configured resource limits, returning nil when none are set
+func buildLimits(cfg *config.ResourceLimits) (*Limits, error) {
+	if cfg.IsZero() {
+		return nil, nil
+	}
+
+	memoryMax, err := config.ParseByteSize(cfg.MemoryMax)
+	if err != nil {
+		return nil, err
+	}
+	cpuQuota, err := config.ParseCPUQuota(cfg.CPUQuota)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Limits{
+		MemoryMax: memoryMax,
+		CPUWeight: cfg.CPUWeight,
+		CPUQuota:  cpuQuota,
+		PidsMax:   cfg.PidsMax,
+		NoFile:    cfg.NoFile,
+	}, nil
+}
+
+// formatEnvironment converts an environment map to KEY=value entries
+func formatEnvironment(env map[string]string) []string {
+	formatted := make([]string, 0, len(env))
+	for key, value := range env {
+		formatted = append(formatted, fmt.Sprintf("%s=%s", key, value))
+	}
+	return formatted
+}
+
+// envKeys returns the names of KEY=value entries, leaving out the values
+func envKeys(env []string) []string {
+	keys := make([]string, 0, len(env))
+	for _, kv := range env {
+		key, _, _ := strings.Cut(kv, "=")
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// orDefault returns values, or defaults when values is empty
+func orDefault(values, defaults []string) []string {
+	if len(values) == 0 {
+		return defaults
+	}
+	return values
+}
+
+// buildOSEnv builds OS environment variables for hook. Secret variables
+// come last so that they override a plain variable of the same name.
+func buildOSEnv(hookEnv, secretEnv []string, data config.TemplateData) []string {
+	env := make([]string, 0, len(hookEnv)+len(secretEnv)+2)
 
 	// Add standard environment variables
 	env = append(env, fmt.Sprintf("EVENT_TYPE=%s", data.Event))
 	env = append(env, fmt.Sprintf("NODE_ID=%s", data.NodeID))
 
 	// Add hook-specific environment variables
-	for key, value := range hookEnv {
-		env = append(env, fmt.Sprintf("%s=%s", key, value))
-	}
+	env = append(env, hookEnv...)
+	env = append(env, secretEnv...)
 
 	return env
 }
@@ -0,0 +1,483 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hook
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"vip-switch-go/internal/config"
+	"vip-switch-go/internal/redact"
+)
+
+// newRetryTestSystem returns a system whose ToMaster hook runs script with
+// the retry strategy and the given policy
+func newRetryTestSystem(script string, policy config.RetryPolicy) *System {
+	cfg := testPayloadConfig()
+	cfg.Hooks = config.HooksConfig{
+		Enabled:   true,
+		Timeout:   5 * time.Second,
+		OnFailure: "abort",
+		ToMaster: config.HookDefinition{
+			Command:   "sh",
+			Args:      []string{"-c", script},
+			OnFailure: "retry",
+			Retry:     policy,
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	return NewSystem(cfg, logger)
+}
+
+// countAttempts returns the number of lines the test script appended to path
+func countAttempts(t *testing.T, path string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read attempt log: %v", err)
+	}
+	return strings.Count(string(data), "\n")
+}
+
+func TestRetryHook_SucceedsAfterFailures(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "attempts")
+	script := `echo x >> ` + counter + `; [ $(wc -l < ` + counter + `) -ge 3 ]`
+
+	system := newRetryTestSystem(script, config.RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     20 * time.Millisecond,
+	})
+
+	if err := system.ExecuteHook(context.Background(), "ToMaster", Transition{}); err != nil {
+		t.Fatalf("ExecuteHook() unexpected error: %v", err)
+	}
+	if got := countAttempts(t, counter); got != 3 {
+		t.Errorf("attempts = %d, want 3", got)
+	}
+}
+
+func TestRetryHook_MaxAttempts(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "attempts")
+
+	system := newRetryTestSystem(`echo x >> `+counter+`; exit 1`, config.RetryPolicy{
+		MaxAttempts:    2,
+		InitialBackoff: 10 * time.Millisecond,
+	})
+
+	err := system.ExecuteHook(context.Background(), "ToMaster", Transition{})
+	if err == nil {
+		t.Fatal("ExecuteHook() expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "after 2 attempts") {
+		t.Errorf("ExecuteHook() error = %v, want to contain 'after 2 attempts'", err)
+	}
+	if got := countAttempts(t, counter); got != 2 {
+		t.Errorf("attempts = %d, want 2", got)
+	}
+}
+
+func TestRetryHook_NonRetryableExitCode(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "attempts")
+
+	system := newRetryTestSystem(`echo x >> `+counter+`; exit 2`, config.RetryPolicy{
+		MaxAttempts:        5,
+		InitialBackoff:     10 * time.Millisecond,
+		RetryableExitCodes: []int{75},
+	})
+
+	if err := system.ExecuteHook(context.Background(), "ToMaster", Transition{}); err == nil {
+		t.Fatal("ExecuteHook() expected error, got nil")
+	}
+	if got := countAttempts(t, counter); got != 1 {
+		t.Errorf("attempts = %d, want 1 for a non-retryable exit code", got)
+	}
+}
+
+func TestRetryHook_AttemptTimeoutIsPerAttempt(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "attempts")
+	// The first attempt hangs past its timeout, the second succeeds at once
+	script := `echo x >> ` + counter + `; [ $(wc -l < ` + counter + `) -ge 2 ] || exec sleep 5`
+
+	system := newRetryTestSystem(script, config.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 10 * time.Millisecond,
+		AttemptTimeout: 200 * time.Millisecond,
+	})
+
+	if err := system.ExecuteHook(context.Background(), "ToMaster", Transition{}); err != nil {
+		t.Fatalf("ExecuteHook() unexpected error: %v", err)
+	}
+	if got := countAttempts(t, counter); got != 2 {
+		t.Errorf("attempts = %d, want 2", got)
+	}
+}
+
+func TestRetryHook_Deadline(t *testing.T) {
+	system := newRetryTestSystem(`exit 1`, config.RetryPolicy{
+		MaxAttempts:    100,
+		InitialBackoff: 50 * time.Millisecond,
+		Deadline:       300 * time.Millisecond,
+	})
+
+	start := time.Now()
+	err := system.ExecuteHook(context.Background(), "ToMaster", Transition{})
+	if err == nil {
+		t.Fatal("ExecuteHook() expected error, got nil")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("ExecuteHook() took %v, want the deadline to stop retries", elapsed)
+	}
+}
+
+func TestJitterBackoff(t *testing.T) {
+	backoff := 100 * time.Millisecond
+
+	if got := jitterBackoff(backoff, 0); got != backoff {
+		t.Errorf("jitterBackoff() without jitter = %v, want %v", got, backoff)
+	}
+
+	for i := 0; i < 100; i++ {
+		got := jitterBackoff(backoff, 0.5)
+		if got < 50*time.Millisecond || got > 150*time.Millisecond {
+			t.Fatalf("jitterBackoff() = %v, want within 50ms..150ms", got)
+		}
+	}
+}
+
+func TestExecuteHook_ValidateCommandPath(t *testing.T) {
+	cfg := testPayloadConfig()
+	cfg.Hooks = config.HooksConfig{
+		Enabled:   true,
+		Timeout:   5 * time.Second,
+		OnFailure: "continue",
+		ToMaster:  config.HookDefinition{Command: "sh", Args: []string{"-c", "true"}},
+	}
+	cfg.Security = config.SecurityConfig{
+		ValidateCommandPath: true,
+		SafeDirs:            []string{"/nonexistent/safe"},
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	err := NewSystem(cfg, logger).ExecuteHook(context.Background(), "ToMaster", Transition{})
+	if err == nil || !strings.Contains(err.Error(), "hook command rejected") {
+		t.Errorf("ExecuteHook() error = %v, want 'hook command rejected'", err)
+	}
+}
+
+func TestExecuteHook_SanitizeEnvironment(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "env")
+	t.Setenv("VIPTEST_INHERITED", "from-daemon")
+
+	cfg := testPayloadConfig()
+	cfg.Hooks = config.HooksConfig{
+		Enabled:   true,
+		Timeout:   5 * time.Second,
+		OnFailure: "abort",
+		ToMaster: config.HookDefinition{
+			Command: "/bin/sh",
+			Args:    []string{"-c", "env > " + out},
+			Environment: map[string]string{
+				"VIP_ADDRESS": "192.168.1.100/32",
+				"SECRET_VAR":  "dropped",
+			},
+			InheritEnv: []string{"VIPTEST_INHERITED"},
+		},
+	}
+	cfg.Security = config.SecurityConfig{
+		SanitizeEnvironment: true,
+		ValidateCommandPath: true,
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	if err := NewSystem(cfg, logger).ExecuteHook(context.Background(), "ToMaster", Transition{}); err != nil {
+		t.Fatalf("ExecuteHook() unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read hook environment: %v", err)
+	}
+	env := string(data)
+	for _, want := range []string{"VIP_ADDRESS=192.168.1.100/32", "EVENT_TYPE=ToMaster", "NODE_ID=node1", "VIPTEST_INHERITED=from-daemon"} {
+		if !strings.Contains(env, want) {
+			t.Errorf("hook environment missing %q:\n%s", want, env)
+		}
+	}
+	if strings.Contains(env, "SECRET_VAR") {
+		t.Errorf("hook environment contains unsanitized SECRET_VAR:\n%s", env)
+	}
+}
+
+func TestExecuteHook_RecordsHistory(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "attempts")
+	script := `echo attempt; echo oops >&2; echo x >> ` + counter + `; [ $(wc -l < ` + counter + `) -ge 2 ]`
+
+	system := newRetryTestSystem(script, config.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 10 * time.Millisecond,
+	})
+	system.config.Load().Hooks.History.OutputTail = 64
+	system.config.Load().Hooks.ToMaster.Environment = map[string]string{"VIP_TOKEN": "secret-value"}
+
+	history, err := OpenHistory(filepath.Join(t.TempDir(), "history.jsonl"), 10, 0)
+	if err != nil {
+		t.Fatalf("OpenHistory() unexpected error: %v", err)
+	}
+	system.SetHistory(history)
+
+	tr := Transition{PreviousState: "Slave", NewState: "Master"}
+	if err := system.ExecuteHook(context.Background(), "ToMaster", tr); err != nil {
+		t.Fatalf("ExecuteHook() unexpected error: %v", err)
+	}
+
+	records, err := history.Query(HistoryFilter{})
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Query() = %d records, %v, want 1", len(records), err)
+	}
+	rec := records[0]
+	if !rec.Success || rec.Event != "ToMaster" || rec.PreviousState != "Slave" || rec.Strategy != "retry" {
+		t.Errorf("record = %+v, want a successful ToMaster retry run from Slave", rec)
+	}
+	if len(rec.Attempts) != 2 {
+		t.Fatalf("record has %d attempts, want 2", len(rec.Attempts))
+	}
+	first := rec.Attempts[0]
+	if first.ExitCode != 1 || first.Reason != ReasonExitCode || first.Error == "" {
+		t.Errorf("first attempt = %+v, want exit code 1", first)
+	}
+	if first.Stdout != "attempt\n" || first.Stderr != "oops\n" {
+		t.Errorf("first attempt output = %q, %q, want the hook's output", first.Stdout, first.Stderr)
+	}
+
+	data, _ := os.ReadFile(history.Path())
+	if strings.Contains(string(data), "secret-value") {
+		t.Error("history contains an environment value")
+	}
+	if !strings.Contains(strings.Join(rec.EnvKeys, ","), "VIP_TOKEN") {
+		t.Errorf("record env keys = %v, want VIP_TOKEN", rec.EnvKeys)
+	}
+}
+
+func TestSystem_RunWithSecrets(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte("tok-12345\n"), 0600); err != nil {
+		t.Fatalf("failed to write secret: %v", err)
+	}
+
+	system := newRetryTestSystem(`echo "token is $VIP_TOKEN"; echo "$VIP_TOKEN" >&2; exit 1`, config.RetryPolicy{MaxAttempts: 1})
+	hookDef := &system.config.Load().Hooks.ToMaster
+	hookDef.Args = append(hookDef.Args, "tok-12345")
+	hookDef.EnvironmentFromFile = map[string]string{"VIP_TOKEN": tokenFile}
+	system.config.Load().Hooks.History.OutputTail = 64
+
+	secrets := redact.NewSecrets()
+	var logs bytes.Buffer
+	system.logger = slog.New(redact.NewHandler(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}), secrets))
+	system.executor.logger = system.logger
+	system.SetSecrets(secrets)
+
+	inv, err := system.Render("ToMaster", Transition{})
+	if err != nil {
+		t.Fatalf("Render() unexpected error: %v", err)
+	}
+	if !slices.Contains(inv.Env, "VIP_TOKEN=tok-12345") || !slices.Equal(inv.Secrets, []string{"VIP_TOKEN"}) {
+		t.Errorf("Render() env = %v, secrets = %v, want VIP_TOKEN from its file", inv.Env, inv.Secrets)
+	}
+
+	rec, err := system.Run(context.Background(), "ToMaster", Transition{})
+	if err == nil {
+		t.Fatal("Run() expected error, got nil")
+	}
+	attempt := rec.Attempts[0]
+	if attempt.Stdout != "token is [REDACTED]\n" || attempt.Stderr != "[REDACTED]\n" {
+		t.Errorf("attempt output = %q, %q, want the secret redacted", attempt.Stdout, attempt.Stderr)
+	}
+	if rec.Args[len(rec.Args)-1] != redact.Placeholder || inv.Args[len(inv.Args)-1] != "tok-12345" {
+		t.Errorf("record args = %v, want the secret redacted without changing the invocation", rec.Args)
+	}
+	if strings.Contains(logs.String(), "tok-12345") {
+		t.Errorf("logs contain the secret:\n%s", logs.String())
+	}
+}
+
+func TestSystem_LoadSecrets(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("tok-12345\n"), 0600); err != nil {
+		t.Fatalf("failed to write secret: %v", err)
+	}
+
+	system := newRetryTestSystem("exit 0", config.RetryPolicy{MaxAttempts: 1})
+	cfg := system.config.Load()
+	cfg.Hooks.ToSlave.EnvironmentFromFile = map[string]string{"VIP_TOKEN": tokenFile}
+	secrets := redact.NewSecrets()
+	system.SetSecrets(secrets)
+
+	if err := system.LoadSecrets(cfg); err != nil {
+		t.Fatalf("LoadSecrets() unexpected error: %v", err)
+	}
+	if got := secrets.String("token tok-12345"); got != "token [REDACTED]" {
+		t.Errorf("String() = %q, want the secret redacted before any hook ran", got)
+	}
+}
+
+func TestSystem_Render(t *testing.T) {
+	cfg := testPayloadConfig()
+	cfg.Hooks = config.HooksConfig{
+		Enabled:   false,
+		Timeout:   5 * time.Second,
+		OnFailure: "abort",
+		ToMaster: config.HookDefinition{
+			Command:     "/bin/sh",
+			Args:        []string{"-c", "touch should-not-exist"},
+			Environment: map[string]string{"VIP_NODE": "{{.NodeID}}"},
+			WorkingDir:  t.TempDir(),
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	system := NewSystem(cfg, logger)
+
+	inv, err := system.Render("ToMaster", Transition{PreviousState: "Slave", NewState: "Master"})
+	if err != nil {
+		t.Fatalf("Render() unexpected error: %v", err)
+	}
+	if inv.Command != "/bin/sh" || len(inv.Args) != 2 {
+		t.Errorf("Render() command = %s %v, want /bin/sh with 2 args", inv.Command, inv.Args)
+	}
+	if !strings.Contains(strings.Join(inv.Env, " "), "VIP_NODE=node1") {
+		t.Errorf("Render() env = %v, want expanded VIP_NODE=node1", inv.Env)
+	}
+	if !strings.Contains(string(inv.Stdin), `"previous_state":"Slave"`) {
+		t.Errorf("Render() payload = %s, want previous_state Slave", inv.Stdin)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.Hooks.ToMaster.WorkingDir, "should-not-exist")); err == nil {
+		t.Error("Render() ran the hook")
+	}
+
+	if inv, err := system.Render("ToSlave", Transition{}); err != nil || inv != nil {
+		t.Errorf("Render() of an unconfigured hook = %v, %v, want nil, nil", inv, err)
+	}
+	if _, err := system.Render("ToNowhere", Transition{}); err == nil {
+		t.Error("Render() of an unknown event expected error, got nil")
+	}
+}
+
+func TestSystem_RunReturnsRecord(t *testing.T) {
+	system := newRetryTestSystem(`echo done`, config.RetryPolicy{MaxAttempts: 1})
+	system.config.Load().Hooks.History.OutputTail = 64
+
+	rec, err := system.Run(context.Background(), "ToMaster", Transition{})
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+	if rec == nil || !rec.Success || len(rec.Attempts) != 1 || rec.Attempts[0].Stdout != "done\n" {
+		t.Errorf("Run() record = %+v, want one successful attempt printing done", rec)
+	}
+
+	system.config.Load().Hooks.Enabled = false
+	if rec, err := system.Run(context.Background(), "ToMaster", Transition{}); rec != nil || err != nil {
+		t.Errorf("Run() with hooks disabled = %v, %v, want nil, nil", rec, err)
+	}
+}
+
+func TestExecuteHook_TemplatedArgs(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "args")
+
+	cfg := testPayloadConfig()
+	cfg.Hooks = config.HooksConfig{
+		Enabled:   true,
+		Timeout:   5 * time.Second,
+		OnFailure: "abort",
+		ToMaster: config.HookDefinition{
+			Command: "sh",
+			Args:    []string{"-c", `echo "$0 $1" > ` + out, "{{.PreviousState}}->{{.NewState}}", "{{join \",\" .Peers}}"},
+			Environment: map[string]string{
+				"VIP_DEV": "{{.VIP.Interface}}",
+			},
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	err := NewSystem(cfg, logger).ExecuteHook(context.Background(), "ToMaster", Transition{PreviousState: "Slave", NewState: "Master"})
+	if err != nil {
+		t.Fatalf("ExecuteHook() unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read hook output: %v", err)
+	}
+	if got := strings.TrimSpace(string(data)); got != "Slave->Master 127.0.0.1:10002" {
+		t.Errorf("hook args = %q, want %q", got, "Slave->Master 127.0.0.1:10002")
+	}
+}
+
+func TestSystem_Check(t *testing.T) {
+	cfg := testPayloadConfig()
+	cfg.Hooks = config.HooksConfig{
+		Enabled:   true,
+		Timeout:   5 * time.Second,
+		OnFailure: "continue",
+		EnsureMaster: config.HookDefinition{
+			Command: "/bin/sh",
+			Args:    []string{"-c", "exit 1"},
+		},
+		EnsureSlave: config.HookDefinition{
+			Command: "/bin/sh",
+			Args:    []string{"-c", "exit 0"},
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	system := NewSystem(cfg, logger)
+	history, err := OpenHistory(filepath.Join(t.TempDir(), "history.jsonl"), 10, 0)
+	if err != nil {
+		t.Fatalf("OpenHistory() unexpected error: %v", err)
+	}
+	system.SetHistory(history)
+
+	tests := []struct {
+		event string
+		want  bool
+	}{
+		{"EnsureMaster", true},
+		{"EnsureSlave", false},
+	}
+	for _, tt := range tests {
+		drift, err := system.Check(context.Background(), tt.event, Transition{})
+		if err != nil {
+			t.Fatalf("Check(%s) unexpected error: %v", tt.event, err)
+		}
+		if drift != tt.want {
+			t.Errorf("Check(%s) = %v, want %v", tt.event, drift, tt.want)
+		}
+	}
+
+	// Only the drift is recorded
+	records, _ := history.Query(HistoryFilter{})
+	if len(records) != 1 || records[0].Event != "EnsureMaster" {
+		t.Errorf("history = %+v, want only the EnsureMaster drift", records)
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package raft
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+// Raft's own RPC types are small integers, so a first byte at or above
+// minRPCKind marks a connection for another handler on the Raft port
+const minRPCKind byte = 0xf0
+
+// rpcKindTimeout bounds the wait for the first byte of a connection
+const rpcKindTimeout = 10 * time.Second
+
+// ErrMuxClosed is returned by Accept once the mux is closed
+var ErrMuxClosed = errors.New("raft mux closed")
+
+// Mux shares the Raft listener with lightweight RPCs. Every connection
+// starts with a byte naming its RPC; Raft's own connections start with the
+// type of their first Raft RPC and are passed on to the Raft transport
+// untouched.
+type Mux struct {
+	listener net.Listener
+	logger   *slog.Logger
+
+	mu       sync.RWMutex
+	handlers map[byte]func(net.Conn)
+
+	raftConns chan net.Conn
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// NewMux listens on addr, which must be advertisable to peers
+func NewMux(addr string, logger *slog.Logger) (*Mux, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if tcpAddr, ok := listener.Addr().(*net.TCPAddr); !ok || tcpAddr.IP == nil || tcpAddr.IP.IsUnspecified() {
+		listener.Close()
+		return nil, fmt.Errorf("local bind address is not advertisable: %s", addr)
+	}
+
+	m := &Mux{
+		listener:  listener,
+		logger:    logger,
+		handlers:  make(map[byte]func(net.Conn)),
+		raftConns: make(chan net.Conn),
+		closed:    make(chan struct{}),
+	}
+	go m.serve()
+	return m, nil
+}
+
+// Handle routes connections starting with kind to handler, which owns the
+// connection. kind must be at least 0xf0.
+func (m *Mux) Handle(kind byte, handler func(net.Conn)) {
+	if kind < minRPCKind {
+		panic(fmt.Sprintf("raft mux: RPC kind %#x collides with Raft RPCs", kind))
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers[kind] = handler
+}
+
+// serve accepts connections until the listener is closed
+func (m *Mux) serve() {
+	for {
+		conn, err := m.listener.Accept()
+		if err != nil {
+			select {
+			case <-m.closed:
+			default:
+				m.logger.Error("Raft listener failed", "error", err)
+				m.Close()
+			}
+			return
+		}
+		go m.route(conn)
+	}
+}
+
+// route reads the first byte of conn and hands it to its handler
+func (m *Mux) route(conn net.Conn) {
+	conn.SetReadDeadline(time.Now().Add(rpcKindTimeout))
+	var kind [1]byte
+	if _, err := io.ReadFull(conn, kind[:]); err != nil {
+		conn.Close()
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	if kind[0] < minRPCKind {
+		select {
+		case m.raftConns <- &peekedConn{Conn: conn, first: kind[:]}:
+		case <-m.closed:
+			conn.Close()
+		}
+		return
+	}
+
+	m.mu.RLock()
+	handler, ok := m.handlers[kind[0]]
+	m.mu.RUnlock()
+	if !ok {
+		m.logger.Debug("Unknown RPC on the Raft port", "kind", kind[0], "remote", conn.RemoteAddr())
+		conn.Close()
+		return
+	}
+	handler(conn)
+}
+
+// Accept returns the next Raft connection
+func (m *Mux) Accept() (net.Conn, error) {
+	select {
+	case conn := <-m.raftConns:
+		return conn, nil
+	case <-m.closed:
+		return nil, ErrMuxClosed
+	}
+}
+
+// Close stops accepting connections
+func (m *Mux) Close() error {
+	var err error
+	m.closeOnce.Do(func() {
+		close(m.closed)
+		err = m.listener.Close()
+	})
+	return err
+}
+
+// Addr returns the listener's address
+func (m *Mux) Addr() net.Addr {
+	return m.listener.Addr()
+}
+
+// Dial connects to a Raft peer
+func (m *Mux) Dial(address raft.ServerAddress, timeout time.Duration) (net.Conn, error) {
+	return net.DialTimeout("tcp", string(address), timeout)
+}
+
+// DialRPC connects to the Raft port at addr for an RPC of the given kind
+func DialRPC(ctx context.Context, addr string, kind byte) (net.Conn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if _, err := conn.Write([]byte{kind}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// peekedConn replays the byte the mux read before passing on a connection
+type peekedConn struct {
+	net.Conn
+	first []byte
+}
+
+func (c *peekedConn) Read(p []byte) (int, error) {
+	if len(c.first) > 0 {
+		n := copy(p, c.first)
+		c.first = c.first[n:]
+		return n, nil
+	}
+	return c.Conn.Read(p)
+}
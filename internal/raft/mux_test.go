@@ -0,0 +1,110 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package raft
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+func newTestMux(t *testing.T) *Mux {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	mux, err := NewMux("127.0.0.1:0", logger)
+	if err != nil {
+		t.Fatalf("NewMux() unexpected error: %v", err)
+	}
+	t.Cleanup(func() { mux.Close() })
+	return mux
+}
+
+func TestMux_PassesRaftConnections(t *testing.T) {
+	mux := newTestMux(t)
+
+	conn, err := mux.Dial(raft.ServerAddress(mux.Addr().String()), time.Second)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	conn.Write([]byte{0, 'r', 'a', 'f', 't'})
+
+	accepted, err := mux.Accept()
+	if err != nil {
+		t.Fatalf("Accept() unexpected error: %v", err)
+	}
+	defer accepted.Close()
+
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(accepted, buf); err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	if string(buf) != "\x00raft" {
+		t.Errorf("Raft connection read %q, want the first byte replayed", buf)
+	}
+}
+
+func TestMux_RoutesRPCs(t *testing.T) {
+	mux := newTestMux(t)
+	mux.Handle(0xf1, func(conn net.Conn) {
+		defer conn.Close()
+		conn.Write([]byte("pong"))
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn, err := DialRPC(ctx, mux.Addr().String(), 0xf1)
+	if err != nil {
+		t.Fatalf("DialRPC() unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	reply, err := io.ReadAll(conn)
+	if err != nil || string(reply) != "pong" {
+		t.Errorf("RPC reply = %q, %v, want pong", reply, err)
+	}
+}
+
+func TestMux_Close(t *testing.T) {
+	mux := newTestMux(t)
+	mux.Close()
+	if _, err := mux.Accept(); err != ErrMuxClosed {
+		t.Errorf("Accept() after Close error = %v, want ErrMuxClosed", err)
+	}
+}
+
+func TestMux_HandleRejectsRaftKinds(t *testing.T) {
+	mux := newTestMux(t)
+	defer func() {
+		if recover() == nil {
+			t.Error("Handle() with a Raft RPC kind did not panic")
+		}
+	}()
+	mux.Handle(0x01, func(net.Conn) {})
+}
+
+func TestNewMux_NotAdvertisable(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	if mux, err := NewMux("0.0.0.0:0", logger); err == nil {
+		mux.Close()
+		t.Error("NewMux() on an unspecified address expected error, got nil")
+	}
+}
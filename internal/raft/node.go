@@ -17,49 +17,61 @@ package raft
 import (
 	"fmt"
 	"log/slog"
-	"os"
-	"path/filepath"
+	"net"
 	"sync"
 	"time"
 
 	"github.com/hashicorp/raft"
-	"github.com/hashicorp/raft-boltdb"
 	"vip-switch-go/internal/config"
+	"vip-switch-go/internal/consensus"
 )
 
+var _ consensus.Consensus = (*Node)(nil)
+
 type Node struct {
 	raftInstance *raft.Raft
 	config       *config.Config
 	fsm          *FSM
+	mux          *Mux
+	storage      *Storage // opened by NewNode, closed on shutdown
 	logger       *slog.Logger
 	shutdown     bool
 	shutdownLock sync.RWMutex
 }
 
 func NewNode(cfg *config.Config, fsm *FSM, logger *slog.Logger) (*Node, error) {
-	if err := os.MkdirAll(cfg.Node.DataDir, 0700); err != nil {
-		return nil, fmt.Errorf("failed to create data directory: %w", err)
-	}
-
-	store, err := raftboltdb.NewBoltStore(filepath.Join(cfg.Node.DataDir, "raft.db"))
+	storage, err := OpenStorage(cfg, logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create BoltDB store: %w", err)
+		return nil, err
 	}
 
-	snapshots, err := raft.NewFileSnapshotStore(cfg.Node.DataDir, 1, os.Stderr)
+	transport, mux, err := NewTCPTransport(cfg.Node.RaftAddr, logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create snapshot store: %w", err)
+		storage.Close()
+		return nil, fmt.Errorf("failed to create transport: %w", err)
 	}
 
-	transport, err := NewTCPTransport(cfg.Node.RaftAddr, logger)
+	node, err := NewNodeWithTransport(cfg, fsm, transport, storage, logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create transport: %w", err)
+		transport.(raft.WithClose).Close()
+		storage.Close()
+		return nil, err
 	}
+	node.mux = mux
+	node.storage = &storage
+	return node, nil
+}
 
+// NewNodeWithTransport creates a node on the given transport and storage
+// rather than on a TCP port and the data directory. Such a node serves no
+// RPCs registered with HandleRPC.
+func NewNodeWithTransport(cfg *config.Config, fsm *FSM, transport raft.Transport, storage Storage, logger *slog.Logger) (*Node, error) {
+	settings := cfg.Storage.WithDefaults()
 	raftCfg := raft.DefaultConfig()
 	raftCfg.LocalID = raft.ServerID(cfg.Node.ID)
-	raftCfg.SnapshotInterval = 30 * time.Second
-	raftCfg.SnapshotThreshold = 2
+	raftCfg.SnapshotInterval = settings.SnapshotInterval
+	raftCfg.SnapshotThreshold = settings.SnapshotThreshold
+	raftCfg.TrailingLogs = settings.TrailingLogs
 	raftCfg.HeartbeatTimeout = 1 * time.Second
 	raftCfg.ElectionTimeout = 1 * time.Second
 	raftCfg.LeaderLeaseTimeout = 500 * time.Millisecond
@@ -68,9 +80,9 @@ func NewNode(cfg *config.Config, fsm *FSM, logger *slog.Logger) (*Node, error) {
 	raftInstance, err := raft.NewRaft(
 		raftCfg,
 		fsm,
-		store,
-		store,
-		snapshots,
+		storage.Logs,
+		storage.Stable,
+		storage.Snapshots,
 		transport,
 	)
 	if err != nil {
@@ -160,6 +172,41 @@ func (n *Node) Leader() string {
 	return string(n.raftInstance.Leader())
 }
 
+// LeaderID returns the server ID of the current leader, if known
+func (n *Node) LeaderID() string {
+	_, id := n.raftInstance.LeaderWithID()
+	return string(id)
+}
+
+// Term returns the current Raft term
+func (n *Node) Term() uint64 {
+	return n.raftInstance.CurrentTerm()
+}
+
+// AppliedIndex returns the last index applied to the FSM
+func (n *Node) AppliedIndex() uint64 {
+	return n.raftInstance.AppliedIndex()
+}
+
+// Members returns the servers in the latest Raft configuration
+func (n *Node) Members() []config.ClusterNode {
+	future := n.raftInstance.GetConfiguration()
+	if err := future.Error(); err != nil {
+		n.logger.Debug("Failed to get configuration", "error", err)
+		return nil
+	}
+
+	servers := future.Configuration().Servers
+	members := make([]config.ClusterNode, 0, len(servers))
+	for _, server := range servers {
+		members = append(members, config.ClusterNode{
+			ID:   string(server.ID),
+			Addr: string(server.Address),
+		})
+	}
+	return members
+}
+
 func (n *Node) Stats() map[string]string {
 	return n.raftInstance.Stats()
 }
@@ -180,6 +227,11 @@ func (n *Node) Shutdown() error {
 		n.logger.Error("Error during Raft shutdown", "error", err)
 		return err
 	}
+	if n.storage != nil {
+		if err := n.storage.Close(); err != nil {
+			n.logger.Warn("Error closing Raft storage", "error", err)
+		}
+	}
 
 	n.logger.Info("Raft node shutdown complete")
 	return nil
@@ -189,6 +241,37 @@ func (n *Node) IsLeader() bool {
 	return n.raftInstance.State() == raft.Leader
 }
 
-func (n *Node) Apply(cmd []byte, timeout time.Duration) raft.ApplyFuture {
-	return n.raftInstance.Apply(cmd, timeout)
+// TransferLeadership hands leadership to the most up-to-date follower and
+// waits for the transfer to finish
+func (n *Node) TransferLeadership() error {
+	return n.raftInstance.LeadershipTransfer().Error()
+}
+
+// TransferLeadershipTo hands leadership to the server id at address
+func (n *Node) TransferLeadershipTo(id, address string) error {
+	return n.raftInstance.LeadershipTransferToServer(raft.ServerID(id), raft.ServerAddress(address)).Error()
+}
+
+// HandleRPC serves RPCs of the given kind on the Raft port, see Mux.Handle
+func (n *Node) HandleRPC(kind byte, handler func(net.Conn)) {
+	if n.mux == nil {
+		n.logger.Debug("Transport serves no RPCs, ignoring handler", "kind", kind)
+		return
+	}
+	n.mux.Handle(kind, handler)
+}
+
+// Apply appends cmd to the Raft log and waits until it is applied
+func (n *Node) Apply(cmd []byte, timeout time.Duration) error {
+	return n.raftInstance.Apply(cmd, timeout).Error()
+}
+
+// AddVoter adds the server id at address as a voter, or updates its address
+func (n *Node) AddVoter(id, address string) error {
+	return n.raftInstance.AddVoter(raft.ServerID(id), raft.ServerAddress(address), 0, 0).Error()
+}
+
+// RemoveServer removes the server id from the cluster
+func (n *Node) RemoveServer(id string) error {
+	return n.raftInstance.RemoveServer(raft.ServerID(id), 0, 0).Error()
 }
@@ -0,0 +1,160 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package raft
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb"
+	raftbbolt "github.com/hashicorp/raft-boltdb/v2"
+	"golang.org/x/sys/unix"
+	"vip-switch-go/internal/config"
+)
+
+// Files the storage keeps in the data directory
+const (
+	logFile  = "raft.db"
+	lockFile = "raft.lock"
+)
+
+// Storage holds where a node keeps its log, its stable state and its
+// snapshots
+type Storage struct {
+	Logs      raft.LogStore
+	Stable    raft.StableStore
+	Snapshots raft.SnapshotStore
+
+	closers []io.Closer // released by Close, in order
+}
+
+// NewInmemStorage returns storage kept in memory. It survives a restart of
+// the node using it but not of the process, which suits tests.
+func NewInmemStorage() Storage {
+	store := raft.NewInmemStore()
+	return Storage{
+		Logs:      store,
+		Stable:    store,
+		Snapshots: raft.NewInmemSnapshotStore(),
+	}
+}
+
+// OpenStorage opens the storage backend of cfg in the node's data
+// directory, which it locks against other processes until Close
+func OpenStorage(cfg *config.Config, logger *slog.Logger) (Storage, error) {
+	dir := cfg.Node.DataDir
+	settings := cfg.Storage.WithDefaults()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return Storage{}, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	lock, err := lockDataDir(dir)
+	if err != nil {
+		return Storage{}, err
+	}
+
+	var storage Storage
+	switch settings.Backend {
+	case config.StorageInmem:
+		storage = NewInmemStorage()
+	case config.StorageBolt, config.StorageBBolt:
+		storage, err = openBolt(settings, dir, logger)
+	default:
+		err = fmt.Errorf("unknown storage backend %s", settings.Backend)
+	}
+	if err != nil {
+		lock.Close()
+		return Storage{}, err
+	}
+	storage.closers = append(storage.closers, lock)
+
+	logger.Info("Opened Raft storage", "backend", settings.Backend, "data_dir", dir)
+	return storage, nil
+}
+
+// openBolt opens raft.db with the bolt or bbolt library and the snapshots
+// beside it
+func openBolt(settings config.StorageConfig, dir string, logger *slog.Logger) (Storage, error) {
+	path := filepath.Join(dir, logFile)
+	var (
+		store interface {
+			raft.LogStore
+			raft.StableStore
+			io.Closer
+		}
+		err error
+	)
+	if settings.Backend == config.StorageBBolt {
+		store, err = raftbbolt.NewBoltStore(path)
+	} else {
+		store, err = raftboltdb.NewBoltStore(path)
+	}
+	if err != nil {
+		return Storage{}, fmt.Errorf("failed to open %s store: %w", settings.Backend, err)
+	}
+
+	snapshots, err := raft.NewFileSnapshotStoreWithLogger(dir, settings.RetainSnapshots, NewRaftLogger(logger))
+	if err != nil {
+		store.Close()
+		return Storage{}, fmt.Errorf("failed to create snapshot store: %w", err)
+	}
+
+	return Storage{
+		Logs:      store,
+		Stable:    store,
+		Snapshots: snapshots,
+		closers:   []io.Closer{store},
+	}, nil
+}
+
+// Close releases the files the storage opened and its lock on the data
+// directory
+func (s Storage) Close() error {
+	var errs []error
+	for _, closer := range s.closers {
+		errs = append(errs, closer.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// lockDataDir takes an exclusive lock on the data directory, so that a
+// second daemon fails rather than waits for raft.db. The lock file records
+// the holder's pid for the error message.
+func lockDataDir(dir string) (*os.File, error) {
+	path := filepath.Join(dir, lockFile)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := unix.Flock(int(f.Fd()), unix.LOCK_EX|unix.LOCK_NB); err != nil {
+		defer f.Close()
+		if errors.Is(err, unix.EWOULDBLOCK) {
+			holder, _ := io.ReadAll(f)
+			return nil, fmt.Errorf("data directory %s is in use by another process (pid %s)", dir, strings.TrimSpace(string(holder)))
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+
+	if err := f.Truncate(0); err == nil {
+		f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	return f, nil
+}
@@ -0,0 +1,177 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package raft
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"vip-switch-go/internal/config"
+)
+
+// waitForLeader polls until node leads or timeout passes
+func waitForLeader(t *testing.T, node *Node, timeout time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !node.IsLeader() {
+		if time.Now().After(deadline) {
+			t.Fatalf("node did not become leader within %s", timeout)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestNewNodeWithTransport_InmemStorage(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	cfg := &config.Config{Node: config.NodeConfig{ID: "node1", RaftAddr: "node1"}}
+	storage := NewInmemStorage()
+
+	_, transport := raft.NewInmemTransport("node1")
+	node, err := NewNodeWithTransport(cfg, NewFSM(logger), transport, storage, logger)
+	if err != nil {
+		t.Fatalf("NewNodeWithTransport() unexpected error: %v", err)
+	}
+	if err := node.Start(); err != nil {
+		t.Fatalf("Start() unexpected error: %v", err)
+	}
+	waitForLeader(t, node, 10*time.Second)
+	term := node.Term()
+	node.HandleRPC(0xf1, nil)
+	if err := node.Shutdown(); err != nil {
+		t.Fatalf("Shutdown() unexpected error: %v", err)
+	}
+
+	// A node restarted on the same storage keeps its term and does not
+	// bootstrap again
+	_, transport = raft.NewInmemTransport("node1")
+	node, err = NewNodeWithTransport(cfg, NewFSM(logger), transport, storage, logger)
+	if err != nil {
+		t.Fatalf("NewNodeWithTransport() on used storage unexpected error: %v", err)
+	}
+	defer node.Shutdown()
+	if err := node.Start(); err != nil {
+		t.Fatalf("Start() on used storage unexpected error: %v", err)
+	}
+	if got := node.Term(); got < term {
+		t.Errorf("Term() = %d after restart, want at least %d", got, term)
+	}
+	waitForLeader(t, node, 10*time.Second)
+}
+
+// openTestStorage opens the backend in dir with the given snapshot retention
+func openTestStorage(t *testing.T, backend, dir string, retain int) (Storage, error) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	cfg := &config.Config{
+		Node:    config.NodeConfig{ID: "node1", DataDir: dir},
+		Storage: config.StorageConfig{Backend: backend, RetainSnapshots: retain},
+	}
+	return OpenStorage(cfg, logger)
+}
+
+func TestOpenStorage_Backends(t *testing.T) {
+	tests := []struct {
+		backend string
+		durable bool
+	}{
+		{config.StorageBolt, true},
+		{config.StorageBBolt, true},
+		{config.StorageInmem, false},
+		{"", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.backend, func(t *testing.T) {
+			dir := t.TempDir()
+			storage, err := openTestStorage(t, tt.backend, dir, 1)
+			if err != nil {
+				t.Fatalf("OpenStorage() unexpected error: %v", err)
+			}
+			if err := storage.Stable.SetUint64([]byte("CurrentTerm"), 7); err != nil {
+				t.Fatalf("SetUint64() unexpected error: %v", err)
+			}
+			if err := storage.Logs.StoreLog(&raft.Log{Index: 1, Term: 7, Data: []byte("cmd")}); err != nil {
+				t.Fatalf("StoreLog() unexpected error: %v", err)
+			}
+			if err := storage.Close(); err != nil {
+				t.Fatalf("Close() unexpected error: %v", err)
+			}
+
+			// Only durable backends still know the term after a restart
+			storage, err = openTestStorage(t, tt.backend, dir, 1)
+			if err != nil {
+				t.Fatalf("OpenStorage() after Close() unexpected error: %v", err)
+			}
+			defer storage.Close()
+			term, _ := storage.Stable.GetUint64([]byte("CurrentTerm"))
+			last, _ := storage.Logs.LastIndex()
+			if got := term == 7 && last == 1; got != tt.durable {
+				t.Errorf("term %d, last index %d after reopening, want durable %v", term, last, tt.durable)
+			}
+		})
+	}
+}
+
+func TestOpenStorage_LocksDataDir(t *testing.T) {
+	dir := t.TempDir()
+	storage, err := openTestStorage(t, config.StorageBolt, dir, 1)
+	if err != nil {
+		t.Fatalf("OpenStorage() unexpected error: %v", err)
+	}
+
+	// Any backend needs the lock, inmem included
+	_, err = openTestStorage(t, config.StorageInmem, dir, 1)
+	if err == nil || !strings.Contains(err.Error(), "in use by another process (pid "+strconv.Itoa(os.Getpid())+")") {
+		t.Fatalf("second OpenStorage() error = %v, want the data directory in use by this process", err)
+	}
+
+	storage.Close()
+	storage, err = openTestStorage(t, config.StorageBolt, dir, 1)
+	if err != nil {
+		t.Fatalf("OpenStorage() after Close() unexpected error: %v", err)
+	}
+	storage.Close()
+}
+
+func TestOpenStorage_RetainSnapshots(t *testing.T) {
+	storage, err := openTestStorage(t, config.StorageBolt, t.TempDir(), 2)
+	if err != nil {
+		t.Fatalf("OpenStorage() unexpected error: %v", err)
+	}
+	defer storage.Close()
+
+	for i := uint64(1); i <= 4; i++ {
+		sink, err := storage.Snapshots.Create(raft.SnapshotVersionMax, i*10, 1, raft.Configuration{}, 1, nil)
+		if err != nil {
+			t.Fatalf("Create() unexpected error: %v", err)
+		}
+		sink.Write([]byte("state"))
+		if err := sink.Close(); err != nil {
+			t.Fatalf("Close() unexpected error: %v", err)
+		}
+	}
+	snapshots, err := storage.Snapshots.List()
+	if err != nil {
+		t.Fatalf("List() unexpected error: %v", err)
+	}
+	if len(snapshots) != 2 || snapshots[0].Index != 40 {
+		t.Errorf("List() = %d snapshots, want the 2 latest", len(snapshots))
+	}
+}
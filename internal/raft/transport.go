@@ -26,13 +26,15 @@ import (
 	"github.com/hashicorp/raft"
 )
 
-func NewTCPTransport(addr string, logger *slog.Logger) (raft.Transport, error) {
-	writer := &logWriter{logger: logger}
-	transport, err := raft.NewTCPTransport(addr, nil, 3, 10*time.Second, writer)
+// NewTCPTransport creates a Raft transport listening on addr. The returned
+// mux shares the listener with other RPCs.
+func NewTCPTransport(addr string, logger *slog.Logger) (raft.Transport, *Mux, error) {
+	mux, err := NewMux(addr, logger)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return transport, nil
+	writer := &logWriter{logger: logger}
+	return raft.NewNetworkTransport(mux, 3, 10*time.Second, writer), mux, nil
 }
 
 type logWriter struct {
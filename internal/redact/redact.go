@@ -0,0 +1,157 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redact
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Placeholder replaces secret values
+const Placeholder = "[REDACTED]"
+
+// MinLength is the shortest value hidden. Shorter values would match too
+// much unrelated text to hide usefully.
+const MinLength = 4
+
+// Secrets holds the secret values to hide from logs and hook history
+type Secrets struct {
+	mu       sync.RWMutex
+	values   map[string]struct{}
+	replacer *strings.Replacer // rebuilt when values change
+}
+
+// NewSecrets creates an empty set of secrets
+func NewSecrets() *Secrets {
+	return &Secrets{values: make(map[string]struct{})}
+}
+
+// Add registers a secret value. Surrounding whitespace is ignored, as are
+// values shorter than MinLength.
+func (s *Secrets) Add(value string) {
+	value = strings.TrimSpace(value)
+	if len(value) < MinLength {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.values[value]; ok {
+		return
+	}
+	s.values[value] = struct{}{}
+
+	// Longer values first, so that a secret containing another is hidden
+	// whole
+	values := make([]string, 0, len(s.values))
+	for v := range s.values {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	pairs := make([]string, 0, 2*len(values))
+	for _, v := range values {
+		pairs = append(pairs, v, Placeholder)
+	}
+	s.replacer = strings.NewReplacer(pairs...)
+}
+
+// String replaces every secret in text with Placeholder
+func (s *Secrets) String(text string) string {
+	if s == nil {
+		return text
+	}
+	s.mu.RLock()
+	replacer := s.replacer
+	s.mu.RUnlock()
+	if replacer == nil {
+		return text
+	}
+	return replacer.Replace(text)
+}
+
+// Strings replaces every secret in texts, in place
+func (s *Secrets) Strings(texts []string) {
+	for i, text := range texts {
+		texts[i] = s.String(text)
+	}
+}
+
+// Handler hides secrets in the message and attributes of every record
+// before passing it on
+type Handler struct {
+	next    slog.Handler
+	secrets *Secrets
+}
+
+// NewHandler wraps next so that it never sees secrets
+func NewHandler(next slog.Handler, secrets *Secrets) *Handler {
+	return &Handler{next: next, secrets: secrets}
+}
+
+// Enabled reports whether the wrapped handler handles level
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle redacts the record and passes it on
+func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, h.secrets.String(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(h.attr(attr))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+// WithAttrs redacts attrs and returns a handler adding them
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = h.attr(attr)
+	}
+	return &Handler{next: h.next.WithAttrs(redacted), secrets: h.secrets}
+}
+
+// WithGroup returns a handler nesting attributes in a group
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{next: h.next.WithGroup(name), secrets: h.secrets}
+}
+
+// attr redacts an attribute. Values other than strings and groups are
+// formatted, and replaced by their redacted text if it hides something.
+func (h *Handler) attr(attr slog.Attr) slog.Attr {
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, h.secrets.String(value.String()))
+	case slog.KindGroup:
+		group := value.Group()
+		redacted := make([]any, len(group))
+		for i, a := range group {
+			redacted[i] = h.attr(a)
+		}
+		return slog.Group(attr.Key, redacted...)
+	case slog.KindAny:
+		text := fmt.Sprint(value.Any())
+		if redacted := h.secrets.String(text); redacted != text {
+			return slog.String(attr.Key, redacted)
+		}
+	}
+	return slog.Attr{Key: attr.Key, Value: value}
+}
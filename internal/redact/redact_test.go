@@ -0,0 +1,75 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redact
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSecrets_String(t *testing.T) {
+	secrets := NewSecrets()
+	secrets.Add("s3cr3t-token\n")
+	secrets.Add("s3cr3t-token-extended")
+	secrets.Add("abc")
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Authorization: Bearer s3cr3t-token", "Authorization: Bearer [REDACTED]"},
+		{"token=s3cr3t-token-extended", "token=[REDACTED]"},
+		{"abc is too short to hide", "abc is too short to hide"},
+		{"nothing secret", "nothing secret"},
+	}
+	for _, tt := range tests {
+		if got := secrets.String(tt.text); got != tt.want {
+			t.Errorf("String(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+
+	var empty *Secrets
+	if got := empty.String("s3cr3t-token"); got != "s3cr3t-token" {
+		t.Errorf("nil String() = %q, want the text unchanged", got)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	secrets := NewSecrets()
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(slog.NewTextHandler(&buf, nil), secrets))
+
+	// Secrets added after the logger was created are hidden too
+	secrets.Add("hunter22")
+	logger.With("preset", "pw=hunter22").WithGroup("hook").Info("running with hunter22",
+		"args", []string{"--token", "hunter22"},
+		"error", errors.New("auth failed for hunter22"),
+		"exit_code", 1,
+		slog.Group("env", "TOKEN", "hunter22"),
+	)
+
+	out := buf.String()
+	if strings.Contains(out, "hunter22") {
+		t.Errorf("log output leaks the secret: %s", out)
+	}
+	for _, want := range []string{`msg="running with [REDACTED]"`, `preset="pw=[REDACTED]"`, `hook.args="[--token [REDACTED]]"`, `hook.exit_code=1`, `hook.env.TOKEN=[REDACTED]`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log output = %s, want it to contain %s", out, want)
+		}
+	}
+}
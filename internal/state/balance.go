@@ -0,0 +1,191 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	metrics "github.com/hashicorp/go-metrics/compat"
+	"vip-switch-go/internal/config"
+	"vip-switch-go/internal/track"
+)
+
+// cluster is the part of the Raft node the balancer needs
+type cluster interface {
+	IsLeader() bool
+	Members() []config.ClusterNode
+	TransferLeadershipTo(id, address string) error
+}
+
+// Balancer collects the scores of the other voters over the Raft port.
+// With balancing enabled, the leader hands leadership to the eligible voter
+// with the highest score once it leads the leader's own score by the margin.
+type Balancer struct {
+	nodeID      string
+	config      config.ScoreConfig
+	eligibility *track.Eligibility
+	cluster     cluster
+	fetch       func(ctx context.Context, addr string) (track.Score, error)
+	logger      *slog.Logger
+
+	mu    sync.RWMutex           // guards peers and config.Balance and Margin
+	peers map[string]track.Score // latest score by node ID
+}
+
+// NewBalancer creates a balancer for the node nodeID
+func NewBalancer(nodeID string, cfg config.ScoreConfig, eligibility *track.Eligibility, node cluster, logger *slog.Logger) *Balancer {
+	return &Balancer{
+		nodeID:      nodeID,
+		config:      cfg,
+		eligibility: eligibility,
+		cluster:     node,
+		fetch:       track.FetchScore,
+		logger:      logger,
+		peers:       make(map[string]track.Score),
+	}
+}
+
+// Start compares scores every interval until ctx is done
+func (b *Balancer) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(b.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				b.balance(ctx)
+			}
+		}
+	}()
+}
+
+// SetConfig applies new balancing settings. The interval only changes on
+// restart.
+func (b *Balancer) SetConfig(cfg config.ScoreConfig) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.config.Balance = cfg.Balance
+	b.config.Margin = cfg.Margin
+}
+
+// Scores returns the node's own score followed by the latest scores of the
+// other voters, sorted by node ID
+func (b *Balancer) Scores() []track.Score {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	scores := []track.Score{b.eligibility.Snapshot(b.nodeID)}
+	ids := make([]string, 0, len(b.peers))
+	for id := range b.peers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		scores = append(scores, b.peers[id])
+	}
+	return scores
+}
+
+// balance refreshes the peer scores and, on an eligible leader, transfers
+// leadership to a voter scoring higher by the margin
+func (b *Balancer) balance(ctx context.Context) {
+	members := b.refresh(ctx)
+
+	b.mu.RLock()
+	enabled, margin := b.config.Balance, b.config.Margin
+	b.mu.RUnlock()
+	if !enabled || !b.cluster.IsLeader() || !b.eligibility.Eligible() {
+		return
+	}
+
+	own := b.eligibility.Score()
+	var best *config.ClusterNode
+	var bestScore int
+	b.mu.RLock()
+	for i, member := range members {
+		score, ok := b.peers[member.ID]
+		if !ok || !score.Eligible || score.Score < own+margin {
+			continue
+		}
+		if best == nil || score.Score > bestScore {
+			best, bestScore = &members[i], score.Score
+		}
+	}
+	b.mu.RUnlock()
+	if best == nil {
+		return
+	}
+
+	b.logger.Info("Voter scores higher, transferring leadership",
+		"node_id", best.ID,
+		"score", bestScore,
+		"own_score", own,
+		"margin", margin,
+	)
+	metrics.IncrCounter([]string{"score", "transfers"}, 1)
+	if err := b.cluster.TransferLeadershipTo(best.ID, best.Addr); err != nil {
+		b.logger.Error("Leadership transfer failed", "node_id", best.ID, "error", err)
+	}
+}
+
+// refresh fetches the scores of the other members and returns them
+func (b *Balancer) refresh(ctx context.Context) []config.ClusterNode {
+	var members []config.ClusterNode
+	for _, member := range b.cluster.Members() {
+		if member.ID != b.nodeID {
+			members = append(members, member)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, b.config.Interval/2)
+	defer cancel()
+
+	scores := make(map[string]track.Score, len(members))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, member := range members {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			score, err := b.fetch(ctx, member.Addr)
+			if err != nil {
+				b.logger.Debug("Failed to fetch score", "node_id", member.ID, "error", err)
+				return
+			}
+			if score.NodeID != member.ID {
+				b.logger.Warn("Score from unexpected node", "addr", member.Addr, "node_id", score.NodeID, "want", member.ID)
+				return
+			}
+			metrics.SetGaugeWithLabels([]string{"score", "peer"}, float32(score.Score), []metrics.Label{{Name: "node_id", Value: member.ID}})
+			mu.Lock()
+			scores[member.ID] = score
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	// Unreachable peers drop out rather than keep a stale score
+	b.mu.Lock()
+	b.peers = scores
+	b.mu.Unlock()
+	return members
+}
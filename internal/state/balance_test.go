@@ -0,0 +1,132 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"vip-switch-go/internal/config"
+	"vip-switch-go/internal/track"
+)
+
+// fakeCluster is a three node cluster led by node1
+type fakeCluster struct {
+	leader      bool
+	transferred string
+}
+
+func (c *fakeCluster) IsLeader() bool { return c.leader }
+
+func (c *fakeCluster) Members() []config.ClusterNode {
+	return []config.ClusterNode{
+		{ID: "node1", Addr: "10.0.0.1:7000"},
+		{ID: "node2", Addr: "10.0.0.2:7000"},
+		{ID: "node3", Addr: "10.0.0.3:7000"},
+	}
+}
+
+func (c *fakeCluster) TransferLeadershipTo(id, address string) error {
+	c.transferred = id
+	return nil
+}
+
+func TestBalancer(t *testing.T) {
+	tests := []struct {
+		name    string
+		leader  bool
+		balance bool
+		peers   map[string]track.Score
+		want    string
+	}{
+		{
+			name: "within margin", leader: true, balance: true,
+			peers: map[string]track.Score{"10.0.0.2:7000": {NodeID: "node2", Score: 109, Eligible: true}},
+		},
+		{
+			name: "highest voter above margin", leader: true, balance: true,
+			peers: map[string]track.Score{
+				"10.0.0.2:7000": {NodeID: "node2", Score: 110, Eligible: true},
+				"10.0.0.3:7000": {NodeID: "node3", Score: 120, Eligible: true},
+			},
+			want: "node3",
+		},
+		{
+			name: "ineligible voter", leader: true, balance: true,
+			peers: map[string]track.Score{"10.0.0.2:7000": {NodeID: "node2", Score: 150}},
+		},
+		{
+			name: "follower", leader: false, balance: true,
+			peers: map[string]track.Score{"10.0.0.2:7000": {NodeID: "node2", Score: 150, Eligible: true}},
+		},
+		{
+			name: "balancing disabled", leader: true, balance: false,
+			peers: map[string]track.Score{"10.0.0.2:7000": {NodeID: "node2", Score: 150, Eligible: true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+			cluster := &fakeCluster{leader: tt.leader}
+			cfg := config.ScoreConfig{Priority: 100, Balance: tt.balance, Margin: 10, Interval: time.Second}
+
+			b := NewBalancer("node1", cfg, track.NewEligibility(cfg.Priority, logger), cluster, logger)
+			b.fetch = func(ctx context.Context, addr string) (track.Score, error) {
+				if score, ok := tt.peers[addr]; ok {
+					return score, nil
+				}
+				return track.Score{}, errors.New("unreachable")
+			}
+			b.balance(context.Background())
+
+			if cluster.transferred != tt.want {
+				t.Errorf("transferred leadership to %q, want %q", cluster.transferred, tt.want)
+			}
+			if scores := b.Scores(); len(scores) != len(tt.peers)+1 || scores[0].NodeID != "node1" {
+				t.Errorf("Scores() = %+v, want own score first and %d peers", scores, len(tt.peers))
+			}
+		})
+	}
+}
+
+func TestBalancer_SetConfig(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	cluster := &fakeCluster{leader: true}
+	cfg := config.ScoreConfig{Priority: 100, Margin: 10, Interval: time.Second}
+
+	b := NewBalancer("node1", cfg, track.NewEligibility(cfg.Priority, logger), cluster, logger)
+	b.fetch = func(ctx context.Context, addr string) (track.Score, error) {
+		if addr == "10.0.0.2:7000" {
+			return track.Score{NodeID: "node2", Score: 105, Eligible: true}, nil
+		}
+		return track.Score{}, errors.New("unreachable")
+	}
+
+	b.balance(context.Background())
+	if cluster.transferred != "" {
+		t.Fatalf("transferred leadership to %q with balancing disabled", cluster.transferred)
+	}
+
+	b.SetConfig(config.ScoreConfig{Balance: true, Margin: 5})
+	b.balance(context.Background())
+	if cluster.transferred != "node2" {
+		t.Errorf("transferred leadership to %q, want node2 after enabling balancing", cluster.transferred)
+	}
+}
@@ -16,12 +16,16 @@ package state
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	metrics "github.com/hashicorp/go-metrics/compat"
+	"vip-switch-go/internal/consensus"
 	"vip-switch-go/internal/hook"
-	"vip-switch-go/internal/raft"
+	"vip-switch-go/internal/track"
 )
 
 // State represents the node state
@@ -54,22 +58,25 @@ type Machine struct {
 	currentState    State
 	previousState   State
 	nodeID          string
-	hookSystem      *hook.System
-	raftNode        *raft.Node
+	hooks           *hook.Dispatcher
+	consensus       consensus.Consensus
+	eligibility     *track.Eligibility
 	logger          *slog.Logger
 	mu              sync.RWMutex
 	shutdown        chan struct{}
 	lastStateChange time.Time
 	debounceDelay   time.Duration
+	heartbeat       atomic.Int64 // unix nanoseconds of the last loop iteration
 }
 
-// NewMachine creates a new state machine
-func NewMachine(hookSystem *hook.System, nodeID string, logger *slog.Logger) *Machine {
+// NewMachine creates a new state machine. Hooks for transitions are
+// submitted to hooks and run without holding the machine's lock.
+func NewMachine(hooks *hook.Dispatcher, nodeID string, logger *slog.Logger) *Machine {
 	return &Machine{
 		currentState:    StateReady,
 		previousState:   StateReady,
 		nodeID:          nodeID,
-		hookSystem:      hookSystem,
+		hooks:           hooks,
 		logger:          logger,
 		shutdown:        make(chan struct{}),
 		debounceDelay:   2 * time.Second,
@@ -77,11 +84,20 @@ func NewMachine(hookSystem *hook.System, nodeID string, logger *slog.Logger) *Ma
 	}
 }
 
-// SetRaftNode sets the Raft node reference
-func (m *Machine) SetRaftNode(node *raft.Node) {
+// SetConsensus sets the consensus backend whose leader becomes Master,
+// e.g. a raft.Node
+func (m *Machine) SetConsensus(c consensus.Consensus) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.raftNode = node
+	m.consensus = c
+}
+
+// SetEligibility sets the tracker deciding whether the node may be Master.
+// An ineligible leader transfers leadership instead of becoming Master.
+func (m *Machine) SetEligibility(eligibility *track.Eligibility) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.eligibility = eligibility
 }
 
 // GetCurrentState returns the current state
@@ -91,7 +107,17 @@ func (m *Machine) GetCurrentState() State {
 	return m.currentState
 }
 
-// Start begins monitoring Raft leadership changes
+// Heartbeat returns when the leadership loop last ran, or the zero time if
+// it never did. The loop runs at least once a second while it is healthy.
+func (m *Machine) Heartbeat() time.Time {
+	nanos := m.heartbeat.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+// Start begins monitoring leadership changes
 func (m *Machine) Start(ctx context.Context) error {
 	m.logger.Info("Starting state machine", "state", m.currentState.String())
 
@@ -100,17 +126,25 @@ func (m *Machine) Start(ctx context.Context) error {
 	return nil
 }
 
-// monitorLeadership monitors Raft leadership changes and triggers state transitions
+// monitorLeadership monitors leadership changes and triggers state transitions
 func (m *Machine) monitorLeadership(ctx context.Context) {
-	if m.raftNode == nil {
-		m.logger.Warn("Raft node not set, cannot monitor leadership")
+	if m.consensus == nil {
+		m.logger.Warn("Consensus not set, cannot monitor leadership")
 		return
 	}
 
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
 
+	// A nil channel never fires when eligibility is not tracked
+	var eligibilityChanges <-chan bool
+	if m.eligibility != nil {
+		eligibilityChanges = m.eligibility.Changes()
+	}
+
 	for {
+		m.heartbeat.Store(time.Now().UnixNano())
+
 		select {
 		case <-ctx.Done():
 			m.logger.Info("Stopping state machine")
@@ -118,17 +152,26 @@ func (m *Machine) monitorLeadership(ctx context.Context) {
 		case <-m.shutdown:
 			m.logger.Info("Stopping state machine (shutdown)")
 			return
-		case isLeader := <-m.raftNode.LeaderCh():
+		case isLeader := <-m.consensus.LeaderCh():
 			m.handleLeadershipChange(isLeader, ctx)
 		case <-ticker.C:
-			m.checkRaftState(ctx)
+			m.checkLeadership(ctx)
+		case eligible := <-eligibilityChanges:
+			if !eligible && m.consensus.IsLeader() {
+				m.stepDown()
+			}
 		}
 	}
 }
 
-// checkRaftState periodically checks Raft state
-func (m *Machine) checkRaftState(ctx context.Context) {
-	leader := m.raftNode.Leader()
+// checkLeadership periodically checks the leadership. An ineligible leader
+// retries the transfer on every tick, Master or not, until it succeeds.
+func (m *Machine) checkLeadership(ctx context.Context) {
+	if m.consensus.IsLeader() && !m.eligible() && m.stepDown() {
+		return
+	}
+
+	leader := m.consensus.Leader()
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -136,7 +179,7 @@ func (m *Machine) checkRaftState(ctx context.Context) {
 	var newState State
 	if leader == "" {
 		newState = StateSlave
-	} else if m.raftNode.IsLeader() {
+	} else if m.consensus.IsLeader() {
 		newState = StateMaster
 	} else {
 		newState = StateSlave
@@ -149,6 +192,11 @@ func (m *Machine) checkRaftState(ctx context.Context) {
 
 // handleLeadershipChange handles leadership change events
 func (m *Machine) handleLeadershipChange(isLeader bool, ctx context.Context) {
+	// Losing leadership after the transfer moves the node to Slave
+	if isLeader && !m.eligible() && m.stepDown() {
+		return
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -164,6 +212,26 @@ func (m *Machine) handleLeadershipChange(isLeader bool, ctx context.Context) {
 	}
 }
 
+// eligible reports whether the node may be Master
+func (m *Machine) eligible() bool {
+	return m.eligibility == nil || m.eligibility.Eligible()
+}
+
+// stepDown transfers leadership away from an ineligible node and reports
+// whether it succeeded. A node that cannot hand over, e.g. in a single node
+// cluster, stays leader.
+func (m *Machine) stepDown() bool {
+	m.logger.Warn("Node ineligible to be Master, transferring leadership", "reasons", m.eligibility.Reasons())
+	metrics.IncrCounter([]string{"track", "step_down"}, 1)
+
+	if err := m.consensus.TransferLeadership(); err != nil {
+		m.logger.Error("Leadership transfer failed", "error", err)
+		return false
+	}
+	m.logger.Info("Leadership transferred")
+	return true
+}
+
 // transition performs state transition with debounce
 func (m *Machine) transition(newState State, ctx context.Context) {
 	timeSinceLastChange := time.Since(m.lastStateChange)
@@ -177,61 +245,128 @@ func (m *Machine) transition(newState State, ctx context.Context) {
 		return
 	}
 
-	m.logger.Info("State transition",
-		"from", m.currentState.String(),
-		"to", newState.String(),
-	)
-
-	m.previousState = m.currentState
-	m.currentState = newState
-	m.lastStateChange = time.Now()
-
-	if err := m.executeHookForState(newState, ctx); err != nil {
-		m.logger.Error("Hook execution failed during state transition",
-			"state", newState.String(),
-			"error", err,
-		)
-	}
+	// The hook runs on the dispatcher's worker so that the lock is not held
+	// while it runs; its outcome is only logged
+	result := m.enter(newState, ctx)
+	go func() {
+		err := <-result
+		if err != nil && !errors.Is(err, hook.ErrSuperseded) {
+			m.logger.Error("Hook execution failed during state transition",
+				"state", newState.String(),
+				"error", err,
+			)
+		}
+	}()
 }
 
-// executeHookForState executes the appropriate hook for a state
-func (m *Machine) executeHookForState(state State, ctx context.Context) error {
-	var eventType string
-
+// eventTypeForState returns the hook event for entering a state
+func eventTypeForState(state State) string {
 	switch state {
 	case StateReady:
-		eventType = "ToReady"
+		return "ToReady"
 	case StateMaster:
-		eventType = "ToMaster"
+		return "ToMaster"
 	case StateSlave:
-		eventType = "ToSlave"
+		return "ToSlave"
 	case StateDestroy:
-		eventType = "ToDestroy"
+		return "ToDestroy"
 	default:
-		return nil
+		return ""
 	}
+}
 
-	return m.hookSystem.ExecuteHook(ctx, eventType)
+// submitHook submits the hook for entering a state to the dispatcher
+func (m *Machine) submitHook(from, state State, ctx context.Context) <-chan error {
+	return m.hooks.Submit(ctx, eventTypeForState(state), hook.Transition{
+		PreviousState: from.String(),
+		NewState:      state.String(),
+	})
 }
 
-// Shutdown gracefully shuts down the state machine
-func (m *Machine) Shutdown(ctx context.Context) error {
+// Shutdown gracefully shuts down the state machine. A leader first hands
+// leadership to another node, waiting up to handoff for one to take over,
+// and a Master runs ToSlave to release the VIP. ToDestroy then runs after
+// any pending hooks. The hooks run with ctx, which bounds the whole
+// shutdown.
+func (m *Machine) Shutdown(ctx context.Context, handoff time.Duration) error {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	select {
 	case <-m.shutdown:
+		m.mu.Unlock()
 		return nil
 	default:
 	}
-
 	m.logger.Info("Shutting down state machine", "current_state", m.currentState.String())
-
+	// Stops the leadership loop, so that nothing moves the node back to Master
 	close(m.shutdown)
+	m.mu.Unlock()
 
-	if err := m.executeHookForState(StateDestroy, ctx); err != nil {
-		m.logger.Error("Destroy hook failed", "error", err)
+	// Peers must not hand leadership back while the node goes away
+	if m.eligibility != nil {
+		m.eligibility.SetIneligible("shutdown", "shutting down")
+	}
+	if m.consensus != nil && m.consensus.IsLeader() {
+		handoffCtx, cancel := context.WithTimeout(ctx, handoff)
+		if err := m.handOff(handoffCtx); err != nil {
+			m.logger.Warn("Leadership handoff failed, shutting down as leader", "error", err)
+		}
+		cancel()
 	}
 
+	// ToDestroy would drop a queued ToSlave, so it waits for its outcome
+	m.mu.Lock()
+	if m.currentState == StateMaster {
+		released := m.enter(StateSlave, ctx)
+		m.mu.Unlock()
+		m.wait(ctx, released, "ToSlave")
+		m.mu.Lock()
+	}
+	destroyed := m.enter(StateDestroy, ctx)
+	m.mu.Unlock()
+	m.wait(ctx, destroyed, "ToDestroy")
 	return nil
 }
+
+// enter moves to state without debouncing and submits its hook. The caller
+// must hold the lock.
+func (m *Machine) enter(state State, ctx context.Context) <-chan error {
+	m.logger.Info("State transition", "from", m.currentState.String(), "to", state.String())
+	m.previousState = m.currentState
+	m.currentState = state
+	m.lastStateChange = time.Now()
+	return m.submitHook(m.previousState, state, ctx)
+}
+
+// handOff transfers leadership and waits until another node leads
+func (m *Machine) handOff(ctx context.Context) error {
+	m.logger.Info("Transferring leadership before shutdown")
+	if err := m.consensus.TransferLeadership(); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if leader := m.consensus.LeaderID(); leader != "" && leader != m.nodeID {
+			m.logger.Info("Leadership handed off", "leader", leader)
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// wait waits for the outcome of a shutdown hook, logging a failure
+func (m *Machine) wait(ctx context.Context, result <-chan error, eventType string) {
+	select {
+	case err := <-result:
+		if err != nil {
+			m.logger.Error("Shutdown hook failed", "event_type", eventType, "error", err)
+		}
+	case <-ctx.Done():
+		m.logger.Error("Shutdown hook did not finish", "event_type", eventType, "error", ctx.Err())
+	}
+}
@@ -15,7 +15,18 @@
 package state
 
 import (
+	"context"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
 	"testing"
+	"time"
+
+	"vip-switch-go/internal/config"
+	"vip-switch-go/internal/consensus"
+	"vip-switch-go/internal/hook"
+	"vip-switch-go/internal/track"
 )
 
 func TestState_String(t *testing.T) {
@@ -54,3 +65,212 @@ func TestStateValues(t *testing.T) {
 		t.Errorf("StateDestroy = %v, want 3", StateDestroy)
 	}
 }
+
+func TestMachine_StateQueriesDoNotWaitForHooks(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan string, 4)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dispatcher := hook.NewDispatcher(func(ctx context.Context, eventType string, tr hook.Transition) error {
+		started <- eventType
+		if eventType == "ToMaster" {
+			select {
+			case <-release:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	}, logger)
+	dispatcher.Start()
+	defer dispatcher.Stop()
+
+	m := NewMachine(dispatcher, "node1", logger)
+	ctx := context.Background()
+	dispatcher.Dispatch(ctx, "ToReady", hook.Transition{NewState: "Ready"})
+	<-started
+
+	m.handleLeadershipChange(true, ctx)
+	select {
+	case event := <-started:
+		if event != "ToMaster" {
+			t.Fatalf("started %s, want ToMaster", event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("ToMaster hook did not start")
+	}
+
+	done := make(chan State)
+	go func() { done <- m.GetCurrentState() }()
+	select {
+	case state := <-done:
+		if state != StateMaster {
+			t.Errorf("GetCurrentState() = %v, want Master", state)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("GetCurrentState() blocked while the ToMaster hook ran")
+	}
+}
+
+func TestMachine_HeartbeatWithoutLoop(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	m := NewMachine(nil, "node1", logger)
+
+	// Without consensus the leadership loop never runs
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("Start() unexpected error: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if hb := m.Heartbeat(); !hb.IsZero() {
+		t.Errorf("Heartbeat() = %v, want the zero time", hb)
+	}
+}
+
+// fakeMachine is a state machine driven by a fake consensus, recording the
+// hooks it runs
+type fakeMachine struct {
+	*Machine
+	consensus *consensus.Fake
+
+	mu    sync.Mutex
+	hooks []string
+}
+
+// ran returns the hooks run so far
+func (f *fakeMachine) ran() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.hooks)
+}
+
+// waitForHooks waits until the hooks run so far are want
+func (f *fakeMachine) waitForHooks(t *testing.T, want ...string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !slices.Equal(f.ran(), want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("hooks = %v, want %v", f.ran(), want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// newFakeMachine starts a machine for node1 of a three node cluster, without
+// debounce, once ToReady ran
+func newFakeMachine(t *testing.T, eligibility *track.Eligibility) *fakeMachine {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	f := &fakeMachine{consensus: consensus.NewFake("node1", []config.ClusterNode{
+		{ID: "node1", Addr: "127.0.0.1:10001"},
+		{ID: "node2", Addr: "127.0.0.1:10002"},
+		{ID: "node3", Addr: "127.0.0.1:10003"},
+	})}
+
+	dispatcher := hook.NewDispatcher(func(ctx context.Context, eventType string, tr hook.Transition) error {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.hooks = append(f.hooks, eventType)
+		return nil
+	}, logger)
+	dispatcher.Start()
+	t.Cleanup(dispatcher.Stop)
+
+	f.Machine = NewMachine(dispatcher, "node1", logger)
+	f.debounceDelay = 0
+	f.SetConsensus(f.consensus)
+	if eligibility != nil {
+		f.SetEligibility(eligibility)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	dispatcher.Dispatch(ctx, "ToReady", hook.Transition{NewState: StateReady.String()})
+	f.Start(ctx)
+	return f
+}
+
+func TestMachine_FollowsConsensus(t *testing.T) {
+	m := newFakeMachine(t, nil)
+
+	m.consensus.Elect()
+	m.waitForHooks(t, "ToReady", "ToMaster")
+	if state := m.GetCurrentState(); state != StateMaster {
+		t.Errorf("state = %v after Elect(), want Master", state)
+	}
+
+	m.consensus.Follow("node2")
+	m.waitForHooks(t, "ToReady", "ToMaster", "ToSlave")
+	if state := m.GetCurrentState(); state != StateSlave {
+		t.Errorf("state = %v after Follow(), want Slave", state)
+	}
+}
+
+func TestMachine_IneligibleLeaderStepsDown(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	eligibility := track.NewEligibility(100, logger)
+	eligibility.SetIneligible("link", "eth0 is down")
+	m := newFakeMachine(t, eligibility)
+
+	// Leadership moves on before the node becomes Master
+	m.consensus.Elect()
+	deadline := time.Now().Add(5 * time.Second)
+	for m.consensus.Transfers() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("ineligible leader did not transfer leadership")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	m.waitForHooks(t, "ToReady", "ToSlave")
+	if leader := m.consensus.LeaderID(); leader != "node2" {
+		t.Errorf("leader = %q after the transfer, want node2", leader)
+	}
+}
+
+func TestMachine_IneligibleMasterRetriesStepDown(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	eligibility := track.NewEligibility(100, logger)
+	m := newFakeMachine(t, eligibility)
+	m.consensus.Elect()
+	m.waitForHooks(t, "ToReady", "ToMaster")
+
+	// Without another voter the first transfer fails and the node keeps
+	// leading
+	for _, id := range []string{"node2", "node3"} {
+		if err := m.consensus.RemoveServer(id); err != nil {
+			t.Fatalf("RemoveServer(%s) unexpected error: %v", id, err)
+		}
+	}
+	eligibility.SetIneligible("link", "eth0 is down")
+	time.Sleep(1500 * time.Millisecond)
+	if !m.consensus.IsLeader() || m.consensus.Transfers() != 0 {
+		t.Fatalf("leader = %q, transfers = %d, want node1 still leading", m.consensus.LeaderID(), m.consensus.Transfers())
+	}
+
+	// A later tick hands over once a voter is back
+	if err := m.consensus.AddVoter("node2", "127.0.0.1:10002"); err != nil {
+		t.Fatalf("AddVoter() unexpected error: %v", err)
+	}
+	m.waitForHooks(t, "ToReady", "ToMaster", "ToSlave")
+	if leader := m.consensus.LeaderID(); leader != "node2" {
+		t.Errorf("leader = %q after the retry, want node2", leader)
+	}
+}
+
+func TestMachine_ShutdownWithFakeConsensus(t *testing.T) {
+	m := newFakeMachine(t, nil)
+	m.consensus.Elect()
+	m.waitForHooks(t, "ToReady", "ToMaster")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.Shutdown(ctx, time.Second); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if want := []string{"ToReady", "ToMaster", "ToSlave", "ToDestroy"}; !slices.Equal(m.ran(), want) {
+		t.Errorf("hooks = %v, want %v", m.ran(), want)
+	}
+	if m.consensus.IsLeader() {
+		t.Error("node still leads after Shutdown()")
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	metrics "github.com/hashicorp/go-metrics/compat"
+	"vip-switch-go/internal/hook"
+	"vip-switch-go/internal/vip"
+)
+
+// DriftCheck reports whether the VIP has drifted from what state requires
+type DriftCheck func(ctx context.Context, state State) (bool, error)
+
+// namedCheck is a drift check with the name it is reported under
+type namedCheck struct {
+	name  string
+	check DriftCheck
+}
+
+// Reconciler periodically checks the VIP against the node's role and
+// re-runs the role hook when it has drifted, e.g. when the address was
+// removed by hand on the Master
+type Reconciler struct {
+	machine  *Machine
+	interval time.Duration
+	checks   []namedCheck
+	logger   *slog.Logger
+}
+
+// NewReconciler creates a reconciler for machine that checks every interval
+func NewReconciler(machine *Machine, interval time.Duration, logger *slog.Logger) *Reconciler {
+	return &Reconciler{
+		machine:  machine,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// AddCheck adds a drift check. Checks run in the order they were added
+// until one reports drift.
+func (r *Reconciler) AddCheck(name string, check DriftCheck) {
+	r.checks = append(r.checks, namedCheck{name: name, check: check})
+}
+
+// Start runs the checks every interval until ctx is done or the machine
+// shuts down
+func (r *Reconciler) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-r.machine.shutdown:
+				return
+			case <-ticker.C:
+				r.reconcile(ctx)
+			}
+		}
+	}()
+}
+
+// reconcile runs the checks once and repairs the first drift found
+func (r *Reconciler) reconcile(ctx context.Context) {
+	state := r.machine.GetCurrentState()
+	if state != StateMaster && state != StateSlave {
+		return
+	}
+	// A pending hook is about to change the VIP anyway
+	if r.machine.hooks.Busy() {
+		return
+	}
+
+	for _, c := range r.checks {
+		drift, err := c.check(ctx, state)
+		if err != nil {
+			r.logger.Warn("VIP check failed", "check", c.name, "state", state.String(), "error", err)
+			continue
+		}
+		if !drift {
+			continue
+		}
+		if r.machine.GetCurrentState() != state {
+			// The role changed while the check ran
+			return
+		}
+
+		r.logger.Warn("VIP drift detected", "check", c.name, "state", state.String())
+		metrics.IncrCounterWithLabels([]string{"vip", "drift"}, 1, []metrics.Label{
+			{Name: "state", Value: state.String()},
+			{Name: "check", Value: c.name},
+		})
+		r.machine.reassert(ctx, state)
+		return
+	}
+}
+
+// reassert re-runs the hook for entering state and waits for it
+func (m *Machine) reassert(ctx context.Context, state State) {
+	result, ok := m.hooks.Reassert(ctx, eventTypeForState(state), hook.Transition{
+		PreviousState: state.String(),
+		NewState:      state.String(),
+	})
+	if !ok {
+		m.logger.Debug("Skipping drift repair while hooks are pending", "state", state.String())
+		return
+	}
+
+	select {
+	case err := <-result:
+		switch {
+		case err == nil:
+			m.logger.Info("Repaired VIP drift", "state", state.String())
+		case !errors.Is(err, hook.ErrSuperseded):
+			m.logger.Error("Hook execution failed while repairing VIP drift", "state", state.String(), "error", err)
+		}
+	case <-ctx.Done():
+	}
+}
+
+// VIPCheck checks directly whether the VIP is assigned to iface: it must be
+// present on the Master and absent on a Slave
+func VIPCheck(iface, address string) DriftCheck {
+	return func(_ context.Context, state State) (bool, error) {
+		present, err := vip.Present(iface, address)
+		if err != nil {
+			return false, err
+		}
+		return present != (state == StateMaster), nil
+	}
+}
+
+// HookChecker runs ensure hooks, see hook.System.Check
+type HookChecker interface {
+	Check(ctx context.Context, eventType string, tr hook.Transition) (bool, error)
+}
+
+// HookCheck checks with the EnsureMaster and EnsureSlave hooks
+func HookCheck(hooks HookChecker) DriftCheck {
+	return func(ctx context.Context, state State) (bool, error) {
+		return hooks.Check(ctx, "Ensure"+state.String(), hook.Transition{
+			PreviousState: state.String(),
+			NewState:      state.String(),
+		})
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"vip-switch-go/internal/hook"
+)
+
+// newSlaveMachine returns a machine whose ToReady and ToSlave hooks have
+// run, and the list of hooks run so far
+func newSlaveMachine(t *testing.T) (*Machine, func() string) {
+	t.Helper()
+	var mu sync.Mutex
+	var ran []string
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dispatcher := hook.NewDispatcher(func(ctx context.Context, eventType string, tr hook.Transition) error {
+		mu.Lock()
+		defer mu.Unlock()
+		ran = append(ran, eventType+":"+tr.PreviousState+"->"+tr.NewState)
+		return nil
+	}, logger)
+	dispatcher.Start()
+	t.Cleanup(dispatcher.Stop)
+
+	m := NewMachine(dispatcher, "node1", logger)
+	ctx := context.Background()
+	dispatcher.Dispatch(ctx, "ToReady", hook.Transition{NewState: "Ready"})
+	m.handleLeadershipChange(false, ctx)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for dispatcher.Busy() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return m, func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return strings.Join(ran, " ")
+	}
+}
+
+func TestReconciler_RepairsDrift(t *testing.T) {
+	m, ran := newSlaveMachine(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	r := NewReconciler(m, time.Hour, logger)
+	r.AddCheck("test", func(ctx context.Context, state State) (bool, error) {
+		return state == StateSlave, nil
+	})
+	r.reconcile(context.Background())
+
+	if got, want := ran(), "ToReady:->Ready ToSlave:Ready->Slave ToSlave:Slave->Slave"; got != want {
+		t.Errorf("ran %q, want %q", got, want)
+	}
+}
+
+func TestReconciler_NoDrift(t *testing.T) {
+	m, ran := newSlaveMachine(t)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	r := NewReconciler(m, time.Hour, logger)
+	r.AddCheck("test", func(ctx context.Context, state State) (bool, error) {
+		return false, nil
+	})
+	r.reconcile(context.Background())
+
+	if got, want := ran(), "ToReady:->Ready ToSlave:Ready->Slave"; got != want {
+		t.Errorf("ran %q, want %q", got, want)
+	}
+}
+
+func TestVIPCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		state   State
+		want    bool
+	}{
+		{"master with vip", "127.0.0.1/32", StateMaster, false},
+		{"master without vip", "192.0.2.10/32", StateMaster, true},
+		{"slave with vip", "127.0.0.1/32", StateSlave, true},
+		{"slave without vip", "192.0.2.10/32", StateSlave, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drift, err := VIPCheck("lo", tt.address)(context.Background(), tt.state)
+			if err != nil {
+				t.Fatalf("VIPCheck() unexpected error: %v", err)
+			}
+			if drift != tt.want {
+				t.Errorf("VIPCheck() = %v, want %v", drift, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	hraft "github.com/hashicorp/raft"
+	"vip-switch-go/internal/config"
+	"vip-switch-go/internal/hook"
+	"vip-switch-go/internal/raft"
+)
+
+// shutdownTestNode is a node of the shutdown test cluster, recording the
+// hooks it ran
+type shutdownTestNode struct {
+	id      string
+	raft    *raft.Node
+	machine *Machine
+
+	mu    sync.Mutex
+	hooks []string
+}
+
+// ran returns the hooks the node ran, in order
+func (n *shutdownTestNode) ran() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return slices.Clone(n.hooks)
+}
+
+// startShutdownTestCluster starts a Raft cluster of size nodes on in-memory
+// transports and storage, each with a state machine, and waits for ToReady
+// on all of them
+func startShutdownTestCluster(t *testing.T, size int) []*shutdownTestNode {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	var members []config.ClusterNode
+	transports := make(map[string]*hraft.InmemTransport)
+	for i := 1; i <= size; i++ {
+		id := fmt.Sprintf("node%d", i)
+		members = append(members, config.ClusterNode{ID: id, Addr: id})
+		_, transports[id] = hraft.NewInmemTransport(hraft.ServerAddress(id))
+	}
+	for from, transport := range transports {
+		for to, peer := range transports {
+			if from != to {
+				transport.Connect(hraft.ServerAddress(to), peer)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	var nodes []*shutdownTestNode
+	for _, member := range members {
+		cfg := &config.Config{
+			Node:    config.NodeConfig{ID: member.ID, RaftAddr: member.Addr},
+			Cluster: config.ClusterConfig{Nodes: members},
+		}
+		node := &shutdownTestNode{id: member.ID}
+
+		storage := raft.NewInmemStorage()
+		if err := bootstrapVoters(member.ID, storage, transports[member.ID], members); err != nil {
+			t.Fatalf("bootstrapVoters(%s) error = %v", member.ID, err)
+		}
+		raftNode, err := raft.NewNodeWithTransport(cfg, raft.NewFSM(logger), transports[member.ID], storage, logger)
+		if err != nil {
+			t.Fatalf("NewNodeWithTransport(%s) error = %v", member.ID, err)
+		}
+		t.Cleanup(func() { raftNode.Shutdown() })
+		node.raft = raftNode
+
+		dispatcher := hook.NewDispatcher(func(ctx context.Context, eventType string, tr hook.Transition) error {
+			node.mu.Lock()
+			node.hooks = append(node.hooks, eventType)
+			node.mu.Unlock()
+			return nil
+		}, logger)
+		dispatcher.Start()
+		t.Cleanup(dispatcher.Stop)
+
+		node.machine = NewMachine(dispatcher, member.ID, logger)
+		node.machine.SetConsensus(raftNode)
+		if err := raftNode.Start(); err != nil {
+			t.Fatalf("Start(%s) error = %v", member.ID, err)
+		}
+		if err := dispatcher.Dispatch(ctx, "ToReady", hook.Transition{NewState: StateReady.String()}); err != nil {
+			t.Fatalf("ToReady on %s error = %v", member.ID, err)
+		}
+		node.machine.Start(ctx)
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// bootstrapVoters seeds storage with all members as voters before the node
+// starts, so that the nodes form one cluster rather than each bootstrapping
+// its own
+func bootstrapVoters(id string, storage raft.Storage, transport hraft.Transport, members []config.ClusterNode) error {
+	conf := hraft.DefaultConfig()
+	conf.LocalID = hraft.ServerID(id)
+	var configuration hraft.Configuration
+	for _, member := range members {
+		configuration.Servers = append(configuration.Servers, hraft.Server{
+			ID:      hraft.ServerID(member.ID),
+			Address: hraft.ServerAddress(member.Addr),
+		})
+	}
+	err := hraft.BootstrapCluster(conf, storage.Logs, storage.Stable, storage.Snapshots, transport, configuration)
+	if errors.Is(err, hraft.ErrCantBootstrap) {
+		return nil
+	}
+	return err
+}
+
+// waitFor polls cond until it holds or timeout passes
+func waitFor(t *testing.T, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// master returns the only node in the Master state, or nil
+func master(nodes []*shutdownTestNode) *shutdownTestNode {
+	var found *shutdownTestNode
+	for _, node := range nodes {
+		if node.machine.GetCurrentState() == StateMaster {
+			if found != nil {
+				return nil
+			}
+			found = node
+		}
+	}
+	return found
+}
+
+func TestMachine_ShutdownHandsOffLeadership(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts a Raft cluster")
+	}
+	nodes := startShutdownTestCluster(t, 3)
+
+	var old *shutdownTestNode
+	waitFor(t, 15*time.Second, "a single Master", func() bool {
+		old = master(nodes)
+		return old != nil && old.raft.IsLeader()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	if err := old.machine.Shutdown(ctx, 10*time.Second); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	// The old Master no longer leads by the time its hooks ran
+	if old.raft.IsLeader() {
+		t.Error("old Master is still the Raft leader after Shutdown()")
+	}
+	if got := old.ran(); len(got) < 2 || !slices.Equal(got[len(got)-2:], []string{"ToSlave", "ToDestroy"}) {
+		t.Errorf("old Master ran %v, want ToSlave then ToDestroy last", got)
+	}
+	if state := old.machine.GetCurrentState(); state != StateDestroy {
+		t.Errorf("old Master state = %v, want Destroy", state)
+	}
+
+	rest := slices.DeleteFunc(slices.Clone(nodes), func(n *shutdownTestNode) bool { return n == old })
+	waitFor(t, 15*time.Second, "a new Master", func() bool {
+		next := master(rest)
+		return next != nil && next.raft.IsLeader()
+	})
+
+	// A second call is a no-op
+	before := len(old.ran())
+	old.machine.Shutdown(ctx, time.Second)
+	if after := len(old.ran()); after != before {
+		t.Errorf("second Shutdown() ran %d more hooks, want none", after-before)
+	}
+}
+
+func TestMachine_ShutdownAsSlave(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts a Raft cluster")
+	}
+	nodes := startShutdownTestCluster(t, 3)
+
+	waitFor(t, 15*time.Second, "a single Master", func() bool { return master(nodes) != nil })
+	var slave *shutdownTestNode
+	waitFor(t, 10*time.Second, "a Slave", func() bool {
+		for _, node := range nodes {
+			if node.machine.GetCurrentState() == StateSlave {
+				slave = node
+				return true
+			}
+		}
+		return false
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	slave.machine.Shutdown(ctx, time.Second)
+
+	// A Slave has no VIP to release, so ToSlave does not run again
+	if got, want := slave.ran(), []string{"ToReady", "ToSlave", "ToDestroy"}; !slices.Equal(got, want) {
+		t.Errorf("Slave ran %v, want %v", got, want)
+	}
+}
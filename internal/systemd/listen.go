@@ -0,0 +1,67 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package systemd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// listenFDsStart is the first file descriptor passed by socket activation
+const listenFDsStart = 3
+
+// Listeners returns the sockets passed by systemd socket activation, in the
+// order of the ListenStream= lines of the socket unit, or none when the
+// daemon was not socket activated. The LISTEN_* variables are cleared so
+// that hooks do not see them.
+func Listeners() ([]net.Listener, error) {
+	return listeners(listenFDsStart)
+}
+
+// listeners returns the sockets passed from file descriptor start on
+func listeners(start int) ([]net.Listener, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	if os.Getenv("LISTEN_PID") != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, nil
+	}
+
+	result := make([]net.Listener, 0, count)
+	for fd := start; fd < start+count; fd++ {
+		syscall.CloseOnExec(fd)
+		file := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+		listener, err := net.FileListener(file)
+		file.Close()
+		if err != nil {
+			for _, l := range result {
+				l.Close()
+			}
+			return nil, fmt.Errorf("socket activation: file descriptor %d: %w", fd, err)
+		}
+		result = append(result, listener)
+	}
+	return result, nil
+}
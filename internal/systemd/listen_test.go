@@ -0,0 +1,56 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package systemd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestListeners(t *testing.T) {
+	listener, err := net.Listen("unix", filepath.Join(t.TempDir(), "admin.sock"))
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer listener.Close()
+	file, err := listener.(*net.UnixListener).File()
+	if err != nil {
+		t.Fatalf("File() error = %v", err)
+	}
+	defer file.Close()
+
+	t.Setenv("LISTEN_PID", "1")
+	t.Setenv("LISTEN_FDS", "1")
+	if got, err := listeners(int(file.Fd())); err != nil || got != nil {
+		t.Errorf("listeners() for another process = %v, %v, want none", got, err)
+	}
+
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	t.Setenv("LISTEN_FDS", "1")
+	got, err := listeners(int(file.Fd()))
+	if err != nil || len(got) != 1 {
+		t.Fatalf("listeners() = %v, %v, want one listener", got, err)
+	}
+	defer got[0].Close()
+	if got[0].Addr().String() != listener.Addr().String() {
+		t.Errorf("listener address = %s, want %s", got[0].Addr(), listener.Addr())
+	}
+	if _, ok := os.LookupEnv("LISTEN_FDS"); ok {
+		t.Error("listeners() kept LISTEN_FDS in the environment")
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Notification states understood by systemd
+const (
+	Ready    = "READY=1"
+	Stopping = "STOPPING=1"
+	Watchdog = "WATCHDOG=1"
+)
+
+// Status returns the notification setting the status line systemctl shows
+func Status(text string) string {
+	return "STATUS=" + text
+}
+
+// Notifier sends notifications to the service manager over the datagram
+// socket named by NOTIFY_SOCKET. A nil Notifier discards them, so callers
+// need not check whether the daemon runs under systemd.
+type Notifier struct {
+	addr   *net.UnixAddr
+	logger *slog.Logger
+}
+
+// NewNotifier returns a notifier for NOTIFY_SOCKET, or nil when it is not
+// set, e.g. when the service is not of Type=notify
+func NewNotifier(logger *slog.Logger) *Notifier {
+	name := os.Getenv("NOTIFY_SOCKET")
+	if name == "" {
+		return nil
+	}
+	// A leading @ names a socket in the abstract namespace
+	if strings.HasPrefix(name, "@") {
+		name = "\x00" + name[1:]
+	}
+	return &Notifier{addr: &net.UnixAddr{Name: name, Net: "unixgram"}, logger: logger}
+}
+
+// Notify sends states, e.g. Ready and Status("Master"), in one datagram
+func (n *Notifier) Notify(states ...string) error {
+	if n == nil {
+		return nil
+	}
+	conn, err := net.DialUnix("unixgram", nil, n.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to notify socket: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(strings.Join(states, "\n"))); err != nil {
+		return fmt.Errorf("failed to notify: %w", err)
+	}
+	return nil
+}
+
+// WatchdogInterval returns the interval within which systemd expects a
+// Watchdog notification, or 0 when the watchdog is not enabled for this
+// process
+func WatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// RunWatchdog sends a Watchdog notification every half interval while alive
+// reports true, until ctx is done. Pings stop while alive reports false, so
+// that systemd restarts a daemon whose main loop is stuck.
+func (n *Notifier) RunWatchdog(ctx context.Context, interval time.Duration, alive func() bool) {
+	if n == nil || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !alive() {
+				n.logger.Warn("State machine loop is not responding, withholding watchdog ping")
+				continue
+			}
+			if err := n.Notify(Watchdog); err != nil {
+				n.logger.Warn("Failed to ping watchdog", "error", err)
+			}
+		}
+	}
+}
+
+// RunStatus polls status every interval until ctx is done, sending it to
+// systemd whenever it changes
+func (n *Notifier) RunStatus(ctx context.Context, interval time.Duration, status func() string) {
+	if n == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := ""
+	for {
+		if text := status(); text != last {
+			if err := n.Notify(Status(text)); err != nil {
+				n.logger.Warn("Failed to send status", "error", err)
+			} else {
+				last = text
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// listenNotify starts a unixgram socket standing in for systemd and points
+// NOTIFY_SOCKET at it. A name starting with @ is in the abstract namespace.
+func listenNotify(t *testing.T, name string) *net.UnixConn {
+	t.Helper()
+	addr := name
+	if strings.HasPrefix(name, "@") {
+		addr = "\x00" + name[1:]
+	}
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("ListenUnixgram() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", name)
+	return conn
+}
+
+// receive returns the next datagram sent to conn
+func receive(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("failed to receive notification: %v", err)
+	}
+	return string(buf[:n])
+}
+
+func TestNotifier_Notify(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	for _, name := range []string{
+		filepath.Join(t.TempDir(), "notify.sock"),
+		fmt.Sprintf("@vip-switch-test-%d", os.Getpid()),
+	} {
+		conn := listenNotify(t, name)
+
+		notifier := NewNotifier(logger)
+		if err := notifier.Notify(Ready, Status("Slave, leader node2")); err != nil {
+			t.Fatalf("Notify() error = %v", err)
+		}
+		if got, want := receive(t, conn), "READY=1\nSTATUS=Slave, leader node2"; got != want {
+			t.Errorf("notification = %q, want %q", got, want)
+		}
+	}
+
+	t.Setenv("NOTIFY_SOCKET", "")
+	notifier := NewNotifier(logger)
+	if notifier != nil {
+		t.Fatalf("NewNotifier() without NOTIFY_SOCKET = %v, want nil", notifier)
+	}
+	if err := notifier.Notify(Ready); err != nil {
+		t.Errorf("nil Notify() error = %v, want nil", err)
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	tests := []struct {
+		usec string
+		pid  string
+		want time.Duration
+	}{
+		{"", "", 0},
+		{"30000000", "", 30 * time.Second},
+		{"500000", strconv.Itoa(os.Getpid()), 500 * time.Millisecond},
+		{"500000", "1", 0},
+		{"-1", "", 0},
+	}
+	for _, tt := range tests {
+		t.Setenv("WATCHDOG_USEC", tt.usec)
+		t.Setenv("WATCHDOG_PID", tt.pid)
+		if got := WatchdogInterval(); got != tt.want {
+			t.Errorf("WatchdogInterval() with WATCHDOG_USEC=%s WATCHDOG_PID=%s = %v, want %v", tt.usec, tt.pid, got, tt.want)
+		}
+	}
+}
+
+func TestNotifier_RunWatchdog(t *testing.T) {
+	conn := listenNotify(t, filepath.Join(t.TempDir(), "notify.sock"))
+	notifier := NewNotifier(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+
+	var alive atomic.Bool
+	alive.Store(true)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go notifier.RunWatchdog(ctx, 40*time.Millisecond, alive.Load)
+
+	if got := receive(t, conn); got != Watchdog {
+		t.Errorf("notification = %q, want %q", got, Watchdog)
+	}
+
+	// A stuck loop stops the pings
+	alive.Store(false)
+	time.Sleep(30 * time.Millisecond)
+	for {
+		conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		if _, err := conn.Read(make([]byte, 64)); err != nil {
+			break
+		}
+	}
+
+	alive.Store(true)
+	if got := receive(t, conn); got != Watchdog {
+		t.Errorf("notification after recovery = %q, want %q", got, Watchdog)
+	}
+}
+
+func TestNotifier_RunStatus(t *testing.T) {
+	conn := listenNotify(t, filepath.Join(t.TempDir(), "notify.sock"))
+	notifier := NewNotifier(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+
+	var state atomic.Value
+	state.Store("Ready")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go notifier.RunStatus(ctx, 10*time.Millisecond, func() string { return state.Load().(string) })
+
+	if got := receive(t, conn); got != "STATUS=Ready" {
+		t.Errorf("notification = %q, want STATUS=Ready", got)
+	}
+	state.Store("Master, leader node1")
+	if got := receive(t, conn); got != "STATUS=Master, leader node1" {
+		t.Errorf("notification = %q, want the new status only once it changed", got)
+	}
+}
@@ -0,0 +1,379 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testcluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	hraft "github.com/hashicorp/raft"
+	"vip-switch-go/internal/config"
+	"vip-switch-go/internal/hook"
+	"vip-switch-go/internal/raft"
+	"vip-switch-go/internal/state"
+)
+
+// pollInterval is how often waits and assertions look at the cluster
+const pollInterval = 20 * time.Millisecond
+
+// Node is a member of a test cluster. Its Raft log survives Kill and
+// Restart, its hook recorder too.
+type Node struct {
+	ID    string
+	Hooks *Hooks
+
+	storage raft.Storage
+
+	mu         sync.Mutex
+	alive      bool
+	transport  *hraft.InmemTransport
+	raftNode   *raft.Node
+	dispatcher *hook.Dispatcher
+	machine    *state.Machine
+	cancel     context.CancelFunc
+}
+
+// Alive reports whether the node runs, that is it was not killed
+func (n *Node) Alive() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.alive
+}
+
+// State returns the state of the node's state machine. A killed node keeps
+// the state it had when it was killed.
+func (n *Node) State() state.State {
+	return n.Machine().GetCurrentState()
+}
+
+// Machine returns the node's current state machine
+func (n *Node) Machine() *state.Machine {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.machine
+}
+
+// Raft returns the node's current Raft node
+func (n *Node) Raft() *raft.Node {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.raftNode
+}
+
+// Cluster is a Raft cluster running in the test process. Its nodes talk
+// over in-memory transports, keep their log in memory and record hooks
+// instead of running them.
+type Cluster struct {
+	t       testing.TB
+	logger  *slog.Logger
+	members []config.ClusterNode
+	nodes   []*Node
+
+	mu       sync.Mutex
+	isolated map[string]bool // nodes cut off from the others by Partition
+}
+
+// New starts a cluster of size nodes named node1, node2 and so on and
+// waits until each ran ToReady. The nodes are killed when the test ends.
+func New(t testing.TB, size int) *Cluster {
+	t.Helper()
+	c := &Cluster{
+		t:        t,
+		logger:   slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelWarn})),
+		isolated: make(map[string]bool),
+	}
+	for i := 1; i <= size; i++ {
+		id := fmt.Sprintf("node%d", i)
+		c.members = append(c.members, config.ClusterNode{ID: id, Addr: id})
+		c.nodes = append(c.nodes, &Node{ID: id, Hooks: NewHooks(), storage: raft.NewInmemStorage()})
+	}
+	t.Cleanup(func() {
+		for _, node := range c.nodes {
+			if node.Alive() {
+				c.kill(node)
+			}
+		}
+	})
+
+	for _, node := range c.nodes {
+		c.start(node)
+	}
+	return c
+}
+
+// Nodes returns all nodes, killed ones included
+func (c *Cluster) Nodes() []*Node {
+	return c.nodes
+}
+
+// Node returns the node with the given ID
+func (c *Cluster) Node(id string) *Node {
+	c.t.Helper()
+	for _, node := range c.nodes {
+		if node.ID == id {
+			return node
+		}
+	}
+	c.t.Fatalf("no node %s in the cluster", id)
+	return nil
+}
+
+// Kill stops a node the way a crash would: without handing off leadership
+// and without running ToSlave or ToDestroy
+func (c *Cluster) Kill(id string) {
+	c.t.Helper()
+	node := c.Node(id)
+	if !node.Alive() {
+		c.t.Fatalf("Kill(%s): node is not running", id)
+	}
+	c.kill(node)
+}
+
+// Restart starts a killed node again on the Raft log it had
+func (c *Cluster) Restart(id string) {
+	c.t.Helper()
+	node := c.Node(id)
+	if node.Alive() {
+		c.t.Fatalf("Restart(%s): node is running", id)
+	}
+	c.start(node)
+}
+
+// Partition cuts the given nodes off from the rest of the cluster. They
+// still reach each other. A later Partition replaces the earlier one.
+func (c *Cluster) Partition(ids ...string) {
+	c.t.Helper()
+	isolated := make(map[string]bool)
+	for _, id := range ids {
+		isolated[c.Node(id).ID] = true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.isolated = isolated
+	c.rewire()
+}
+
+// Heal undoes Partition so that every running node reaches every other
+func (c *Cluster) Heal() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.isolated = make(map[string]bool)
+	c.rewire()
+}
+
+// Masters returns the running nodes in the Master state
+func (c *Cluster) Masters() []*Node {
+	var masters []*Node
+	for _, node := range c.nodes {
+		if node.Alive() && node.State() == state.StateMaster {
+			masters = append(masters, node)
+		}
+	}
+	return masters
+}
+
+// WaitFor polls cond until it holds, failing the test after timeout
+func (c *Cluster) WaitFor(timeout time.Duration, what string, cond func() bool) {
+	c.t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			c.t.Fatalf("timed out after %s waiting for %s", timeout, what)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// WaitForMaster waits until exactly one running node is Master and leads
+// Raft, and returns it
+func (c *Cluster) WaitForMaster(timeout time.Duration) *Node {
+	c.t.Helper()
+	var master *Node
+	c.WaitFor(timeout, "a single Master", func() bool {
+		masters := c.Masters()
+		if len(masters) != 1 || !masters[0].Raft().IsLeader() {
+			return false
+		}
+		master = masters[0]
+		return true
+	})
+	return master
+}
+
+// AssertOneMaster checks that exactly one running node is Master, and
+// always the same one, throughout d
+func (c *Cluster) AssertOneMaster(d time.Duration) {
+	c.t.Helper()
+	var first *Node
+	c.assertMasters(d, func(masters []*Node) string {
+		if len(masters) != 1 {
+			return fmt.Sprintf("%d Masters, want exactly one", len(masters))
+		}
+		if first == nil {
+			first = masters[0]
+		}
+		if masters[0] != first {
+			return fmt.Sprintf("Master moved from %s to %s", first.ID, masters[0].ID)
+		}
+		return ""
+	})
+}
+
+// AssertAtMostOneMaster checks that no two running nodes are Master at the
+// same time throughout d
+func (c *Cluster) AssertAtMostOneMaster(d time.Duration) {
+	c.t.Helper()
+	c.assertMasters(d, func(masters []*Node) string {
+		if len(masters) > 1 {
+			return fmt.Sprintf("%d Masters, want at most one", len(masters))
+		}
+		return ""
+	})
+}
+
+// assertMasters polls the Masters for d, failing the test at the first
+// violation check reports
+func (c *Cluster) assertMasters(d time.Duration, check func([]*Node) string) {
+	c.t.Helper()
+	start := time.Now()
+	for time.Since(start) < d {
+		masters := c.Masters()
+		if violation := check(masters); violation != "" {
+			ids := make([]string, 0, len(masters))
+			for _, master := range masters {
+				ids = append(ids, master.ID)
+			}
+			c.t.Fatalf("after %s: %s (Masters %v)", time.Since(start).Round(time.Millisecond), violation, ids)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// start runs a node: Raft on a fresh transport, a dispatcher recording
+// into the node's hooks and a state machine, once ToReady ran
+func (c *Cluster) start(node *Node) {
+	c.t.Helper()
+	logger := c.logger.With("node", node.ID)
+	cfg := &config.Config{
+		Node:    config.NodeConfig{ID: node.ID, RaftAddr: node.ID},
+		Cluster: config.ClusterConfig{Nodes: c.members},
+	}
+
+	_, transport := hraft.NewInmemTransport(hraft.ServerAddress(node.ID))
+	if err := bootstrap(node.ID, node.storage, transport, c.members); err != nil {
+		c.t.Fatalf("bootstrap(%s) error = %v", node.ID, err)
+	}
+	raftNode, err := raft.NewNodeWithTransport(cfg, raft.NewFSM(logger), transport, node.storage, logger)
+	if err != nil {
+		c.t.Fatalf("NewNodeWithTransport(%s) error = %v", node.ID, err)
+	}
+	dispatcher := hook.NewDispatcher(node.Hooks.Run, logger)
+	dispatcher.Start()
+	machine := state.NewMachine(dispatcher, node.ID, logger)
+	machine.SetConsensus(raftNode)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	node.mu.Lock()
+	node.alive = true
+	node.transport = transport
+	node.raftNode = raftNode
+	node.dispatcher = dispatcher
+	node.machine = machine
+	node.cancel = cancel
+	node.mu.Unlock()
+
+	c.mu.Lock()
+	c.rewire()
+	c.mu.Unlock()
+
+	if err := raftNode.Start(); err != nil {
+		c.t.Fatalf("Start(%s) error = %v", node.ID, err)
+	}
+	if err := dispatcher.Dispatch(ctx, "ToReady", hook.Transition{NewState: state.StateReady.String()}); err != nil {
+		c.t.Fatalf("ToReady on %s error = %v", node.ID, err)
+	}
+	machine.Start(ctx)
+}
+
+// kill stops a running node and cuts it off from the others
+func (c *Cluster) kill(node *Node) {
+	node.mu.Lock()
+	node.alive = false
+	node.cancel()
+	dispatcher, raftNode := node.dispatcher, node.raftNode
+	node.mu.Unlock()
+
+	c.mu.Lock()
+	c.rewire()
+	c.mu.Unlock()
+
+	dispatcher.Stop()
+	raftNode.Shutdown()
+}
+
+// rewire connects every pair of running nodes on the same side of the
+// partition and disconnects every other pair. The caller holds c.mu.
+func (c *Cluster) rewire() {
+	for _, from := range c.nodes {
+		from.mu.Lock()
+		transport, fromAlive := from.transport, from.alive
+		from.mu.Unlock()
+		if transport == nil {
+			continue
+		}
+		for _, to := range c.nodes {
+			if to == from {
+				continue
+			}
+			to.mu.Lock()
+			peer, toAlive := to.transport, to.alive
+			to.mu.Unlock()
+
+			addr := hraft.ServerAddress(to.ID)
+			if fromAlive && toAlive && c.isolated[from.ID] == c.isolated[to.ID] {
+				transport.Connect(addr, peer)
+			} else {
+				transport.Disconnect(addr)
+			}
+		}
+	}
+}
+
+// bootstrap seeds the storage of a node that never ran with all members as
+// voters, so that the nodes elect one leader between them. Start then finds
+// an existing configuration and leaves it alone.
+func bootstrap(id string, storage raft.Storage, transport hraft.Transport, members []config.ClusterNode) error {
+	conf := hraft.DefaultConfig()
+	conf.LocalID = hraft.ServerID(id)
+	var configuration hraft.Configuration
+	for _, member := range members {
+		configuration.Servers = append(configuration.Servers, hraft.Server{
+			ID:      hraft.ServerID(member.ID),
+			Address: hraft.ServerAddress(member.Addr),
+		})
+	}
+	err := hraft.BootstrapCluster(conf, storage.Logs, storage.Stable, storage.Snapshots, transport, configuration)
+	if errors.Is(err, hraft.ErrCantBootstrap) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testcluster
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"vip-switch-go/internal/state"
+)
+
+func TestCluster_ElectsOneMaster(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs a Raft cluster")
+	}
+	c := New(t, 3)
+
+	master := c.WaitForMaster(15 * time.Second)
+	c.AssertOneMaster(3 * time.Second)
+
+	if got := master.Hooks.Events(); got[len(got)-1] != "ToMaster" {
+		t.Errorf("Master ran %v, want ToMaster last", got)
+	}
+	for _, node := range c.Nodes() {
+		if node == master {
+			continue
+		}
+		c.WaitFor(5*time.Second, node.ID+" to be Slave", func() bool { return node.State() == state.StateSlave })
+		if got := node.Hooks.Events(); slices.Contains(got, "ToMaster") {
+			t.Errorf("%s ran %v, want no ToMaster", node.ID, got)
+		}
+	}
+}
+
+func TestCluster_KillMaster(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs a Raft cluster")
+	}
+	c := New(t, 3)
+
+	old := c.WaitForMaster(15 * time.Second)
+	c.Kill(old.ID)
+	next := c.WaitForMaster(15 * time.Second)
+	if next == old {
+		t.Fatalf("killed node %s is still the Master", old.ID)
+	}
+
+	// The restarted node rejoins as a Slave and leaves the Master alone
+	c.Restart(old.ID)
+	c.WaitFor(15*time.Second, old.ID+" to be Slave", func() bool { return old.State() == state.StateSlave })
+	c.AssertOneMaster(3 * time.Second)
+	if got := c.Masters(); got[0] != next {
+		t.Errorf("Master = %s after restart, want %s", got[0].ID, next.ID)
+	}
+}
+
+func TestCluster_PartitionMaster(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs a Raft cluster")
+	}
+	c := New(t, 3)
+
+	old := c.WaitForMaster(15 * time.Second)
+	c.Partition(old.ID)
+
+	// The cut off Master loses its lease and steps down, the majority
+	// elects a Master of its own
+	c.WaitFor(15*time.Second, old.ID+" to step down", func() bool { return old.State() != state.StateMaster })
+	next := c.WaitForMaster(15 * time.Second)
+	if next == old {
+		t.Fatalf("cut off node %s is the Master", old.ID)
+	}
+
+	c.Heal()
+	c.AssertOneMaster(5 * time.Second)
+	if got := old.State(); got != state.StateSlave {
+		t.Errorf("%s state = %v after Heal(), want Slave", old.ID, got)
+	}
+}
+
+func TestCluster_NoQuorum(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs a Raft cluster")
+	}
+	c := New(t, 3)
+
+	master := c.WaitForMaster(15 * time.Second)
+	for _, node := range c.Nodes() {
+		if node != master {
+			c.Kill(node.ID)
+		}
+	}
+
+	// Alone, the Master cannot keep leading
+	c.WaitFor(15*time.Second, "no Master", func() bool { return len(c.Masters()) == 0 })
+	c.AssertAtMostOneMaster(2 * time.Second)
+}
@@ -0,0 +1,80 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testcluster
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"vip-switch-go/internal/hook"
+)
+
+// Call is a hook event a node ran
+type Call struct {
+	EventType  string
+	Transition hook.Transition
+}
+
+// Hooks stands in for the hook system of a node. It records the events it
+// runs and fails those it was told to.
+type Hooks struct {
+	mu       sync.Mutex
+	calls    []Call
+	failures map[string]error
+}
+
+// NewHooks creates a recorder that runs every event successfully
+func NewHooks() *Hooks {
+	return &Hooks{failures: make(map[string]error)}
+}
+
+// Run records the event, returning the error set for its type. It has the
+// signature of hook.RunFunc.
+func (h *Hooks) Run(ctx context.Context, eventType string, tr hook.Transition) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.calls = append(h.calls, Call{EventType: eventType, Transition: tr})
+	return h.failures[eventType]
+}
+
+// Fail makes events of eventType return err, or succeed again if err is nil
+func (h *Hooks) Fail(eventType string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err == nil {
+		delete(h.failures, eventType)
+		return
+	}
+	h.failures[eventType] = err
+}
+
+// Calls returns the events run so far, in order
+func (h *Hooks) Calls() []Call {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return slices.Clone(h.calls)
+}
+
+// Events returns the types of the events run so far, in order
+func (h *Hooks) Events() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	events := make([]string, 0, len(h.calls))
+	for _, call := range h.calls {
+		events = append(events, call.EventType)
+	}
+	return events
+}
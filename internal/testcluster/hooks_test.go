@@ -0,0 +1,57 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testcluster
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"vip-switch-go/internal/hook"
+)
+
+func TestHooks(t *testing.T) {
+	hooks := NewHooks()
+	errFailed := errors.New("failed")
+	hooks.Fail("ToMaster", errFailed)
+
+	tests := []struct {
+		eventType string
+		want      error
+	}{
+		{"ToReady", nil},
+		{"ToMaster", errFailed},
+		{"ToSlave", nil},
+	}
+	for _, tt := range tests {
+		if err := hooks.Run(context.Background(), tt.eventType, hook.Transition{NewState: tt.eventType}); err != tt.want {
+			t.Errorf("Run(%s) = %v, want %v", tt.eventType, err, tt.want)
+		}
+	}
+
+	hooks.Fail("ToMaster", nil)
+	if err := hooks.Run(context.Background(), "ToMaster", hook.Transition{}); err != nil {
+		t.Errorf("Run(ToMaster) after Fail(nil) = %v, want nil", err)
+	}
+
+	want := []string{"ToReady", "ToMaster", "ToSlave", "ToMaster"}
+	if got := hooks.Events(); !slices.Equal(got, want) {
+		t.Errorf("Events() = %v, want %v", got, want)
+	}
+	if got := hooks.Calls()[1].Transition.NewState; got != "ToMaster" {
+		t.Errorf("Calls()[1].Transition.NewState = %q, want ToMaster", got)
+	}
+}
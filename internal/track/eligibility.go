@@ -0,0 +1,217 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package track
+
+import (
+	"log/slog"
+	"sort"
+	"sync"
+
+	metrics "github.com/hashicorp/go-metrics/compat"
+)
+
+// Eligibility records why a node may not be Master, and its score for
+// being Master. The node is eligible while no source reports a problem; its
+// score is its priority plus the weights reported by sources.
+type Eligibility struct {
+	mu       sync.Mutex
+	reasons  map[string]string
+	weights  map[string]int
+	priority int
+	changes  chan bool
+	logger   *slog.Logger
+}
+
+// NewEligibility creates an eligibility with no problems reported and a
+// score of priority
+func NewEligibility(priority int, logger *slog.Logger) *Eligibility {
+	metrics.SetGauge([]string{"track", "eligible"}, 1)
+	metrics.SetGauge([]string{"score"}, float32(priority))
+	return &Eligibility{
+		reasons:  make(map[string]string),
+		weights:  make(map[string]int),
+		priority: priority,
+		changes:  make(chan bool, 1),
+		logger:   logger,
+	}
+}
+
+// Report records the state of a tracked item. With a zero weight a down
+// item makes the node ineligible; otherwise a positive weight counts while
+// the item is up and a negative one while it is down.
+func (e *Eligibility) Report(source string, up bool, weight int, reason string) {
+	switch {
+	case weight == 0 && up:
+		e.SetEligible(source)
+	case weight == 0:
+		e.SetIneligible(source, reason)
+	case up == (weight > 0):
+		e.setWeight(source, weight)
+	default:
+		e.setWeight(source, 0)
+	}
+}
+
+// setWeight sets the weight source adds to the score
+func (e *Eligibility) setWeight(source string, weight int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.weights[source] == weight {
+		return
+	}
+	if weight == 0 {
+		delete(e.weights, source)
+	} else {
+		e.weights[source] = weight
+	}
+
+	score := e.score()
+	e.logger.Info("Score changed", "source", source, "weight", weight, "score", score)
+	metrics.SetGauge([]string{"score"}, float32(score))
+}
+
+// Score returns the node's score for being Master
+func (e *Eligibility) Score() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.score()
+}
+
+func (e *Eligibility) score() int {
+	score := e.priority
+	for _, weight := range e.weights {
+		score += weight
+	}
+	return score
+}
+
+// SetPriority changes the base score
+func (e *Eligibility) SetPriority(priority int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.priority == priority {
+		return
+	}
+	e.priority = priority
+	score := e.score()
+	e.logger.Info("Priority changed", "priority", priority, "score", score)
+	metrics.SetGauge([]string{"score"}, float32(score))
+}
+
+// Forget drops everything source reported, for an item no longer tracked
+func (e *Eligibility) Forget(source string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.weights[source]; ok {
+		delete(e.weights, source)
+		metrics.SetGauge([]string{"score"}, float32(e.score()))
+	}
+	if _, ok := e.reasons[source]; !ok {
+		return
+	}
+	delete(e.reasons, source)
+	e.logger.Info("Problem dropped with its source", "source", source, "remaining", len(e.reasons))
+
+	if len(e.reasons) == 0 {
+		e.logger.Info("Node eligible to be Master again")
+		e.notify(true)
+	}
+}
+
+// SetIneligible reports a problem from source, e.g. "link:eth0"
+func (e *Eligibility) SetIneligible(source, reason string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	wasEligible := len(e.reasons) == 0
+	if e.reasons[source] == reason {
+		return
+	}
+	e.reasons[source] = reason
+	e.logger.Warn("Node ineligible to be Master", "source", source, "reason", reason)
+
+	if wasEligible {
+		e.notify(false)
+	}
+}
+
+// SetEligible clears the problem reported by source
+func (e *Eligibility) SetEligible(source string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.reasons[source]; !ok {
+		return
+	}
+	delete(e.reasons, source)
+	e.logger.Info("Problem cleared", "source", source, "remaining", len(e.reasons))
+
+	if len(e.reasons) == 0 {
+		e.logger.Info("Node eligible to be Master again")
+		e.notify(true)
+	}
+}
+
+// Eligible reports whether no source reports a problem
+func (e *Eligibility) Eligible() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.reasons) == 0
+}
+
+// Reported reports whether source currently reports a problem
+func (e *Eligibility) Reported(source string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, ok := e.reasons[source]
+	return ok
+}
+
+// Reasons returns the reported problems as "source: reason", sorted
+func (e *Eligibility) Reasons() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	reasons := make([]string, 0, len(e.reasons))
+	for source, reason := range e.reasons {
+		reasons = append(reasons, source+": "+reason)
+	}
+	sort.Strings(reasons)
+	return reasons
+}
+
+// Changes returns a channel that receives the eligibility whenever it
+// changes. Only the latest value is kept for a slow reader.
+func (e *Eligibility) Changes() <-chan bool {
+	return e.changes
+}
+
+// notify publishes a change, replacing an unread one
+func (e *Eligibility) notify(eligible bool) {
+	value := float32(0)
+	if eligible {
+		value = 1
+	}
+	metrics.SetGauge([]string{"track", "eligible"}, value)
+
+	select {
+	case <-e.changes:
+	default:
+	}
+	e.changes <- eligible
+}
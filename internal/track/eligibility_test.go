@@ -0,0 +1,123 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package track
+
+import (
+	"log/slog"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// waitChange waits for an eligibility change
+func waitChange(t *testing.T, e *Eligibility) bool {
+	t.Helper()
+	select {
+	case eligible := <-e.Changes():
+		return eligible
+	case <-time.After(5 * time.Second):
+		t.Fatal("no eligibility change")
+		return false
+	}
+}
+
+func TestEligibility(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	e := NewEligibility(100, logger)
+
+	if !e.Eligible() {
+		t.Fatal("Eligible() = false, want true initially")
+	}
+
+	e.SetIneligible("link:eth0", "link down")
+	e.SetIneligible("link:eth1", "link down")
+	if waitChange(t, e) {
+		t.Error("change = eligible, want ineligible")
+	}
+	if got, want := e.Reasons(), []string{"link:eth0: link down", "link:eth1: link down"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Reasons() = %v, want %v", got, want)
+	}
+
+	e.SetEligible("link:eth0")
+	if e.Eligible() {
+		t.Error("Eligible() = true while link:eth1 is down")
+	}
+	e.SetEligible("link:eth1")
+	if !waitChange(t, e) {
+		t.Error("change = ineligible, want eligible")
+	}
+	if e.Reported("link:eth1") {
+		t.Error("Reported(link:eth1) = true after SetEligible")
+	}
+}
+
+func TestEligibility_Report(t *testing.T) {
+	tests := []struct {
+		name         string
+		up           bool
+		weight       int
+		wantEligible bool
+		wantScore    int
+	}{
+		{"unweighted up", true, 0, true, 100},
+		{"unweighted down", false, 0, false, 100},
+		{"positive weight up", true, 20, true, 120},
+		{"positive weight down", false, 20, true, 100},
+		{"negative weight up", true, -30, true, 100},
+		{"negative weight down", false, -30, true, 70},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+			e := NewEligibility(100, logger)
+			e.Report("check:test", tt.up, tt.weight, "failing")
+
+			if got := e.Eligible(); got != tt.wantEligible {
+				t.Errorf("Eligible() = %v, want %v", got, tt.wantEligible)
+			}
+			if got := e.Score(); got != tt.wantScore {
+				t.Errorf("Score() = %d, want %d", got, tt.wantScore)
+			}
+		})
+	}
+}
+
+func TestEligibility_PriorityAndForget(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	e := NewEligibility(100, logger)
+	e.Report("check:disk", false, -30, "failing")
+	e.Report("target:gateway", false, 0, "unreachable")
+	<-e.Changes()
+
+	e.SetPriority(150)
+	if got := e.Score(); got != 120 {
+		t.Errorf("Score() = %d, want 150 - 30", got)
+	}
+
+	e.Forget("check:disk")
+	if got := e.Score(); got != 150 {
+		t.Errorf("Score() = %d, want 150 after forgetting check:disk", got)
+	}
+
+	e.Forget("target:gateway")
+	if !e.Eligible() {
+		t.Errorf("Eligible() = false after forgetting target:gateway, reasons %v", e.Reasons())
+	}
+	if !<-e.Changes() {
+		t.Error("change = ineligible, want eligible")
+	}
+}
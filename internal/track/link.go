@@ -0,0 +1,199 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package track
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	metrics "github.com/hashicorp/go-metrics/compat"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
+	"vip-switch-go/internal/config"
+)
+
+// LinkWatcher reports the carrier of tracked interfaces to the node's
+// eligibility: a link without carrier makes the node ineligible, or changes
+// its score when the link has a weight. A link that comes back must stay up
+// for the up delay before it counts as up, so that a flapping link does not
+// bounce the VIP.
+type LinkWatcher struct {
+	interfaces  map[string]int // weight by interface name
+	upDelay     time.Duration
+	eligibility *Eligibility
+	logger      *slog.Logger
+	ns          netns.NsHandle // network namespace to watch, None for the current one
+
+	mu     sync.Mutex
+	down   map[string]bool        // links reported down
+	timers map[string]*time.Timer // pending up delays by interface
+}
+
+// NewLinkWatcher creates a watcher for interfaces
+func NewLinkWatcher(interfaces []config.TrackInterface, upDelay time.Duration, eligibility *Eligibility, logger *slog.Logger) *LinkWatcher {
+	weights := make(map[string]int, len(interfaces))
+	for _, iface := range interfaces {
+		weights[iface.Name] = iface.Weight
+	}
+
+	return &LinkWatcher{
+		interfaces:  weights,
+		upDelay:     upDelay,
+		eligibility: eligibility,
+		logger:      logger,
+		ns:          netns.None(),
+		down:        make(map[string]bool),
+		timers:      make(map[string]*time.Timer),
+	}
+}
+
+// Start records the current state of the interfaces and watches them for
+// changes until ctx is done
+func (w *LinkWatcher) Start(ctx context.Context) error {
+	updates := make(chan netlink.LinkUpdate, 16)
+	done := make(chan struct{})
+
+	opts := netlink.LinkSubscribeOptions{
+		ErrorCallback: func(err error) {
+			w.logger.Error("Link subscription error", "error", err)
+		},
+	}
+	if w.ns.IsOpen() {
+		opts.Namespace = &w.ns
+	}
+	// Subscribe before reading the current state so no change is missed
+	if err := netlink.LinkSubscribeWithOptions(updates, done, opts); err != nil {
+		return fmt.Errorf("failed to subscribe to link updates: %w", err)
+	}
+
+	handle, err := netlink.NewHandleAt(w.ns, unix.NETLINK_ROUTE)
+	if err != nil {
+		close(done)
+		return fmt.Errorf("failed to open netlink handle: %w", err)
+	}
+	defer handle.Close()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for name := range w.interfaces {
+		link, err := handle.LinkByName(name)
+		var notFound netlink.LinkNotFoundError
+		switch {
+		case errors.As(err, &notFound):
+			w.report(name, false, "link not found")
+		case err != nil:
+			close(done)
+			return fmt.Errorf("failed to get link %s: %w", name, err)
+		case carrier(link.Attrs()):
+			w.logger.Info("Tracking link", "interface", name, "state", "up")
+			w.report(name, true, "")
+		default:
+			w.report(name, false, "link down")
+		}
+	}
+
+	go func() {
+		defer w.stopTimers()
+		for {
+			select {
+			case <-ctx.Done():
+				close(done)
+				return
+			case update, ok := <-updates:
+				if !ok {
+					w.logger.Error("Link subscription closed, link tracking stopped")
+					return
+				}
+				w.handleUpdate(update)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// handleUpdate applies a link change
+func (w *LinkWatcher) handleUpdate(update netlink.LinkUpdate) {
+	name := update.Link.Attrs().Name
+	if _, ok := w.interfaces[name]; !ok {
+		return
+	}
+
+	up := update.Header.Type != unix.RTM_DELLINK && carrier(update.Link.Attrs())
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	pendingTimer, pending := w.timers[name]
+	if !up {
+		if pending {
+			pendingTimer.Stop()
+			delete(w.timers, name)
+		}
+		if w.down[name] {
+			return
+		}
+		metrics.IncrCounterWithLabels([]string{"track", "link_down"}, 1, []metrics.Label{{Name: "interface", Value: name}})
+		reason := "link down"
+		if update.Header.Type == unix.RTM_DELLINK {
+			reason = "link removed"
+		}
+		w.report(name, false, reason)
+		return
+	}
+
+	if pending || !w.down[name] {
+		return
+	}
+	w.logger.Info("Link up, waiting before counting it as up", "interface", name, "delay", w.upDelay)
+	var timer *time.Timer
+	timer = time.AfterFunc(w.upDelay, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.timers[name] != timer {
+			// Stopped by a link down that raced the timer
+			return
+		}
+		delete(w.timers, name)
+		w.report(name, true, "")
+	})
+	w.timers[name] = timer
+}
+
+// report records a link's state, with w.mu held
+func (w *LinkWatcher) report(name string, up bool, reason string) {
+	w.down[name] = !up
+	w.eligibility.Report("link:"+name, up, w.interfaces[name], reason)
+}
+
+// stopTimers cancels pending up delays
+func (w *LinkWatcher) stopTimers() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for name, timer := range w.timers {
+		timer.Stop()
+		delete(w.timers, name)
+	}
+}
+
+// carrier reports whether a link is administratively up and has carrier
+func carrier(attrs *netlink.LinkAttrs) bool {
+	return attrs.RawFlags&unix.IFF_UP != 0 && attrs.RawFlags&unix.IFF_LOWER_UP != 0
+}
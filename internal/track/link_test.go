@@ -0,0 +1,168 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package track
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+	"vip-switch-go/internal/config"
+)
+
+// newTestNamespace creates a network namespace holding a down link to toggle,
+// and returns the link's name. The link is a dummy link, or the namespace's
+// loopback when the kernel lacks dummy links. The test is skipped without
+// the privileges to create a namespace.
+func newTestNamespace(t *testing.T) (netns.NsHandle, *netlink.Handle, string) {
+	t.Helper()
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	origin, err := netns.Get()
+	if err != nil {
+		t.Fatalf("failed to get network namespace: %v", err)
+	}
+	defer origin.Close()
+
+	ns, err := netns.New()
+	if err != nil {
+		t.Skipf("cannot create network namespace: %v", err)
+	}
+	if err := netns.Set(origin); err != nil {
+		t.Fatalf("failed to restore network namespace: %v", err)
+	}
+	t.Cleanup(func() { ns.Close() })
+
+	handle, err := netlink.NewHandleAt(ns)
+	if err != nil {
+		t.Fatalf("failed to open netlink handle: %v", err)
+	}
+	t.Cleanup(handle.Close)
+
+	dummy := &netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: "vip0"}}
+	if err := handle.LinkAdd(dummy); err != nil {
+		t.Logf("cannot create dummy link, using loopback: %v", err)
+		return ns, handle, "lo"
+	}
+	return ns, handle, "vip0"
+}
+
+func TestLinkWatcher(t *testing.T) {
+	ns, handle, name := newTestNamespace(t)
+	link, err := handle.LinkByName(name)
+	if err != nil {
+		t.Fatalf("LinkByName() unexpected error: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	eligibility := NewEligibility(100, logger)
+	w := NewLinkWatcher([]config.TrackInterface{{Name: name}}, 100*time.Millisecond, eligibility, logger)
+	w.ns = ns
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := w.Start(ctx); err != nil {
+		t.Fatalf("Start() unexpected error: %v", err)
+	}
+
+	// A new link is down
+	if waitChange(t, eligibility) {
+		t.Fatal("change = eligible, want ineligible for a down link")
+	}
+
+	start := time.Now()
+	if err := handle.LinkSetUp(link); err != nil {
+		t.Fatalf("LinkSetUp() unexpected error: %v", err)
+	}
+	if !waitChange(t, eligibility) {
+		t.Fatal("change = ineligible, want eligible after link up")
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("eligible after %v, want at least the 100ms up delay", elapsed)
+	}
+
+	if err := handle.LinkSetDown(link); err != nil {
+		t.Fatalf("LinkSetDown() unexpected error: %v", err)
+	}
+	if waitChange(t, eligibility) {
+		t.Fatal("change = eligible, want ineligible after link down")
+	}
+
+	// A link that flaps back down within the up delay stays ineligible
+	handle.LinkSetUp(link)
+	handle.LinkSetDown(link)
+	time.Sleep(300 * time.Millisecond)
+	if eligibility.Eligible() {
+		t.Error("Eligible() = true after a flap shorter than the up delay")
+	}
+}
+
+func TestLinkWatcher_MissingLink(t *testing.T) {
+	ns, _, _ := newTestNamespace(t)
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	eligibility := NewEligibility(100, logger)
+	w := NewLinkWatcher([]config.TrackInterface{{Name: "missing0"}}, time.Second, eligibility, logger)
+	w.ns = ns
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := w.Start(ctx); err != nil {
+		t.Fatalf("Start() unexpected error: %v", err)
+	}
+
+	if eligibility.Eligible() {
+		t.Errorf("Eligible() = true for a missing link, reasons %v", eligibility.Reasons())
+	}
+}
+
+func TestLinkWatcher_Weight(t *testing.T) {
+	ns, handle, name := newTestNamespace(t)
+	link, err := handle.LinkByName(name)
+	if err != nil {
+		t.Fatalf("LinkByName() unexpected error: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	eligibility := NewEligibility(100, logger)
+	w := NewLinkWatcher([]config.TrackInterface{{Name: name, Weight: -30}}, 10*time.Millisecond, eligibility, logger)
+	w.ns = ns
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := w.Start(ctx); err != nil {
+		t.Fatalf("Start() unexpected error: %v", err)
+	}
+
+	// A weighted link lowers the score instead of making the node ineligible
+	if !eligibility.Eligible() || eligibility.Score() != 70 {
+		t.Fatalf("Eligible(), Score() = %v, %d, want true, 70 with the link down", eligibility.Eligible(), eligibility.Score())
+	}
+
+	handle.LinkSetUp(link)
+	deadline := time.Now().Add(2 * time.Second)
+	for eligibility.Score() != 100 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := eligibility.Score(); got != 100 {
+		t.Errorf("Score() = %d, want 100 with the link up", got)
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package track
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Prober checks whether a target is reachable
+type Prober func(ctx context.Context) error
+
+// TCPProber connects to address:port, through iface when it is set
+func TCPProber(address string, port int, iface string) Prober {
+	dialer := net.Dialer{Control: bindToDevice(iface)}
+	target := net.JoinHostPort(address, strconv.Itoa(port))
+	return func(ctx context.Context) error {
+		conn, err := dialer.DialContext(ctx, "tcp", target)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// CommandProber runs command with args, succeeding when it exits zero. The
+// command is killed when ctx is done.
+func CommandProber(command string, args []string) Prober {
+	return func(ctx context.Context) error {
+		output, err := exec.CommandContext(ctx, command, args...).CombinedOutput()
+		if err != nil && len(output) > 0 {
+			return fmt.Errorf("%w: %s", err, bytes.TrimSpace(output))
+		}
+		return err
+	}
+}
+
+// bindToDevice returns a dialer control that binds sockets to iface
+func bindToDevice(iface string) func(network, address string, c syscall.RawConn) error {
+	if iface == "" {
+		return nil
+	}
+	return func(_, _ string, c syscall.RawConn) error {
+		var sockErr error
+		err := c.Control(func(fd uintptr) {
+			sockErr = unix.SetsockoptString(int(fd), unix.SOL_SOCKET, unix.SO_BINDTODEVICE, iface)
+		})
+		if err != nil {
+			return err
+		}
+		return sockErr
+	}
+}
+
+// ICMP echo message types
+const (
+	icmpv4EchoRequest = 8
+	icmpv4EchoReply   = 0
+	icmpv6EchoRequest = 128
+	icmpv6EchoReply   = 129
+)
+
+// icmpSequence numbers echo requests across probers
+var icmpSequence atomic.Uint32
+
+// ICMPProber sends an ICMP echo request to address, through iface when it
+// is set, and waits for the reply. It uses an unprivileged ping socket
+// when net.ipv4.ping_group_range allows it, and a raw socket otherwise.
+func ICMPProber(address, iface string) Prober {
+	ip := net.ParseIP(address)
+	return func(ctx context.Context) error {
+		return ping(ctx, ip, iface)
+	}
+}
+
+// ping sends one echo request and waits for its reply
+func ping(ctx context.Context, ip net.IP, iface string) error {
+	family, proto, request, reply := unix.AF_INET, unix.IPPROTO_ICMP, byte(icmpv4EchoRequest), byte(icmpv4EchoReply)
+	if ip.To4() == nil {
+		family, proto, request, reply = unix.AF_INET6, unix.IPPROTO_ICMPV6, icmpv6EchoRequest, icmpv6EchoReply
+	}
+
+	raw := false
+	fd, err := unix.Socket(family, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, proto)
+	if err != nil {
+		raw = true
+		if fd, err = unix.Socket(family, unix.SOCK_RAW|unix.SOCK_CLOEXEC, proto); err != nil {
+			return fmt.Errorf("failed to open ICMP socket: %w", err)
+		}
+	}
+	if iface != "" {
+		if err := unix.SetsockoptString(fd, unix.SOL_SOCKET, unix.SO_BINDTODEVICE, iface); err != nil {
+			unix.Close(fd)
+			return fmt.Errorf("failed to bind to %s: %w", iface, err)
+		}
+	}
+
+	// net.FilePacketConn dups the descriptor and adds deadline support
+	file := os.NewFile(uintptr(fd), "icmp")
+	conn, err := net.FilePacketConn(file)
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("failed to open ICMP socket: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	// Ping sockets pick the identifier themselves, so replies are matched
+	// on the sequence number
+	seq := uint16(icmpSequence.Add(1))
+	id := uint16(os.Getpid())
+	msg := make([]byte, 16)
+	msg[0] = request
+	binary.BigEndian.PutUint16(msg[4:], id)
+	binary.BigEndian.PutUint16(msg[6:], seq)
+	if family == unix.AF_INET {
+		// The kernel computes ICMPv6 checksums
+		binary.BigEndian.PutUint16(msg[2:], checksum(msg))
+	}
+
+	var dst net.Addr = &net.UDPAddr{IP: ip}
+	if raw {
+		dst = &net.IPAddr{IP: ip}
+	}
+	if _, err := conn.WriteTo(msg, dst); err != nil {
+		return fmt.Errorf("failed to send echo request: %w", err)
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("no echo reply: %w", ctx.Err())
+			}
+			return fmt.Errorf("no echo reply: %w", err)
+		}
+		data := buf[:n]
+		if raw && family == unix.AF_INET && n > 0 {
+			// Raw IPv4 sockets return the IP header
+			headerLen := int(data[0]&0x0f) * 4
+			if headerLen > n {
+				continue
+			}
+			data = data[headerLen:]
+		}
+		if len(data) < 8 || data[0] != reply || binary.BigEndian.Uint16(data[6:]) != seq {
+			continue
+		}
+		if raw && binary.BigEndian.Uint16(data[4:]) != id {
+			continue
+		}
+		return nil
+	}
+}
+
+// checksum computes the Internet checksum of b
+func checksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,97 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package track
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// isPermissionError reports whether err is a lack of privileges to open
+// an ICMP socket
+func isPermissionError(err error) bool {
+	return errors.Is(err, unix.EPERM) || errors.Is(err, unix.EACCES)
+}
+
+func TestTCPProber(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := TCPProber("127.0.0.1", port, "lo")(ctx); err != nil {
+		t.Errorf("TCPProber() on an open port unexpected error: %v", err)
+	}
+
+	listener.Close()
+	if err := TCPProber("127.0.0.1", port, "")(ctx); err == nil {
+		t.Error("TCPProber() on a closed port expected error, got nil")
+	}
+}
+
+func TestICMPProber(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		iface   string
+	}{
+		{"ipv4", "127.0.0.1", ""},
+		{"ipv4 through interface", "127.0.0.1", "lo"},
+		{"ipv6", "::1", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			err := ICMPProber(tt.address, tt.iface)(ctx)
+			if err != nil && isPermissionError(err) {
+				t.Skipf("no ICMP socket available: %v", err)
+			}
+			if err != nil {
+				t.Errorf("ICMPProber(%s) unexpected error: %v", tt.address, err)
+			}
+		})
+	}
+}
+
+func TestICMPProber_Timeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	// TEST-NET-1 is not routed, through loopback it cannot answer
+	err := ICMPProber("192.0.2.1", "lo")(ctx)
+	if err == nil {
+		t.Fatal("ICMPProber() of an unreachable host expected error, got nil")
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	// Echo request with id 1 and sequence 1
+	msg := []byte{8, 0, 0, 0, 0, 1, 0, 1}
+	if got := checksum(msg); got != 0xf7fd {
+		t.Errorf("checksum() = %#x, want 0xf7fd", got)
+	}
+}
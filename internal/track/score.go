@@ -0,0 +1,77 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package track
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"vip-switch-go/internal/raft"
+)
+
+// ScoreRPC is the RPC kind on the Raft port that returns a node's score
+const ScoreRPC byte = 0xf1
+
+// scoreRPCTimeout bounds writing a score to a peer
+const scoreRPCTimeout = 5 * time.Second
+
+// Score is a node's standing for being Master
+type Score struct {
+	NodeID   string   `json:"node_id"`
+	Score    int      `json:"score"`
+	Eligible bool     `json:"eligible"`
+	Reasons  []string `json:"reasons,omitempty"`
+}
+
+// Snapshot returns the current score of the node nodeID
+func (e *Eligibility) Snapshot(nodeID string) Score {
+	reasons := e.Reasons()
+	return Score{
+		NodeID:   nodeID,
+		Score:    e.Score(),
+		Eligible: len(reasons) == 0,
+		Reasons:  reasons,
+	}
+}
+
+// ScoreHandler answers score RPCs with the node's score
+func ScoreHandler(nodeID string, eligibility *Eligibility, logger *slog.Logger) func(net.Conn) {
+	return func(conn net.Conn) {
+		defer conn.Close()
+		conn.SetWriteDeadline(time.Now().Add(scoreRPCTimeout))
+		if err := json.NewEncoder(conn).Encode(eligibility.Snapshot(nodeID)); err != nil {
+			logger.Debug("Failed to send score", "remote", conn.RemoteAddr(), "error", err)
+		}
+	}
+}
+
+// FetchScore asks the node with the Raft address addr for its score
+func FetchScore(ctx context.Context, addr string) (Score, error) {
+	conn, err := raft.DialRPC(ctx, addr, ScoreRPC)
+	if err != nil {
+		return Score{}, fmt.Errorf("failed to reach %s: %w", addr, err)
+	}
+	defer conn.Close()
+
+	var score Score
+	if err := json.NewDecoder(conn).Decode(&score); err != nil {
+		return Score{}, fmt.Errorf("failed to read score from %s: %w", addr, err)
+	}
+	return score, nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package track
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"vip-switch-go/internal/raft"
+)
+
+func TestFetchScore(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	mux, err := raft.NewMux("127.0.0.1:0", logger)
+	if err != nil {
+		t.Fatalf("NewMux() unexpected error: %v", err)
+	}
+	defer mux.Close()
+
+	eligibility := NewEligibility(100, logger)
+	eligibility.Report("check:disk", false, -25, "health check failing")
+	eligibility.Report("link:eth0", false, 0, "link down")
+	mux.Handle(ScoreRPC, ScoreHandler("node2", eligibility, logger))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	score, err := FetchScore(ctx, mux.Addr().String())
+	if err != nil {
+		t.Fatalf("FetchScore() unexpected error: %v", err)
+	}
+
+	want := Score{NodeID: "node2", Score: 75, Eligible: false, Reasons: []string{"link:eth0: link down"}}
+	if !reflect.DeepEqual(score, want) {
+		t.Errorf("FetchScore() = %+v, want %+v", score, want)
+	}
+}
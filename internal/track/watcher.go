@@ -0,0 +1,211 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package track
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	metrics "github.com/hashicorp/go-metrics/compat"
+	"vip-switch-go/internal/config"
+)
+
+// probe is a target or health check watched by a ProbeWatcher
+type probe struct {
+	source   string // eligibility source, e.g. "target:gateway"
+	name     string
+	interval time.Duration
+	timeout  time.Duration
+	rise     int
+	fall     int
+	weight   int
+	failure  string // reason reported while down
+	prober   Prober
+}
+
+// ProbeWatcher probes targets the node must reach, such as the default
+// gateway, and runs health checks, reporting their state to the node's
+// eligibility. The first probe decides an item's state; after that it takes
+// rise consecutive successes to come up and fall consecutive failures to go
+// down.
+type ProbeWatcher struct {
+	probes      []*probe
+	eligibility *Eligibility
+	logger      *slog.Logger
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewProbeWatcher creates a watcher for targets and checks
+func NewProbeWatcher(targets []config.TrackTarget, checks []config.TrackCheck, eligibility *Eligibility, logger *slog.Logger) *ProbeWatcher {
+	w := &ProbeWatcher{eligibility: eligibility, logger: logger}
+
+	for _, target := range targets {
+		prober := ICMPProber(target.Address, target.Interface)
+		if target.Type == "tcp" {
+			prober = TCPProber(target.Address, target.Port, target.Interface)
+		}
+		w.probes = append(w.probes, &probe{
+			source:   "target:" + target.Name,
+			name:     target.Name,
+			interval: target.Interval,
+			timeout:  target.Timeout,
+			rise:     target.Rise,
+			fall:     target.Fall,
+			weight:   target.Weight,
+			failure:  target.Type + " probe of " + target.Address + " failing",
+			prober:   prober,
+		})
+	}
+	for _, check := range checks {
+		w.probes = append(w.probes, &probe{
+			source:   "check:" + check.Name,
+			name:     check.Name,
+			interval: check.Interval,
+			timeout:  check.Timeout,
+			rise:     check.Rise,
+			fall:     check.Fall,
+			weight:   check.Weight,
+			failure:  "health check failing",
+			prober:   CommandProber(check.Command, check.Args),
+		})
+	}
+	return w
+}
+
+// Sources returns the eligibility sources the watcher reports as
+func (w *ProbeWatcher) Sources() []string {
+	sources := make([]string, len(w.probes))
+	for i, p := range w.probes {
+		sources[i] = p.source
+	}
+	return sources
+}
+
+// Start probes every item once, so that eligibility reflects them when it
+// returns, then keeps probing them until ctx is done or Stop is called
+func (w *ProbeWatcher) Start(ctx context.Context) {
+	ctx, w.cancel = context.WithCancel(ctx)
+	states := make([]*targetState, len(w.probes))
+
+	var wg sync.WaitGroup
+	for i, p := range w.probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			states[i] = &targetState{up: w.probe(ctx, p) == nil}
+			w.apply(p, states[i].up)
+		}()
+	}
+	wg.Wait()
+
+	for i, p := range w.probes {
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			w.watch(ctx, p, states[i])
+		}()
+	}
+}
+
+// Stop stops probing and waits until no more states are reported
+func (w *ProbeWatcher) Stop() {
+	if w.cancel != nil {
+		w.cancel()
+	}
+	w.wg.Wait()
+}
+
+// watch probes an item every interval until ctx is done
+func (w *ProbeWatcher) watch(ctx context.Context, p *probe, state *targetState) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := w.probe(ctx, p)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				w.logger.Debug("Probe failed", "source", p.source, "error", err)
+			}
+			if state.record(err == nil, p.rise, p.fall) {
+				w.apply(p, state.up)
+			}
+		}
+	}
+}
+
+// probe runs an item's prober with its timeout
+func (w *ProbeWatcher) probe(ctx context.Context, p *probe) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	err := p.prober(ctx)
+	if err != nil {
+		metrics.IncrCounterWithLabels([]string{"track", "probe", "failures"}, 1, []metrics.Label{{Name: "source", Value: p.source}})
+	}
+	return err
+}
+
+// apply reports an item's state to the eligibility
+func (w *ProbeWatcher) apply(p *probe, up bool) {
+	value := float32(0)
+	if up {
+		value = 1
+	}
+	metrics.SetGaugeWithLabels([]string{"track", "probe", "up"}, value, []metrics.Label{{Name: "source", Value: p.source}})
+
+	if up {
+		w.logger.Info("Probe up", "source", p.source)
+	} else {
+		w.logger.Warn("Probe down", "source", p.source, "reason", p.failure)
+	}
+	w.eligibility.Report(p.source, up, p.weight, p.failure)
+}
+
+// targetState counts the probe results that disagree with a target's state
+type targetState struct {
+	up    bool
+	count int
+}
+
+// record adds a probe result and reports whether the state changed
+func (s *targetState) record(ok bool, rise, fall int) bool {
+	if ok == s.up {
+		s.count = 0
+		return false
+	}
+
+	s.count++
+	threshold := fall
+	if ok {
+		threshold = rise
+	}
+	if s.count < threshold {
+		return false
+	}
+
+	s.up = ok
+	s.count = 0
+	return true
+}
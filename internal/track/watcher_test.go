@@ -0,0 +1,146 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package track
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"vip-switch-go/internal/config"
+)
+
+func TestTargetState_Record(t *testing.T) {
+	tests := []struct {
+		name    string
+		up      bool
+		results []bool
+		want    []bool // changed after each result
+	}{
+		{"stays up", true, []bool{true, true}, []bool{false, false}},
+		{"falls after two failures", true, []bool{false, false}, []bool{false, true}},
+		{"success resets the fall count", true, []bool{false, true, false}, []bool{false, false, false}},
+		{"rises after three successes", false, []bool{true, true, true}, []bool{false, false, true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := &targetState{up: tt.up}
+			for i, ok := range tt.results {
+				if got := state.record(ok, 3, 2); got != tt.want[i] {
+					t.Errorf("record() #%d = %v, want %v", i+1, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestProbeWatcher(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	eligibility := NewEligibility(100, logger)
+
+	target := config.TrackTarget{Name: "gateway", Address: "192.0.2.1", Type: "icmp"}
+	target.Interval = 10 * time.Millisecond
+	target.Timeout = 10 * time.Millisecond
+	target.Rise = 2
+	target.Fall = 2
+
+	var reachable atomic.Bool
+	w := NewProbeWatcher([]config.TrackTarget{target}, nil, eligibility, logger)
+	w.probes[0].prober = func(ctx context.Context) error {
+		if reachable.Load() {
+			return nil
+		}
+		return errors.New("unreachable")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w.Start(ctx)
+
+	// The first probe decides the initial state
+	if eligibility.Eligible() {
+		t.Fatal("Eligible() = true with an unreachable gateway")
+	}
+	waitChange(t, eligibility)
+
+	reachable.Store(true)
+	if !waitChange(t, eligibility) {
+		t.Fatal("change = ineligible, want eligible once the gateway answers")
+	}
+
+	reachable.Store(false)
+	if waitChange(t, eligibility) {
+		t.Fatal("change = eligible, want ineligible once the gateway stops answering")
+	}
+	if !eligibility.Reported("target:gateway") {
+		t.Errorf("Reasons() = %v, want target:gateway", eligibility.Reasons())
+	}
+}
+
+func TestProbeWatcher_CheckWeight(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	eligibility := NewEligibility(100, logger)
+
+	checks := []config.TrackCheck{
+		{Name: "healthy", Command: "/bin/true", Weight: 20},
+		{Name: "failing", Command: "/bin/false", Weight: -50},
+	}
+	for i := range checks {
+		checks[i].Interval = time.Hour
+		checks[i].Timeout = time.Second
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	NewProbeWatcher(nil, checks, eligibility, logger).Start(ctx)
+
+	if !eligibility.Eligible() {
+		t.Errorf("Eligible() = false, want weighted checks to leave the node eligible")
+	}
+	if got := eligibility.Score(); got != 70 {
+		t.Errorf("Score() = %d, want 100 + 20 - 50", got)
+	}
+}
+
+func TestProbeWatcher_Stop(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	eligibility := NewEligibility(100, logger)
+
+	check := config.TrackCheck{Name: "flapping", Command: "/bin/true", Interval: time.Millisecond, Timeout: time.Second, Rise: 1, Fall: 1}
+	w := NewProbeWatcher(nil, []config.TrackCheck{check}, eligibility, logger)
+	if got := w.Sources(); len(got) != 1 || got[0] != "check:flapping" {
+		t.Errorf("Sources() = %v, want [check:flapping]", got)
+	}
+
+	var probes atomic.Int32
+	w.probes[0].prober = func(ctx context.Context) error {
+		probes.Add(1)
+		return nil
+	}
+	w.Start(context.Background())
+	time.Sleep(20 * time.Millisecond)
+	w.Stop()
+
+	stopped := probes.Load()
+	time.Sleep(20 * time.Millisecond)
+	if got := probes.Load(); got != stopped {
+		t.Errorf("probes = %d after Stop, want %d", got, stopped)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vip
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// broadcastMAC is the Ethernet broadcast address
+var broadcastMAC = net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+
+// Announce broadcasts count gratuitous ARP announcements of the VIP, in
+// CIDR form, on the interface named iface, interval apart, so that hosts on
+// the segment point their caches at the interface. It needs an IPv4 VIP and
+// CAP_NET_RAW.
+func Announce(ctx context.Context, iface, address string, count int, interval time.Duration) error {
+	ip, _, err := net.ParseCIDR(address)
+	if err != nil {
+		return fmt.Errorf("invalid VIP address: %w", err)
+	}
+	if ip.To4() == nil {
+		return fmt.Errorf("gratuitous ARP needs an IPv4 VIP, got %s", address)
+	}
+	link, err := net.InterfaceByName(iface)
+	if err != nil {
+		return fmt.Errorf("failed to find interface %s: %w", iface, err)
+	}
+	if len(link.HardwareAddr) != 6 {
+		return fmt.Errorf("interface %s has no Ethernet address", iface)
+	}
+
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW|unix.SOCK_CLOEXEC, int(htons(unix.ETH_P_ARP)))
+	if err != nil {
+		return fmt.Errorf("failed to open packet socket: %w", err)
+	}
+	defer unix.Close(fd)
+
+	to := &unix.SockaddrLinklayer{
+		Protocol: htons(unix.ETH_P_ARP),
+		Ifindex:  link.Index,
+		Halen:    6,
+	}
+	copy(to.Addr[:], broadcastMAC)
+	frame := gratuitousARP(link.HardwareAddr, ip.To4())
+
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if err := unix.Sendto(fd, frame, 0, to); err != nil {
+			return fmt.Errorf("failed to send gratuitous ARP on %s: %w", iface, err)
+		}
+	}
+	return nil
+}
+
+// gratuitousARP builds an Ethernet frame holding an ARP announcement: a
+// broadcast request for ip whose sender and target address are both ip
+func gratuitousARP(mac net.HardwareAddr, ip net.IP) []byte {
+	frame := make([]byte, 0, 42)
+	frame = append(frame, broadcastMAC...)
+	frame = append(frame, mac...)
+	frame = binary.BigEndian.AppendUint16(frame, unix.ETH_P_ARP)
+
+	frame = binary.BigEndian.AppendUint16(frame, 1)             // hardware type Ethernet
+	frame = binary.BigEndian.AppendUint16(frame, unix.ETH_P_IP) // protocol type IPv4
+	frame = append(frame, 6, 4)                                 // address lengths
+	frame = binary.BigEndian.AppendUint16(frame, 1)             // request
+	frame = append(frame, mac...)
+	frame = append(frame, ip...)
+	frame = append(frame, make([]byte, 6)...) // target hardware address, unknown
+	frame = append(frame, ip...)
+	return frame
+}
+
+// htons converts a short to network byte order
+func htons(v uint16) uint16 {
+	return v<<8 | v>>8
+}
@@ -0,0 +1,62 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vip
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestGratuitousARP(t *testing.T) {
+	mac := net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x01}
+	ip := net.ParseIP("192.0.2.10").To4()
+
+	frame := gratuitousARP(mac, ip)
+	want := []byte{
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, // destination
+		0x02, 0x00, 0x00, 0x00, 0x00, 0x01, // source
+		0x08, 0x06, // ARP
+		0x00, 0x01, 0x08, 0x00, 6, 4, 0x00, 0x01, // Ethernet, IPv4, request
+		0x02, 0x00, 0x00, 0x00, 0x00, 0x01, 192, 0, 2, 10, // sender
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 192, 0, 2, 10, // target
+	}
+	if !bytes.Equal(frame, want) {
+		t.Errorf("gratuitousARP() = % x, want % x", frame, want)
+	}
+}
+
+func TestAnnounce_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		iface   string
+		address string
+	}{
+		{"invalid address", "lo", "192.0.2.10"},
+		{"IPv6", "lo", "2001:db8::10/64"},
+		{"unknown interface", "no-such-if0", "192.0.2.10/32"},
+		{"no Ethernet address", "lo", "192.0.2.10/32"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Announce(context.Background(), tt.iface, tt.address, 1, time.Millisecond); err == nil {
+				t.Errorf("Announce(%s, %s) = nil, want an error", tt.iface, tt.address)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vip
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// Add assigns the VIP, in CIDR form, to the interface named iface. It is
+// not an error when the VIP is assigned already.
+func Add(iface, address string) error {
+	return add(&netlink.Handle{}, iface, address)
+}
+
+// Remove releases the VIP, in CIDR form, from the interface named iface. It
+// is not an error when the VIP is not assigned.
+func Remove(iface, address string) error {
+	return remove(&netlink.Handle{}, iface, address)
+}
+
+// add assigns the VIP through handle
+func add(handle *netlink.Handle, iface, address string) error {
+	link, addr, err := resolve(handle, iface, address)
+	if err != nil {
+		return err
+	}
+	if err := handle.AddrReplace(link, addr); err != nil {
+		return fmt.Errorf("failed to add %s to %s: %w", address, iface, err)
+	}
+	return nil
+}
+
+// remove releases the VIP through handle
+func remove(handle *netlink.Handle, iface, address string) error {
+	link, addr, err := resolve(handle, iface, address)
+	if err != nil {
+		return err
+	}
+	if err := handle.AddrDel(link, addr); err != nil && !errors.Is(err, unix.EADDRNOTAVAIL) {
+		return fmt.Errorf("failed to remove %s from %s: %w", address, iface, err)
+	}
+	return nil
+}
+
+// resolve looks up the interface and parses the VIP
+func resolve(handle *netlink.Handle, iface, address string) (netlink.Link, *netlink.Addr, error) {
+	addr, err := netlink.ParseAddr(address)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid VIP address: %w", err)
+	}
+	link, err := handle.LinkByName(iface)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find interface %s: %w", iface, err)
+	}
+	return link, addr, nil
+}
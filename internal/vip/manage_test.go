@@ -0,0 +1,109 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vip
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+)
+
+// newTestHandle returns a netlink handle on a new network namespace and the
+// name of a link in it: a dummy link, or the namespace's loopback when the
+// kernel lacks dummy links. The test is skipped without the privileges to
+// create a namespace.
+func newTestHandle(t *testing.T) (*netlink.Handle, string) {
+	t.Helper()
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	origin, err := netns.Get()
+	if err != nil {
+		t.Fatalf("failed to get network namespace: %v", err)
+	}
+	defer origin.Close()
+
+	ns, err := netns.New()
+	if err != nil {
+		t.Skipf("cannot create network namespace: %v", err)
+	}
+	if err := netns.Set(origin); err != nil {
+		t.Fatalf("failed to restore network namespace: %v", err)
+	}
+	t.Cleanup(func() { ns.Close() })
+
+	handle, err := netlink.NewHandleAt(ns)
+	if err != nil {
+		t.Fatalf("failed to open netlink handle: %v", err)
+	}
+	t.Cleanup(handle.Close)
+
+	if err := handle.LinkAdd(&netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Name: "vip0"}}); err != nil {
+		t.Logf("cannot create dummy link, using loopback: %v", err)
+		return handle, "lo"
+	}
+	return handle, "vip0"
+}
+
+// assigned reports whether address is assigned to the link named name
+func assigned(t *testing.T, handle *netlink.Handle, name, address string) bool {
+	t.Helper()
+	link, err := handle.LinkByName(name)
+	if err != nil {
+		t.Fatalf("LinkByName() unexpected error: %v", err)
+	}
+	addrs, err := handle.AddrList(link, netlink.FAMILY_ALL)
+	if err != nil {
+		t.Fatalf("AddrList() unexpected error: %v", err)
+	}
+	for _, addr := range addrs {
+		if addr.IPNet.String() == address {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAddRemove(t *testing.T) {
+	handle, name := newTestHandle(t)
+	const address = "192.0.2.10/24"
+
+	// Both are idempotent
+	for i := 0; i < 2; i++ {
+		if err := add(handle, name, address); err != nil {
+			t.Fatalf("add() unexpected error: %v", err)
+		}
+		if !assigned(t, handle, name, address) {
+			t.Fatalf("%s not assigned after add()", address)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := remove(handle, name, address); err != nil {
+			t.Fatalf("remove() unexpected error: %v", err)
+		}
+		if assigned(t, handle, name, address) {
+			t.Fatalf("%s still assigned after remove()", address)
+		}
+	}
+
+	if err := add(handle, "no-such-if0", address); err == nil {
+		t.Error("add() on an unknown interface = nil, want an error")
+	}
+	if err := add(handle, name, "192.0.2.10"); err == nil {
+		t.Error("add() of an address without prefix = nil, want an error")
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vip
+
+import (
+	"fmt"
+	"net"
+)
+
+// Present reports whether the IP of address, in CIDR form, is assigned to
+// the interface named iface
+func Present(iface, address string) (bool, error) {
+	ip, _, err := net.ParseCIDR(address)
+	if err != nil {
+		return false, fmt.Errorf("invalid VIP address: %w", err)
+	}
+
+	link, err := net.InterfaceByName(iface)
+	if err != nil {
+		return false, fmt.Errorf("failed to find interface %s: %w", iface, err)
+	}
+	addrs, err := link.Addrs()
+	if err != nil {
+		return false, fmt.Errorf("failed to list addresses of %s: %w", iface, err)
+	}
+
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
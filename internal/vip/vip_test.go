@@ -0,0 +1,45 @@
+// Copyright 2026 lowezheng
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vip
+
+import "testing"
+
+func TestPresent(t *testing.T) {
+	tests := []struct {
+		name    string
+		iface   string
+		address string
+		want    bool
+		wantErr bool
+	}{
+		{"assigned", "lo", "127.0.0.1/32", true, false},
+		{"prefix ignored", "lo", "127.0.0.1/8", true, false},
+		{"not assigned", "lo", "192.0.2.10/32", false, false},
+		{"invalid address", "lo", "192.0.2.10", false, true},
+		{"unknown interface", "no-such-if0", "192.0.2.10/32", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Present(tt.iface, tt.address)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Present() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Present() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}